@@ -0,0 +1,215 @@
+package api
+
+import (
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/models"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// createLotRequest creates a lot of capacity medium slots, or one slot per
+// entry of SlotSizes when given, keeping PermitCapacity of them for permit
+// holders
+type createLotRequest struct {
+	ID             string            `json:"id"`
+	Capacity       int               `json:"capacity"`
+	SlotSizes      []models.SlotSize `json:"slot_sizes,omitempty"`
+	PermitCapacity int               `json:"permit_capacity,omitempty"`
+}
+
+type lotStatusResponse struct {
+	ID              string                     `json:"id"`
+	Capacity        int                        `json:"capacity"`
+	Available       int                        `json:"available"`
+	Reserved        int                        `json:"reserved"`
+	PermitCapacity  int                        `json:"permit_capacity"`
+	PermitParked    int                        `json:"permit_parked"`
+	IsFull          bool                       `json:"is_full"`
+	CapacityByType  map[models.VehicleType]int `json:"capacity_by_type,omitempty"`
+	AvailableByType map[models.VehicleType]int `json:"available_by_type,omitempty"`
+}
+
+type capacityResponse struct {
+	ID       string `json:"id"`
+	Capacity int    `json:"capacity"`
+}
+
+// parkRequest parks a car, redeeming the reservation with ReservationCode
+// when given
+type parkRequest struct {
+	LicensePlate    string             `json:"license_plate"`
+	VehicleType     models.VehicleType `json:"vehicle_type,omitempty"`
+	ReservationCode string             `json:"reservation_code,omitempty"`
+}
+
+type ticketPayload struct {
+	TicketNumber    string    `json:"ticket_number"`
+	EntryTime       time.Time `json:"entry_time"`
+	SlotNumber      int       `json:"slot_number,omitempty"`
+	LotID           string    `json:"lot_id,omitempty"`
+	ReservationCode string    `json:"reservation_code,omitempty"`
+	PermitID        string    `json:"permit_id,omitempty"`
+}
+
+// reserveRequest books a space from Start until End. The license plate may
+// be left out, the reservation is then redeemed with its code.
+type reserveRequest struct {
+	LicensePlate string             `json:"license_plate,omitempty"`
+	VehicleType  models.VehicleType `json:"vehicle_type,omitempty"`
+	Start        time.Time          `json:"start"`
+	End          time.Time          `json:"end"`
+}
+
+type reservationResponse struct {
+	Code         string    `json:"code"`
+	LotID        string    `json:"lot_id"`
+	LicensePlate string    `json:"license_plate,omitempty"`
+	VehicleType  string    `json:"vehicle_type"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+}
+
+type receiptResponse struct {
+	LicensePlate    string    `json:"license_plate"`
+	VehicleType     string    `json:"vehicle_type"`
+	TicketNumber    string    `json:"ticket_number"`
+	LotID           string    `json:"lot_id"`
+	SlotNumber      int       `json:"slot_number"`
+	EntryTime       time.Time `json:"entry_time"`
+	ExitTime        time.Time `json:"exit_time"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Fee             string    `json:"fee"`
+	Currency        string    `json:"currency"`
+	FeeStrategy     string    `json:"fee_strategy"`
+	PermitID        string    `json:"permit_id,omitempty"`
+	LostTicket      bool      `json:"lost_ticket,omitempty"`
+}
+
+// lostTicketRequest unparks the car with the license plate when its driver
+// lost the ticket
+type lostTicketRequest struct {
+	LicensePlate string `json:"license_plate"`
+}
+
+// permitPayload registers a permit, valid in every lot when LotIDs is empty
+type permitPayload struct {
+	ID           string    `json:"id,omitempty"`
+	LicensePlate string    `json:"license_plate"`
+	LotIDs       []string  `json:"lot_ids,omitempty"`
+	ValidFrom    time.Time `json:"valid_from"`
+	ValidUntil   time.Time `json:"valid_until"`
+}
+
+type feeQuoteResponse struct {
+	LotID           string  `json:"lot_id"`
+	VehicleType     string  `json:"vehicle_type"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Fee             string  `json:"fee"`
+	Currency        string  `json:"currency"`
+}
+
+type createAttendantRequest struct {
+	Name   string   `json:"name"`
+	LotIDs []string `json:"lot_ids"`
+}
+
+type assignLotRequest struct {
+	LotID string `json:"lot_id"`
+}
+
+type attendantResponse struct {
+	Name            string   `json:"name"`
+	LotIDs          []string `json:"lot_ids"`
+	AvailableLotIDs []string `json:"available_lot_ids"`
+}
+
+func newLotStatusResponse(status models.ParkingLotStatus) lotStatusResponse {
+	return lotStatusResponse{
+		ID:              status.LotID,
+		Capacity:        status.Capacity,
+		Available:       status.Available,
+		Reserved:        status.Reserved,
+		PermitCapacity:  status.PermitCapacity,
+		PermitParked:    status.PermitParked,
+		IsFull:          status.IsFull,
+		CapacityByType:  status.CapacityByType,
+		AvailableByType: status.AvailableByType,
+	}
+}
+
+func (r parkRequest) toCar() *models.Car {
+	return &models.Car{
+		LicensePlate: r.LicensePlate,
+		Type:         r.VehicleType,
+	}
+}
+
+func newTicketPayload(ticket *models.Ticket, lotID string) ticketPayload {
+	return ticketPayload{
+		TicketNumber:    ticket.TicketNumber,
+		EntryTime:       ticket.EntryTime,
+		SlotNumber:      ticket.SlotNumber,
+		LotID:           lotID,
+		ReservationCode: ticket.ReservationCode,
+		PermitID:        ticket.PermitID,
+	}
+}
+
+func (r reserveRequest) toCar() *models.Car {
+	return &models.Car{
+		LicensePlate: r.LicensePlate,
+		Type:         r.VehicleType,
+	}
+}
+
+func newReservationResponse(reservation models.Reservation) reservationResponse {
+	return reservationResponse{
+		Code:         reservation.Code,
+		LotID:        reservation.LotID,
+		LicensePlate: reservation.LicensePlate,
+		VehicleType:  string(reservation.VehicleType),
+		Start:        reservation.Start,
+		End:          reservation.End,
+	}
+}
+
+// toTicket returns the ticket the driver presents. Lots bill the stay from
+// the entry time they issued the ticket with, the one sent may be left out.
+func (t ticketPayload) toTicket() *models.Ticket {
+	return &models.Ticket{
+		TicketNumber: t.TicketNumber,
+		EntryTime:    t.EntryTime,
+		SlotNumber:   t.SlotNumber,
+	}
+}
+
+func newReceiptResponse(receipt *models.Receipt) receiptResponse {
+	return receiptResponse{
+		LicensePlate:    receipt.Car.LicensePlate,
+		VehicleType:     string(receipt.Car.GetVehicleType()),
+		TicketNumber:    receipt.Ticket.TicketNumber,
+		LotID:           receipt.LotID,
+		SlotNumber:      receipt.SlotNumber,
+		EntryTime:       receipt.EntryTime,
+		ExitTime:        receipt.ExitTime,
+		DurationSeconds: receipt.Duration.Seconds(),
+		Fee:             receipt.Fee.Decimal(),
+		Currency:        string(receipt.Fee.Currency()),
+		FeeStrategy:     receipt.FeeStrategy,
+		PermitID:        receipt.PermitID,
+		LostTicket:      receipt.LostTicket,
+	}
+}
+
+func newPermitPayload(permit models.Permit) permitPayload {
+	return permitPayload{
+		ID:           permit.ID,
+		LicensePlate: permit.LicensePlate,
+		LotIDs:       permit.LotIDs,
+		ValidFrom:    permit.ValidFrom,
+		ValidUntil:   permit.ValidUntil,
+	}
+}
@@ -0,0 +1,688 @@
+package api
+
+import (
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natanaelrusli/parking-lot/attendant"
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/logging"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/parkinglot"
+	"github.com/natanaelrusli/parking-lot/permit"
+	"github.com/natanaelrusli/parking-lot/repository"
+)
+
+// Server exposes parking lots, attendants and permits over JSON HTTP.
+//
+//	GET    /lots                           list lot statuses
+//	POST   /lots                           create a lot
+//	GET    /lots/{id}                      lot status
+//	GET    /lots/{id}/capacity             lot capacity
+//	POST   /lots/{id}/park                 park a car, returns a ticket
+//	POST   /lots/{id}/unpark               unpark with a ticket, returns a receipt
+//	POST   /lots/{id}/unpark_lost          unpark by license plate without a ticket
+//	GET    /lots/{id}/fee?duration=        fee quote for a stay of the given duration
+//	GET    /lots/{id}/reservations         list reservations
+//	POST   /lots/{id}/reservations         reserve a space for a time window
+//	DELETE /lots/{id}/reservations/{code}  cancel a reservation
+//	GET    /attendants                     list attendants
+//	POST   /attendants                     create an attendant
+//	GET    /attendants/{name}              attendant status
+//	POST   /attendants/{name}/lots         assign a lot to an attendant
+//	DELETE /attendants/{name}/lots/{id}    unassign a lot from an attendant
+//	POST   /attendants/{name}/park         park a car through an attendant
+//	POST   /attendants/{name}/unpark       unpark a car through an attendant
+//	POST   /attendants/{name}/unpark_lost  unpark a car without a ticket through an attendant
+//	GET    /permits                        list permits
+//	POST   /permits                        register a permit
+//	DELETE /permits/{id}                   revoke a permit
+type Server struct {
+	repo       repository.ParkingLotRepository
+	mu         sync.RWMutex
+	lots       map[string]parkinglot.ParkingLotItf
+	attendants map[string]attendant.ParkingAttendantItf
+	permits    permit.RegistryItf
+	lotOpts    []parkinglot.Option
+	logger     logging.Logger
+}
+
+// NewServer creates a server that restores every lot and permit already
+// stored in repo. opts are applied to every lot the server opens or creates,
+// and every lot takes the server's permits. The lots and attendants log to
+// logger.
+func NewServer(repo repository.Repository, logger logging.Logger, opts ...parkinglot.Option) (*Server, error) {
+	permits, err := permit.NewRegistry(repo)
+	if err != nil {
+		return nil, err
+	}
+	opts = append([]parkinglot.Option{parkinglot.WithPermits(permits), parkinglot.WithLogger(logger)}, opts...)
+
+	lots, err := parkinglot.OpenAll(repo, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		repo:       repo,
+		lots:       make(map[string]parkinglot.ParkingLotItf, len(lots)),
+		attendants: make(map[string]attendant.ParkingAttendantItf),
+		permits:    permits,
+		lotOpts:    opts,
+		logger:     logger,
+	}
+	for _, lot := range lots {
+		s.lots[lot.GetId()] = lot
+	}
+
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "lots":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:  s.listLots,
+			http.MethodPost: s.createLot,
+		})
+	case len(parts) >= 2 && parts[0] == "lots":
+		s.routeLot(w, r, parts[1], parts[2:])
+	case len(parts) == 1 && parts[0] == "attendants":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:  s.listAttendants,
+			http.MethodPost: s.createAttendant,
+		})
+	case len(parts) >= 2 && parts[0] == "attendants":
+		s.routeAttendant(w, r, parts[1], parts[2:])
+	case len(parts) == 1 && parts[0] == "permits":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:  s.listPermits,
+			http.MethodPost: s.registerPermit,
+		})
+	case len(parts) == 2 && parts[0] == "permits":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodDelete: func(w http.ResponseWriter, r *http.Request) { s.revokePermit(w, parts[1]) },
+		})
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) routeLot(w http.ResponseWriter, r *http.Request, id string, rest []string) {
+	lot, err := s.getLot(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	action := strings.Join(rest, "/")
+	switch action {
+	case "":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) { s.getLotStatus(w, lot) },
+		})
+	case "capacity":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) { s.getLotCapacity(w, lot) },
+		})
+	case "park":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) { s.park(w, r, lot) },
+		})
+	case "unpark":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) { s.unpark(w, r, lot) },
+		})
+	case "unpark_lost":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) { s.unparkLostTicket(w, r, lot) },
+		})
+	case "fee":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) { s.quoteFee(w, r, lot) },
+		})
+	case "reservations":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:  func(w http.ResponseWriter, r *http.Request) { s.listReservations(w, lot) },
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) { s.reserve(w, r, lot) },
+		})
+	default:
+		if len(rest) == 2 && rest[0] == "reservations" {
+			s.route(w, r, map[string]http.HandlerFunc{
+				http.MethodDelete: func(w http.ResponseWriter, r *http.Request) { s.cancelReservation(w, lot, rest[1]) },
+			})
+			return
+		}
+		notFound(w)
+	}
+}
+
+func (s *Server) routeAttendant(w http.ResponseWriter, r *http.Request, name string, rest []string) {
+	at, err := s.getAttendant(name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	action := strings.Join(rest, "/")
+	switch action {
+	case "":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, newAttendantResponse(at))
+			},
+		})
+	case "lots":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) { s.assignLot(w, r, at) },
+		})
+	case "park":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) { s.attendantPark(w, r, at) },
+		})
+	case "unpark":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) { s.attendantUnpark(w, r, at) },
+		})
+	case "unpark_lost":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) { s.attendantUnparkLostTicket(w, r, at) },
+		})
+	default:
+		if len(rest) == 2 && rest[0] == "lots" {
+			s.route(w, r, map[string]http.HandlerFunc{
+				http.MethodDelete: func(w http.ResponseWriter, r *http.Request) { s.unassignLot(w, at, rest[1]) },
+			})
+			return
+		}
+		notFound(w)
+	}
+}
+
+// route dispatches on the request method, answering 405 for anything else
+func (s *Server) route(w http.ResponseWriter, r *http.Request, handlers map[string]http.HandlerFunc) {
+	handler, ok := handlers[r.Method]
+	if !ok {
+		allowed := make([]string, 0, len(handlers))
+		for method := range handlers {
+			allowed = append(allowed, method)
+		}
+		sort.Strings(allowed)
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	handler(w, r)
+}
+
+func (s *Server) getLot(id string) (parkinglot.ParkingLotItf, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lot, exists := s.lots[id]
+	if !exists {
+		return nil, errors.ErrLotNotFound
+	}
+	return lot, nil
+}
+
+func (s *Server) getAttendant(name string) (attendant.ParkingAttendantItf, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	at, exists := s.attendants[name]
+	if !exists {
+		return nil, errors.ErrAttendantNotFound
+	}
+	return at, nil
+}
+
+func (s *Server) listLots(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	statuses := make([]lotStatusResponse, 0, len(s.lots))
+	for _, lot := range s.lots {
+		statuses = append(statuses, newLotStatusResponse(lot.GetStatus()))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ID < statuses[j].ID
+	})
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *Server) createLot(w http.ResponseWriter, r *http.Request) {
+	var req createLotRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	opts := append([]parkinglot.Option(nil), s.lotOpts...)
+	if len(req.SlotSizes) > 0 {
+		for _, size := range req.SlotSizes {
+			if !size.IsValid() {
+				writeError(w, fmt.Errorf("%w: %q", errors.ErrInvalidSlotSize, size))
+				return
+			}
+		}
+		req.Capacity = len(req.SlotSizes)
+		opts = append(opts, parkinglot.WithSlotSizes(req.SlotSizes...))
+	}
+
+	if req.Capacity <= 0 {
+		writeError(w, fmt.Errorf("%w: capacity must be positive", errors.ErrInvalidRequest))
+		return
+	}
+	if req.PermitCapacity != 0 {
+		opts = append(opts, parkinglot.WithPermitCapacity(req.PermitCapacity))
+	}
+	if req.ID == "" {
+		req.ID = uuid.New().String()[:8]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.lots[req.ID]; exists {
+		writeError(w, errors.ErrLotAlreadyExists)
+		return
+	}
+
+	lot, err := parkinglot.Open(s.repo, req.ID, req.Capacity, opts...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	s.lots[req.ID] = lot
+
+	writeJSON(w, http.StatusCreated, newLotStatusResponse(lot.GetStatus()))
+}
+
+func (s *Server) getLotStatus(w http.ResponseWriter, lot parkinglot.ParkingLotItf) {
+	writeJSON(w, http.StatusOK, newLotStatusResponse(lot.GetStatus()))
+}
+
+func (s *Server) getLotCapacity(w http.ResponseWriter, lot parkinglot.ParkingLotItf) {
+	writeJSON(w, http.StatusOK, capacityResponse{
+		ID:       lot.GetId(),
+		Capacity: lot.GetCapacity(),
+	})
+}
+
+func (s *Server) park(w http.ResponseWriter, r *http.Request, lot parkinglot.ParkingLotItf) {
+	var req parkRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var ticket *models.Ticket
+	var err error
+	if req.ReservationCode != "" {
+		ticket, err = lot.ParkWithReservation(req.toCar(), req.ReservationCode)
+	} else {
+		ticket, err = lot.Park(req.toCar())
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newTicketPayload(ticket, lot.GetId()))
+}
+
+func (s *Server) unpark(w http.ResponseWriter, r *http.Request, lot parkinglot.ParkingLotItf) {
+	var req ticketPayload
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	receipt, err := lot.Unpark(req.toTicket())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newReceiptResponse(receipt))
+}
+
+func (s *Server) unparkLostTicket(w http.ResponseWriter, r *http.Request, lot parkinglot.ParkingLotItf) {
+	var req lostTicketRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	receipt, err := lot.UnparkLostTicket(req.LicensePlate)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newReceiptResponse(receipt))
+}
+
+func (s *Server) quoteFee(w http.ResponseWriter, r *http.Request, lot parkinglot.ParkingLotItf) {
+	duration, err := time.ParseDuration(r.URL.Query().Get("duration"))
+	if err != nil || duration < 0 {
+		writeError(w, fmt.Errorf("%w: duration must be a non-negative Go duration such as 90m", errors.ErrInvalidRequest))
+		return
+	}
+
+	vehicleType := models.VehicleTypeCar
+	if v := r.URL.Query().Get("vehicle_type"); v != "" {
+		vehicleType = models.VehicleType(v)
+	}
+	if !vehicleType.IsValid() {
+		writeError(w, fmt.Errorf("%w: %q", errors.ErrInvalidVehicleType, vehicleType))
+		return
+	}
+
+	quote := lot.CalculateFee(vehicleType, duration)
+	writeJSON(w, http.StatusOK, feeQuoteResponse{
+		LotID:           lot.GetId(),
+		VehicleType:     string(vehicleType),
+		DurationSeconds: duration.Seconds(),
+		Fee:             quote.Decimal(),
+		Currency:        string(quote.Currency()),
+	})
+}
+
+func (s *Server) listReservations(w http.ResponseWriter, lot parkinglot.ParkingLotItf) {
+	reservations := lot.GetReservations()
+	responses := make([]reservationResponse, 0, len(reservations))
+	for _, reservation := range reservations {
+		responses = append(responses, newReservationResponse(reservation))
+	}
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (s *Server) reserve(w http.ResponseWriter, r *http.Request, lot parkinglot.ParkingLotItf) {
+	var req reserveRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	reservation, err := lot.Reserve(req.toCar(), req.Start, req.End)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newReservationResponse(*reservation))
+}
+
+func (s *Server) cancelReservation(w http.ResponseWriter, lot parkinglot.ParkingLotItf, code string) {
+	if err := lot.CancelReservation(code); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listPermits(w http.ResponseWriter, r *http.Request) {
+	permits := s.permits.GetPermits()
+	responses := make([]permitPayload, 0, len(permits))
+	for _, p := range permits {
+		responses = append(responses, newPermitPayload(p))
+	}
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (s *Server) registerPermit(w http.ResponseWriter, r *http.Request) {
+	var req permitPayload
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	p, err := s.permits.Register(req.LicensePlate, req.LotIDs, req.ValidFrom, req.ValidUntil)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newPermitPayload(*p))
+}
+
+func (s *Server) revokePermit(w http.ResponseWriter, id string) {
+	if err := s.permits.Revoke(id); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listAttendants(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	attendants := make([]attendantResponse, 0, len(s.attendants))
+	for _, at := range s.attendants {
+		attendants = append(attendants, newAttendantResponse(at))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(attendants, func(i, j int) bool {
+		return attendants[i].Name < attendants[j].Name
+	})
+	writeJSON(w, http.StatusOK, attendants)
+}
+
+func (s *Server) createAttendant(w http.ResponseWriter, r *http.Request) {
+	var req createAttendantRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if req.Name == "" {
+		writeError(w, fmt.Errorf("%w: name is required", errors.ErrInvalidRequest))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.attendants[req.Name]; exists {
+		writeError(w, errors.ErrAttendantAlreadyExists)
+		return
+	}
+
+	lots := make([]*parkinglot.ParkingLot, 0, len(req.LotIDs))
+	for _, id := range req.LotIDs {
+		lot, exists := s.lots[id]
+		if !exists {
+			writeError(w, errors.ErrLotNotFound)
+			return
+		}
+		lots = append(lots, lot.(*parkinglot.ParkingLot))
+	}
+
+	at := attendant.NewParkingAttendant(req.Name, lots, attendant.WithLogger(s.logger))
+	s.attendants[req.Name] = at
+
+	writeJSON(w, http.StatusCreated, newAttendantResponse(at))
+}
+
+func (s *Server) assignLot(w http.ResponseWriter, r *http.Request, at attendant.ParkingAttendantItf) {
+	var req assignLotRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	lot, err := s.getLot(req.LotID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	at.AssignParkingLot(lot.(*parkinglot.ParkingLot))
+
+	writeJSON(w, http.StatusOK, newAttendantResponse(at))
+}
+
+func (s *Server) unassignLot(w http.ResponseWriter, at attendant.ParkingAttendantItf, lotID string) {
+	if err := at.UnassignParkingLot(lotID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAttendantResponse(at))
+}
+
+func (s *Server) attendantPark(w http.ResponseWriter, r *http.Request, at attendant.ParkingAttendantItf) {
+	var req parkRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ticket, err := at.ParkCar(req.toCar())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// the attendant picks the lot, so look up which one issued the ticket
+	lotID := ""
+	for _, lot := range at.GetParkingLots() {
+		if lot.GetParkedCars(ticket) != nil {
+			lotID = lot.GetId()
+			break
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, newTicketPayload(ticket, lotID))
+}
+
+func (s *Server) attendantUnpark(w http.ResponseWriter, r *http.Request, at attendant.ParkingAttendantItf) {
+	var req ticketPayload
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	receipt, err := at.UnparkCar(req.toTicket())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newReceiptResponse(receipt))
+}
+
+func (s *Server) attendantUnparkLostTicket(w http.ResponseWriter, r *http.Request, at attendant.ParkingAttendantItf) {
+	var req lostTicketRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	receipt, err := at.UnparkLostTicket(req.LicensePlate)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newReceiptResponse(receipt))
+}
+
+func newAttendantResponse(at attendant.ParkingAttendantItf) attendantResponse {
+	lotIDs := []string{}
+	for _, lot := range at.GetParkingLots() {
+		lotIDs = append(lotIDs, lot.GetId())
+	}
+
+	availableLotIDs := []string{}
+	for id, available := range at.GetAllAvailableLots() {
+		if available {
+			availableLotIDs = append(availableLotIDs, id)
+		}
+	}
+	sort.Strings(availableLotIDs)
+
+	return attendantResponse{
+		Name:            at.GetName(),
+		LotIDs:          lotIDs,
+		AvailableLotIDs: availableLotIDs,
+	}
+}
+
+func notFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, errorResponse{Error: "not found"})
+}
+
+func decodeJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrInvalidRequest, err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, statusCode(err), errorResponse{Error: err.Error()})
+}
+
+// statusCode maps the errors package sentinels to HTTP status codes
+func statusCode(err error) int {
+	switch {
+	case goerrors.Is(err, errors.ErrInvalidRequest),
+		goerrors.Is(err, errors.ErrNilCar),
+		goerrors.Is(err, errors.ErrEmptyLicensePlate),
+		goerrors.Is(err, errors.ErrInvalidVehicleType),
+		goerrors.Is(err, errors.ErrInvalidSlotSize),
+		goerrors.Is(err, errors.ErrInvalidReservationWindow),
+		goerrors.Is(err, errors.ErrInvalidPermit),
+		goerrors.Is(err, errors.ErrInvalidPermitCapacity),
+		goerrors.Is(err, errors.ErrMalformedTicket),
+		goerrors.Is(err, errors.ErrNilTicket),
+		goerrors.Is(err, errors.ErrEmptyTicketNumber):
+		return http.StatusBadRequest
+	case goerrors.Is(err, errors.ErrForgedTicket):
+		return http.StatusForbidden
+	case goerrors.Is(err, errors.ErrLotNotFound),
+		goerrors.Is(err, errors.ErrAttendantNotFound),
+		goerrors.Is(err, errors.ErrUnrecognizedTicket),
+		goerrors.Is(err, errors.ErrTicketNotFound),
+		goerrors.Is(err, errors.ErrCarNotFound),
+		goerrors.Is(err, errors.ErrReservationNotFound),
+		goerrors.Is(err, errors.ErrPermitNotFound),
+		goerrors.Is(err, errors.ErrLotNotAssigned):
+		return http.StatusNotFound
+	case goerrors.Is(err, errors.ErrNoAvailablePosition),
+		goerrors.Is(err, errors.ErrNoCompatibleSlot),
+		goerrors.Is(err, errors.ErrAllLotsAreFull),
+		goerrors.Is(err, errors.ErrFullyBooked),
+		goerrors.Is(err, errors.ErrCarAlreadyParked),
+		goerrors.Is(err, errors.ErrLotAlreadyExists),
+		goerrors.Is(err, errors.ErrAttendantAlreadyExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
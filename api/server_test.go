@@ -0,0 +1,479 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/car"
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/logging"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/parkinglot"
+	"github.com/natanaelrusli/parking-lot/repository"
+	"github.com/natanaelrusli/parking-lot/ticket"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) *Server {
+	server, err := NewServer(repository.NewInMemoryRepository(), logging.Nop())
+	assert.NoError(t, err)
+	return server
+}
+
+func do(t *testing.T, server http.Handler, method string, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if s, ok := body.(string); ok {
+		buf.WriteString(s)
+	} else if body != nil {
+		assert.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(method, path, &buf))
+	return rec
+}
+
+func newSigner(t *testing.T, key string) *ticket.Signer {
+	signer, err := ticket.NewSigner([]byte(key))
+	assert.NoError(t, err)
+	return signer
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(v))
+}
+
+func TestLotEndpoints(t *testing.T) {
+	t.Run("should create a lot and report its status and capacity", func(t *testing.T) {
+		server := newTestServer(t)
+
+		rec := do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 2})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		rec = do(t, server, http.MethodGet, "/lots/lot1", nil)
+		var status lotStatusResponse
+		decode(t, rec, &status)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "lot1", status.ID)
+		assert.Equal(t, 2, status.Capacity)
+		assert.Equal(t, 2, status.Available)
+		assert.False(t, status.IsFull)
+
+		rec = do(t, server, http.MethodGet, "/lots/lot1/capacity", nil)
+		var capacity capacityResponse
+		decode(t, rec, &capacity)
+		assert.Equal(t, capacityResponse{ID: "lot1", Capacity: 2}, capacity)
+
+		rec = do(t, server, http.MethodGet, "/lots", nil)
+		var lots []lotStatusResponse
+		decode(t, rec, &lots)
+		assert.Len(t, lots, 1)
+	})
+
+	t.Run("should park and unpark a car with a receipt", func(t *testing.T) {
+		server := newTestServer(t)
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1})
+
+		rec := do(t, server, http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "AAA111"})
+		var ticket ticketPayload
+		decode(t, rec, &ticket)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "lot1", ticket.LotID)
+		assert.NotEmpty(t, ticket.TicketNumber)
+
+		rec = do(t, server, http.MethodGet, "/lots/lot1", nil)
+		var status lotStatusResponse
+		decode(t, rec, &status)
+		assert.True(t, status.IsFull)
+
+		rec = do(t, server, http.MethodPost, "/lots/lot1/unpark", ticket)
+		var receipt receiptResponse
+		decode(t, rec, &receipt)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "AAA111", receipt.LicensePlate)
+		assert.Equal(t, ticket.TicketNumber, receipt.TicketNumber)
+		assert.Equal(t, "10.00", receipt.Fee)
+		assert.Equal(t, "USD", receipt.Currency)
+		assert.Equal(t, "HourlyFeeStrategy", receipt.FeeStrategy)
+	})
+
+	t.Run("should create a lot with slot sizes and park vehicles by type", func(t *testing.T) {
+		server := newTestServer(t)
+
+		rec := do(t, server, http.MethodPost, "/lots", createLotRequest{
+			ID:        "lot1",
+			SlotSizes: []models.SlotSize{models.SlotSizeSmall, models.SlotSizeLarge},
+		})
+		var status lotStatusResponse
+		decode(t, rec, &status)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, 2, status.Capacity)
+		assert.Equal(t, 2, status.AvailableByType[models.VehicleTypeMotorcycle])
+		assert.Equal(t, 1, status.AvailableByType[models.VehicleTypeVan])
+		assert.Equal(t, 0, status.AvailableByType[models.VehicleTypeBus])
+
+		rec = do(t, server, http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "AAA111", VehicleType: models.VehicleTypeVan})
+		var ticket ticketPayload
+		decode(t, rec, &ticket)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, 2, ticket.SlotNumber)
+
+		rec = do(t, server, http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "BBB222", VehicleType: models.VehicleTypeCar})
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		rec = do(t, server, http.MethodPost, "/lots/lot1/unpark", ticket)
+		var receipt receiptResponse
+		decode(t, rec, &receipt)
+		assert.Equal(t, "van", receipt.VehicleType)
+	})
+
+	t.Run("should bill the stay from the lot's entry time, not the one sent", func(t *testing.T) {
+		server := newTestServer(t)
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1})
+
+		for name, entryTime := range map[string]string{
+			"missing":   "",
+			"in past":   `,"entry_time":"2000-01-01T00:00:00Z"`,
+			"in future": `,"entry_time":"2999-01-01T00:00:00Z"`,
+		} {
+			rec := do(t, server, http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "AAA111"})
+			var ticket ticketPayload
+			decode(t, rec, &ticket)
+
+			rec = do(t, server, http.MethodPost, "/lots/lot1/unpark", `{"ticket_number":"`+ticket.TicketNumber+`"`+entryTime+`}`)
+			var receipt receiptResponse
+			decode(t, rec, &receipt)
+			assert.Equal(t, http.StatusOK, rec.Code, name)
+			assert.True(t, ticket.EntryTime.Equal(receipt.EntryTime), name)
+			assert.GreaterOrEqual(t, receipt.DurationSeconds, 0.0, name)
+			assert.Less(t, receipt.DurationSeconds, 60.0, name)
+			assert.Equal(t, "10.00", receipt.Fee, name)
+		}
+	})
+
+	t.Run("should quote a fee for a duration", func(t *testing.T) {
+		server := newTestServer(t)
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1})
+
+		rec := do(t, server, http.MethodGet, "/lots/lot1/fee?duration=3h", nil)
+		var quote feeQuoteResponse
+		decode(t, rec, &quote)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "30.00", quote.Fee)
+		assert.Equal(t, "USD", quote.Currency)
+		assert.Equal(t, (3 * time.Hour).Seconds(), quote.DurationSeconds)
+	})
+
+	t.Run("should restore lots from the repository", func(t *testing.T) {
+		repo := repository.NewInMemoryRepository()
+		lot, _ := parkinglot.Open(repo, "lot1", 1)
+		_, _ = lot.Park(car.NewCar("AAA111"))
+
+		server, err := NewServer(repo, logging.Nop())
+		assert.NoError(t, err)
+
+		rec := do(t, server, http.MethodGet, "/lots/lot1", nil)
+		var status lotStatusResponse
+		decode(t, rec, &status)
+		assert.True(t, status.IsFull)
+	})
+
+	t.Run("should reserve, list and cancel reservations", func(t *testing.T) {
+		server := newTestServer(t)
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1})
+		start := time.Now().Add(-time.Minute).UTC()
+
+		rec := do(t, server, http.MethodPost, "/lots/lot1/reservations", reserveRequest{Start: start, End: start.Add(time.Hour)})
+		var reservation reservationResponse
+		decode(t, rec, &reservation)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.NotEmpty(t, reservation.Code)
+
+		rec = do(t, server, http.MethodGet, "/lots/lot1", nil)
+		var status lotStatusResponse
+		decode(t, rec, &status)
+		assert.Equal(t, 1, status.Reserved)
+		assert.True(t, status.IsFull)
+
+		rec = do(t, server, http.MethodGet, "/lots/lot1/reservations", nil)
+		var reservations []reservationResponse
+		decode(t, rec, &reservations)
+		assert.Equal(t, []reservationResponse{reservation}, reservations)
+
+		rec = do(t, server, http.MethodDelete, "/lots/lot1/reservations/"+reservation.Code, nil)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = do(t, server, http.MethodDelete, "/lots/lot1/reservations/"+reservation.Code, nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("should park with a reservation code", func(t *testing.T) {
+		server := newTestServer(t)
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1})
+		start := time.Now().Add(-time.Minute).UTC()
+		rec := do(t, server, http.MethodPost, "/lots/lot1/reservations", reserveRequest{Start: start, End: start.Add(time.Hour)})
+		var reservation reservationResponse
+		decode(t, rec, &reservation)
+
+		walkIn := do(t, server, http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "BBB222"})
+		rec = do(t, server, http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "AAA111", ReservationCode: reservation.Code})
+		var ticket ticketPayload
+		decode(t, rec, &ticket)
+
+		assert.Equal(t, http.StatusConflict, walkIn.Code)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, reservation.Code, ticket.ReservationCode)
+	})
+}
+
+func TestErrorMapping(t *testing.T) {
+	server := newTestServer(t)
+	do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1})
+	do(t, server, http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "AAA111"})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     interface{}
+		expected int
+	}{
+		{"unknown lot", http.MethodGet, "/lots/unknown", nil, http.StatusNotFound},
+		{"unknown route", http.MethodGet, "/lots/lot1/unknown", nil, http.StatusNotFound},
+		{"wrong method", http.MethodDelete, "/lots/lot1", nil, http.StatusMethodNotAllowed},
+		{"malformed body", http.MethodPost, "/lots/lot1/park", "{", http.StatusBadRequest},
+		{"empty license plate", http.MethodPost, "/lots/lot1/park", parkRequest{}, http.StatusBadRequest},
+		{"lot is full", http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "BBB222"}, http.StatusConflict},
+		{"lot already exists", http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1}, http.StatusConflict},
+		{"invalid capacity", http.MethodPost, "/lots", createLotRequest{Capacity: 0}, http.StatusBadRequest},
+		{"invalid slot size", http.MethodPost, "/lots", createLotRequest{SlotSizes: []models.SlotSize{"huge"}}, http.StatusBadRequest},
+		{"invalid vehicle type", http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "BBB222", VehicleType: "tank"}, http.StatusBadRequest},
+		{"empty ticket number", http.MethodPost, "/lots/lot1/unpark", ticketPayload{}, http.StatusBadRequest},
+		{"unrecognized ticket", http.MethodPost, "/lots/lot1/unpark", ticketPayload{TicketNumber: "INVALID"}, http.StatusNotFound},
+		{"invalid duration", http.MethodGet, "/lots/lot1/fee?duration=soon", nil, http.StatusBadRequest},
+		{"invalid reservation window", http.MethodPost, "/lots/lot1/reservations", reserveRequest{}, http.StatusBadRequest},
+		{"lot is fully booked", http.MethodPost, "/lots/lot1/reservations", reserveRequest{End: time.Now().Add(time.Hour)}, http.StatusConflict},
+		{"unknown reservation", http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "BBB222", ReservationCode: "unknown"}, http.StatusNotFound},
+		{"unknown attendant", http.MethodGet, "/attendants/nobody", nil, http.StatusNotFound},
+		{"invalid permit", http.MethodPost, "/permits", permitPayload{}, http.StatusBadRequest},
+		{"unknown permit", http.MethodDelete, "/permits/unknown", nil, http.StatusNotFound},
+		{"invalid permit capacity", http.MethodPost, "/lots", createLotRequest{ID: "lot2", Capacity: 1, PermitCapacity: 2}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(t, server, tt.method, tt.path, tt.body)
+
+			var resp errorResponse
+			decode(t, rec, &resp)
+			assert.Equal(t, tt.expected, rec.Code)
+			assert.NotEmpty(t, resp.Error)
+		})
+	}
+
+	t.Run("should map unknown errors to internal server error", func(t *testing.T) {
+		assert.Equal(t, http.StatusInternalServerError, statusCode(errors.ErrCorruptLog))
+	})
+}
+
+func TestAttendantEndpoints(t *testing.T) {
+	t.Run("should park and unpark cars through an attendant", func(t *testing.T) {
+		server := newTestServer(t)
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1})
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot2", Capacity: 1})
+
+		rec := do(t, server, http.MethodPost, "/attendants", createAttendantRequest{Name: "john", LotIDs: []string{"lot1"}})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		rec = do(t, server, http.MethodPost, "/attendants/john/lots", assignLotRequest{LotID: "lot2"})
+		var at attendantResponse
+		decode(t, rec, &at)
+		assert.Equal(t, []string{"lot1", "lot2"}, at.LotIDs)
+
+		rec = do(t, server, http.MethodPost, "/attendants/john/park", parkRequest{LicensePlate: "AAA111"})
+		var ticket1 ticketPayload
+		decode(t, rec, &ticket1)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "lot1", ticket1.LotID)
+
+		rec = do(t, server, http.MethodPost, "/attendants/john/park", parkRequest{LicensePlate: "BBB222"})
+		var ticket2 ticketPayload
+		decode(t, rec, &ticket2)
+		assert.Equal(t, "lot2", ticket2.LotID)
+
+		rec = do(t, server, http.MethodPost, "/attendants/john/park", parkRequest{LicensePlate: "CCC333"})
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		rec = do(t, server, http.MethodGet, "/attendants/john", nil)
+		decode(t, rec, &at)
+		assert.Empty(t, at.AvailableLotIDs)
+
+		rec = do(t, server, http.MethodPost, "/attendants/john/unpark", ticket2)
+		var receipt receiptResponse
+		decode(t, rec, &receipt)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "BBB222", receipt.LicensePlate)
+		assert.Equal(t, "lot2", receipt.LotID)
+
+		rec = do(t, server, http.MethodGet, "/attendants", nil)
+		var attendants []attendantResponse
+		decode(t, rec, &attendants)
+		assert.Len(t, attendants, 1)
+		assert.Equal(t, []string{"lot2"}, attendants[0].AvailableLotIDs)
+	})
+
+	t.Run("should unassign lots from an attendant", func(t *testing.T) {
+		server := newTestServer(t)
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1})
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot2", Capacity: 1})
+		do(t, server, http.MethodPost, "/attendants", createAttendantRequest{Name: "john", LotIDs: []string{"lot1", "lot2"}})
+
+		rec := do(t, server, http.MethodDelete, "/attendants/john/lots/lot1", nil)
+		var at attendantResponse
+		decode(t, rec, &at)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"lot2"}, at.LotIDs)
+		assert.Equal(t, []string{"lot2"}, at.AvailableLotIDs)
+
+		rec = do(t, server, http.MethodPost, "/attendants/john/park", parkRequest{LicensePlate: "AAA111"})
+		var ticket ticketPayload
+		decode(t, rec, &ticket)
+		assert.Equal(t, "lot2", ticket.LotID)
+
+		rec = do(t, server, http.MethodDelete, "/attendants/john/lots/lot1", nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("should log to the server's logger from its attendants", func(t *testing.T) {
+		var buf bytes.Buffer
+		server, err := NewServer(repository.NewInMemoryRepository(), logging.NewTextLogger(&buf, logging.LevelInfo))
+		assert.NoError(t, err)
+		do(t, server, http.MethodPost, "/attendants", createAttendantRequest{Name: "john"})
+
+		rec := do(t, server, http.MethodPost, "/attendants/john/park", parkRequest{LicensePlate: "AAA111"})
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, buf.String(), `level=WARN msg="all parking lots are full" attendant=john plate=AAA111`)
+	})
+
+	t.Run("should reject attendants for unknown lots and duplicate names", func(t *testing.T) {
+		server := newTestServer(t)
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1})
+
+		rec := do(t, server, http.MethodPost, "/attendants", createAttendantRequest{Name: "john", LotIDs: []string{"unknown"}})
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		do(t, server, http.MethodPost, "/attendants", createAttendantRequest{Name: "john", LotIDs: []string{"lot1"}})
+		rec = do(t, server, http.MethodPost, "/attendants", createAttendantRequest{Name: "john"})
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestSignedTicketEndpoints(t *testing.T) {
+	server, err := NewServer(repository.NewInMemoryRepository(), logging.Nop(), parkinglot.WithTicketSigner(newSigner(t, "secret-key-of-32-bytes-for-tests")))
+	assert.NoError(t, err)
+	do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1})
+	rec := do(t, server, http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "AAA111"})
+	var issued ticketPayload
+	decode(t, rec, &issued)
+
+	t.Run("should reject forged and malformed tickets", func(t *testing.T) {
+		forged := newSigner(t, "guessed-key-of-32-bytes-in-tests").Sign(ticket.Claims{LotID: "lot1", EntryTime: issued.EntryTime, Serial: 1})
+
+		forgedRec := do(t, server, http.MethodPost, "/lots/lot1/unpark", ticketPayload{TicketNumber: forged})
+		malformedRec := do(t, server, http.MethodPost, "/lots/lot1/unpark", ticketPayload{TicketNumber: "abcd1234"})
+
+		assert.Equal(t, http.StatusForbidden, forgedRec.Code)
+		assert.Equal(t, http.StatusBadRequest, malformedRec.Code)
+	})
+
+	t.Run("should unpark with the signed ticket", func(t *testing.T) {
+		rec := do(t, server, http.MethodPost, "/lots/lot1/unpark", issued)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestLostTicketEndpoints(t *testing.T) {
+	t.Run("should unpark a car without its ticket", func(t *testing.T) {
+		server := newTestServer(t)
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1})
+		do(t, server, http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "AAA111"})
+
+		rec := do(t, server, http.MethodPost, "/lots/lot1/unpark_lost", lostTicketRequest{LicensePlate: "AAA111"})
+		var receipt receiptResponse
+		decode(t, rec, &receipt)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, receipt.LostTicket)
+		assert.Equal(t, "240.00", receipt.Fee)
+
+		rec = do(t, server, http.MethodPost, "/lots/lot1/unpark_lost", lostTicketRequest{LicensePlate: "AAA111"})
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("should unpark a car without its ticket through an attendant", func(t *testing.T) {
+		server := newTestServer(t)
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1})
+		do(t, server, http.MethodPost, "/attendants", createAttendantRequest{Name: "john", LotIDs: []string{"lot1"}})
+		do(t, server, http.MethodPost, "/attendants/john/park", parkRequest{LicensePlate: "AAA111"})
+
+		rec := do(t, server, http.MethodPost, "/attendants/john/unpark_lost", lostTicketRequest{LicensePlate: "AAA111"})
+		var receipt receiptResponse
+		decode(t, rec, &receipt)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "lot1", receipt.LotID)
+		assert.True(t, receipt.LostTicket)
+	})
+}
+
+func TestPermitEndpoints(t *testing.T) {
+	t.Run("should register, list and revoke permits", func(t *testing.T) {
+		server := newTestServer(t)
+		from := time.Now().Add(-time.Hour).UTC()
+
+		rec := do(t, server, http.MethodPost, "/permits", permitPayload{LicensePlate: "AAA111", LotIDs: []string{"lot1"}, ValidFrom: from, ValidUntil: from.AddDate(0, 1, 0)})
+		var permit permitPayload
+		decode(t, rec, &permit)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.NotEmpty(t, permit.ID)
+
+		rec = do(t, server, http.MethodGet, "/permits", nil)
+		var permits []permitPayload
+		decode(t, rec, &permits)
+		assert.Equal(t, []permitPayload{permit}, permits)
+
+		rec = do(t, server, http.MethodDelete, "/permits/"+permit.ID, nil)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("should let permit holders park in the permit capacity and leave for free", func(t *testing.T) {
+		server := newTestServer(t)
+		from := time.Now().Add(-time.Hour).UTC()
+		do(t, server, http.MethodPost, "/lots", createLotRequest{ID: "lot1", Capacity: 1, PermitCapacity: 1})
+		rec := do(t, server, http.MethodPost, "/permits", permitPayload{LicensePlate: "AAA111", ValidFrom: from, ValidUntil: from.AddDate(0, 1, 0)})
+		var permit permitPayload
+		decode(t, rec, &permit)
+
+		walkIn := do(t, server, http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "BBB222"})
+		rec = do(t, server, http.MethodPost, "/lots/lot1/park", parkRequest{LicensePlate: "AAA111"})
+		var ticket ticketPayload
+		decode(t, rec, &ticket)
+
+		assert.Equal(t, http.StatusConflict, walkIn.Code)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, permit.ID, ticket.PermitID)
+
+		rec = do(t, server, http.MethodPost, "/lots/lot1/unpark", ticket)
+		var receipt receiptResponse
+		decode(t, rec, &receipt)
+		assert.Equal(t, "0.00", receipt.Fee)
+		assert.Equal(t, permit.ID, receipt.PermitID)
+	})
+}
@@ -1,62 +1,234 @@
 package attendant
 
 import (
+	"sync"
+
+	"github.com/natanaelrusli/parking-lot/clock"
 	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/event"
+	"github.com/natanaelrusli/parking-lot/logging"
 	"github.com/natanaelrusli/parking-lot/models"
 	"github.com/natanaelrusli/parking-lot/parkinglot"
 	parking_styles "github.com/natanaelrusli/parking-lot/parkingstyles"
 )
 
+// ParkingAttendant is safe for concurrent use. mu serializes parking
+// decisions across the attendant's lots, availableMu guards AvailableLots
+// which is updated from lot notifications while mu may be held.
+// ParkingLots is only changed with both held, so either is enough to read it.
 type ParkingAttendant struct {
 	Name          string
 	ParkingLots   []*parkinglot.ParkingLot
 	AvailableLots map[string]bool
 	ParkingStyle  parking_styles.ParkingStyleStrategy
+
+	mu          sync.Mutex
+	availableMu sync.Mutex
+	// bus the attendant publishes its events on, set with WithEventBus
+	bus *event.Bus
+	// clock set with WithClock
+	clock clock.Clock
+	// logger set with WithLogger
+	logger logging.Logger
+}
+
+// WithLogger sets the logger the attendant logs its lots filling up and
+// the cars it can't find a space for to, as well as the panics of the
+// handlers of the bus the attendant creates when not given one. Attendants
+// log nothing otherwise.
+func WithLogger(logger logging.Logger) Option {
+	return func(a *ParkingAttendant) {
+		a.logger = logger
+	}
+}
+
+// Option configures a ParkingAttendant created with NewParkingAttendant
+type Option func(*ParkingAttendant)
+
+// WithEventBus makes the attendant publish its events on the bus, e.g. the
+// one its lots publish on. Every attendant has a bus of its own otherwise.
+func WithEventBus(bus *event.Bus) Option {
+	return func(a *ParkingAttendant) {
+		a.bus = bus
+	}
+}
+
+// WithClock sets the clock used to stamp the events the attendant publishes
+func WithClock(c clock.Clock) Option {
+	return func(a *ParkingAttendant) {
+		a.clock = c
+	}
 }
 
 type ParkingAttendantItf interface {
 	GetName() string
 	ParkCar(car *models.Car) (*models.Ticket, error)
-	UnparkCar(ticket *models.Ticket) (*models.Car, error)
+	UnparkCar(ticket *models.Ticket) (*models.Receipt, error)
+	UnparkLostTicket(licensePlate string) (*models.Receipt, error)
 	isCarParkedAnywhere(car *models.Car) bool
 	GetAvailableLotsLen() int
 	OnParkingLotStatusChanged(status models.ParkingLotStatus)
 	GetAllAvailableLots() map[string]bool
 	AssignParkingLot(lot *parkinglot.ParkingLot)
+	UnassignParkingLot(lotID string) error
+	GetParkingLots() []*parkinglot.ParkingLot
 	ChangeParkingStrategy(strategy parking_styles.ParkingStyleStrategy)
+	Events() *event.Bus
 }
 
-func NewParkingAttendant(name string, parkingLots []*parkinglot.ParkingLot) ParkingAttendantItf {
-	availableLots := make(map[string]bool)
-	for _, v := range parkingLots {
-		availableLots[v.ID] = true
-	}
-
-	return &ParkingAttendant{
+// NewParkingAttendant creates an attendant managing the lots. The attendant
+// observes its lots, so it learns when they fill up or free a space.
+func NewParkingAttendant(name string, parkingLots []*parkinglot.ParkingLot, opts ...Option) ParkingAttendantItf {
+	a := &ParkingAttendant{
 		Name:          name,
 		ParkingLots:   parkingLots,
-		AvailableLots: availableLots,
+		AvailableLots: make(map[string]bool),
+		clock:         clock.NewRealClock(),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.bus == nil {
+		var busOpts []event.BusOption
+		if a.logger != nil {
+			busOpts = append(busOpts, event.WithErrorHandler(event.LogErrors(a.logger)))
+		}
+		a.bus = event.NewBus(busOpts...)
+	}
+	if a.logger == nil {
+		a.logger = logging.Nop()
+	}
+
+	for _, lot := range parkingLots {
+		a.refreshAvailability(lot)
+		lot.AddObserver(a)
 	}
+
+	return a
+}
+
+// Events returns the bus the attendant publishes its events on
+func (a *ParkingAttendant) Events() *event.Bus {
+	return a.bus
 }
 
 func (a *ParkingAttendant) ChangeParkingStrategy(strategy parking_styles.ParkingStyleStrategy) {
+	a.mu.Lock()
+	changed := &event.StrategyChanged{
+		Metadata:  event.Metadata{Time: a.clock.Now()},
+		Kind:      event.StrategyKindParkingStyle,
+		Attendant: a.Name,
+		Previous:  parking_styles.Name(a.ParkingStyle),
+		Current:   parking_styles.Name(strategy),
+	}
 	a.ParkingStyle = strategy
+	a.mu.Unlock()
+
+	a.bus.Publish(changed)
+	a.logger.Info("parking style changed",
+		logging.KeyAttendant, a.Name,
+		"previous", changed.Previous,
+		"current", changed.Current)
 }
 
+// AssignParkingLot adds the lot to the ones the attendant parks cars in and
+// starts observing it. Assigning a lot twice does nothing.
 func (a *ParkingAttendant) AssignParkingLot(lot *parkinglot.ParkingLot) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.availableMu.Lock()
+	if a.findLot(lot.ID) != nil {
+		a.availableMu.Unlock()
+		return
+	}
 	a.ParkingLots = append(a.ParkingLots, lot)
+	a.refreshAvailability(lot)
+	a.availableMu.Unlock()
+
+	lot.AddObserver(a)
 }
 
+// UnassignParkingLot removes the lot from the ones the attendant parks cars
+// in and stops observing it. Cars already parked there are left in place.
+func (a *ParkingAttendant) UnassignParkingLot(lotID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.availableMu.Lock()
+	index := -1
+	for i, lot := range a.ParkingLots {
+		if lot.ID == lotID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		a.availableMu.Unlock()
+		return errors.ErrLotNotAssigned
+	}
+	lot := a.ParkingLots[index]
+	// copy rather than shift in place, the slice may be shared with the caller
+	// of NewParkingAttendant or GetParkingLots
+	a.ParkingLots = append(a.ParkingLots[:index:index], a.ParkingLots[index+1:]...)
+	delete(a.AvailableLots, lotID)
+	a.availableMu.Unlock()
+
+	lot.RemoveObserver(a)
+	a.logger.Info("parking lot unassigned", logging.KeyAttendant, a.Name, logging.KeyLot, lotID)
+	return nil
+}
+
+func (a *ParkingAttendant) GetParkingLots() []*parkinglot.ParkingLot {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	parkingLots := make([]*parkinglot.ParkingLot, len(a.ParkingLots))
+	copy(parkingLots, a.ParkingLots)
+	return parkingLots
+}
+
+// OnParkingLotStatusChanged rechecks the availability of the lot the status
+// is for. The lot itself is asked rather than trusting the status, as
+// notifications of concurrent changes may arrive out of order. Statuses of
+// lots the attendant isn't assigned are ignored.
 func (a *ParkingAttendant) OnParkingLotStatusChanged(status models.ParkingLotStatus) {
-	if status.IsFull {
-		delete(a.AvailableLots, status.LotID)
+	a.availableMu.Lock()
+	defer a.availableMu.Unlock()
+
+	if lot := a.findLot(status.LotID); lot != nil {
+		a.refreshAvailability(lot)
+	}
+}
+
+// findLot returns the assigned lot with the ID, or nil.
+// It must be called with a.mu or a.availableMu held.
+func (a *ParkingAttendant) findLot(lotID string) *parkinglot.ParkingLot {
+	for _, lot := range a.ParkingLots {
+		if lot.ID == lotID {
+			return lot
+		}
+	}
+	return nil
+}
+
+// refreshAvailability sets whether the lot is available from its IsFull.
+// It must be called with a.availableMu held.
+func (a *ParkingAttendant) refreshAvailability(lot *parkinglot.ParkingLot) {
+	available := !lot.IsFull()
+	if available == a.AvailableLots[lot.ID] {
 		return
 	}
 
-	if !status.IsFull && status.Available == 1 {
-		a.AvailableLots[status.LotID] = true
+	if available {
+		a.logger.Debug("lot available again", logging.KeyAttendant, a.Name, logging.KeyLot, lot.ID)
+		a.AvailableLots[lot.ID] = true
 		return
 	}
+	a.logger.Debug("lot no longer available", logging.KeyAttendant, a.Name, logging.KeyLot, lot.ID)
+	delete(a.AvailableLots, lot.ID)
 }
 
 func (a *ParkingAttendant) GetName() string {
@@ -64,33 +236,77 @@ func (a *ParkingAttendant) GetName() string {
 }
 
 func (a *ParkingAttendant) GetAvailableLotsLen() int {
+	a.availableMu.Lock()
+	defer a.availableMu.Unlock()
+
 	return len(a.AvailableLots)
 }
 
+// GetAllAvailableLots returns a copy of the available lots so callers can
+// range over it while the attendant keeps receiving notifications. It is
+// kept from the lots' events only, lots publish their status on their own
+// when a reservation starting or ending fills them up or frees a space.
 func (a *ParkingAttendant) GetAllAvailableLots() map[string]bool {
-	return a.AvailableLots
+	a.availableMu.Lock()
+	defer a.availableMu.Unlock()
+
+	availableLots := make(map[string]bool, len(a.AvailableLots))
+	for id, available := range a.AvailableLots {
+		availableLots[id] = available
+	}
+	return availableLots
 }
 
 func (a *ParkingAttendant) ParkCar(car *models.Car) (*models.Ticket, error) {
+	// the whole check-then-park runs under the attendant lock so the same car
+	// can't be parked twice across lots by concurrent calls
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	if a.isCarParkedAnywhere(car) {
 		return nil, errors.ErrCarAlreadyParked
 	}
 
 	// if a parking style is choosen
 	if a.ParkingStyle != nil {
-		return a.ParkingStyle.GetLot(a.ParkingLots).Park(car)
+		lot := a.ParkingStyle.GetLot(a.ParkingLots)
+		// styles return an empty lot when none of the lots qualifies
+		if lot.ParkingLot == nil {
+			return nil, a.allLotsFull(car)
+		}
+		return lot.Park(car)
 	}
 
-	// if no parking style choosen, attendant will prioritize any first lot available
+	// if no parking style choosen, attendant will prioritize any first lot available.
+	// Lots are tried even when IsFull reports them full, as permit holders
+	// may still park in the spaces kept for them. A lot with no space, or no
+	// free slot big enough for the car, is skipped.
 	for _, lot := range a.ParkingLots {
-		if !lot.IsFull() {
-			return lot.Park(car)
+		ticket, err := lot.Park(car)
+		if err == errors.ErrNoAvailablePosition || err == errors.ErrNoCompatibleSlot {
+			continue
 		}
+		return ticket, err
 	}
-	return nil, errors.ErrAllLotsAreFull
+	return nil, a.allLotsFull(car)
 }
 
-func (a *ParkingAttendant) UnparkCar(ticket *models.Ticket) (*models.Car, error) {
+// allLotsFull logs that the car found no space and returns ErrAllLotsAreFull
+func (a *ParkingAttendant) allLotsFull(car *models.Car) error {
+	var licensePlate string
+	if car != nil {
+		licensePlate = car.LicensePlate
+	}
+	a.logger.Warn("all parking lots are full", logging.KeyAttendant, a.Name, logging.KeyPlate, licensePlate)
+	return errors.ErrAllLotsAreFull
+}
+
+// UnparkCar unparks the car from whichever lot issued the ticket, so the
+// receipt is always billed by that lot's fee strategy.
+func (a *ParkingAttendant) UnparkCar(ticket *models.Ticket) (*models.Receipt, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	for _, lot := range a.ParkingLots {
 		if car := lot.GetParkedCars(ticket); car != nil {
 			return lot.Unpark(ticket)
@@ -99,12 +315,29 @@ func (a *ParkingAttendant) UnparkCar(ticket *models.Ticket) (*models.Car, error)
 	return nil, errors.ErrTicketNotFound
 }
 
+// UnparkLostTicket unparks the car with the license plate from whichever
+// lot it is parked in, billed with that lot's lost ticket fee strategy.
+func (a *ParkingAttendant) UnparkLostTicket(licensePlate string) (*models.Receipt, error) {
+	if licensePlate == "" {
+		return nil, errors.ErrEmptyLicensePlate
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, lot := range a.ParkingLots {
+		if lot.IsCarParked(&models.Car{LicensePlate: licensePlate}) {
+			return lot.UnparkLostTicket(licensePlate)
+		}
+	}
+	return nil, errors.ErrCarNotFound
+}
+
+// isCarParkedAnywhere must be called with a.mu held.
 func (a *ParkingAttendant) isCarParkedAnywhere(car *models.Car) bool {
 	for _, lot := range a.ParkingLots {
-		for _, plateNumber := range lot.ParkedCars {
-			if plateNumber == car.LicensePlate {
-				return true
-			}
+		if lot.IsCarParked(car) {
+			return true
 		}
 	}
 
@@ -0,0 +1,192 @@
+package attendant
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/natanaelrusli/parking-lot/car"
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/parkinglot"
+	"github.com/stretchr/testify/assert"
+)
+
+// run with `go test -race` to catch unsynchronized access across lots
+func TestParkingAttendantConcurrency(t *testing.T) {
+	t.Run("should never assign more cars than the total capacity of all lots", func(t *testing.T) {
+		// arrange
+		lot1 := parkinglot.New(3)
+		lot2 := parkinglot.New(4)
+		attendant := NewParkingAttendant("John", []*parkinglot.ParkingLot{
+			lot1.(*parkinglot.ParkingLot),
+			lot2.(*parkinglot.ParkingLot),
+		})
+		lot1.AddObserver(attendant)
+		lot2.AddObserver(attendant)
+		var parked int32
+		var wg sync.WaitGroup
+
+		// act
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, err := attendant.ParkCar(car.NewCar(fmt.Sprintf("CAR%d", i)))
+				if err == nil {
+					atomic.AddInt32(&parked, 1)
+					return
+				}
+				assert.Equal(t, errors.ErrAllLotsAreFull, err)
+			}(i)
+		}
+		wg.Wait()
+
+		// assert
+		assert.Equal(t, int32(7), parked)
+		assert.Equal(t, 3, lot1.GetParkedCarCount())
+		assert.Equal(t, 4, lot2.GetParkedCarCount())
+		assert.Equal(t, 0, attendant.GetAvailableLotsLen())
+	})
+
+	t.Run("should park the same car only once across lots", func(t *testing.T) {
+		// arrange
+		lot1 := parkinglot.New(10)
+		lot2 := parkinglot.New(10)
+		attendant := NewParkingAttendant("John", []*parkinglot.ParkingLot{
+			lot1.(*parkinglot.ParkingLot),
+			lot2.(*parkinglot.ParkingLot),
+		})
+		var parked int32
+		var wg sync.WaitGroup
+
+		// act
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := attendant.ParkCar(car.NewCar("SAME1")); err == nil {
+					atomic.AddInt32(&parked, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		assert.Equal(t, int32(1), parked)
+		assert.Equal(t, 1, lot1.GetParkedCarCount()+lot2.GetParkedCarCount())
+	})
+
+	t.Run("should share the last space between attendants without overbooking", func(t *testing.T) {
+		// arrange
+		lot := parkinglot.New(1)
+		at1 := NewParkingAttendant("John", []*parkinglot.ParkingLot{lot.(*parkinglot.ParkingLot)})
+		at2 := NewParkingAttendant("Jane", []*parkinglot.ParkingLot{lot.(*parkinglot.ParkingLot)})
+		var parked int32
+		var wg sync.WaitGroup
+
+		// act
+		for i := 0; i < 50; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				if _, err := at1.ParkCar(car.NewCar(fmt.Sprintf("A%d", i))); err == nil {
+					atomic.AddInt32(&parked, 1)
+				}
+			}(i)
+			go func(i int) {
+				defer wg.Done()
+				if _, err := at2.ParkCar(car.NewCar(fmt.Sprintf("B%d", i))); err == nil {
+					atomic.AddInt32(&parked, 1)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		// assert
+		assert.Equal(t, int32(1), parked)
+		assert.Equal(t, 1, lot.GetParkedCarCount())
+	})
+
+	t.Run("should handle concurrent park, unpark and availability reads", func(t *testing.T) {
+		// arrange
+		lot1 := parkinglot.New(2)
+		lot2 := parkinglot.New(2)
+		attendant := NewParkingAttendant("John", []*parkinglot.ParkingLot{
+			lot1.(*parkinglot.ParkingLot),
+			lot2.(*parkinglot.ParkingLot),
+		})
+		lot1.AddObserver(attendant)
+		lot2.AddObserver(attendant)
+		var wg sync.WaitGroup
+
+		// act
+		for i := 0; i < 50; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				ticket, err := attendant.ParkCar(car.NewCar(fmt.Sprintf("CAR%d", i)))
+				if err != nil {
+					assert.Equal(t, errors.ErrAllLotsAreFull, err)
+					return
+				}
+				_, err = attendant.UnparkCar(ticket)
+				assert.NoError(t, err)
+			}(i)
+			go func() {
+				defer wg.Done()
+				for range attendant.GetAllAvailableLots() {
+				}
+				attendant.GetAvailableLotsLen()
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		assert.Equal(t, 0, lot1.GetParkedCarCount())
+		assert.Equal(t, 0, lot2.GetParkedCarCount())
+		assert.Equal(t, 2, attendant.GetAvailableLotsLen())
+	})
+
+	t.Run("should track availability while lots are assigned, unassigned and parked in directly", func(t *testing.T) {
+		// arrange
+		lot1 := parkinglot.New(1)
+		lot2 := parkinglot.New(2)
+		attendant := NewParkingAttendant("John", []*parkinglot.ParkingLot{lot1.(*parkinglot.ParkingLot)})
+		var wg sync.WaitGroup
+
+		// act
+		for i := 0; i < 50; i++ {
+			wg.Add(3)
+			go func(i int) {
+				defer wg.Done()
+				if ticket, err := attendant.ParkCar(car.NewCar(fmt.Sprintf("A%d", i))); err == nil {
+					_, _ = attendant.UnparkCar(ticket)
+				}
+			}(i)
+			go func(i int) {
+				defer wg.Done()
+				if ticket, err := lot2.Park(car.NewCar(fmt.Sprintf("B%d", i))); err == nil && i%2 == 0 {
+					_, _ = lot2.Unpark(ticket)
+				}
+			}(i)
+			go func(i int) {
+				defer wg.Done()
+				if i%2 == 0 {
+					attendant.AssignParkingLot(lot2.(*parkinglot.ParkingLot))
+					return
+				}
+				_ = attendant.UnassignParkingLot(lot2.GetId())
+			}(i)
+		}
+		wg.Wait()
+		attendant.AssignParkingLot(lot2.(*parkinglot.ParkingLot))
+
+		// assert
+		want := map[string]bool{lot1.GetId(): true}
+		if !lot2.IsFull() {
+			want[lot2.GetId()] = true
+		}
+		assert.Equal(t, want, attendant.GetAllAvailableLots())
+	})
+}
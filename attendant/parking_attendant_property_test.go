@@ -0,0 +1,188 @@
+package attendant
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/car"
+	"github.com/natanaelrusli/parking-lot/clock"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/parkinglot"
+)
+
+// availabilityScenario drives an attendant and its lots through random
+// operations, some through the attendant and some straight on the lots.
+type availabilityScenario struct {
+	rand      *rand.Rand
+	clock     *clock.FakeClock
+	lots      []*parkinglot.ParkingLot
+	attendant ParkingAttendantItf
+
+	tickets      []*models.Ticket
+	reservations map[string]*parkinglot.ParkingLot
+	plates       int
+	log          []string
+}
+
+func newAvailabilityScenario(seed int64) *availabilityScenario {
+	s := &availabilityScenario{
+		rand:         rand.New(rand.NewSource(seed)),
+		clock:        clock.NewFakeClock(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)),
+		reservations: make(map[string]*parkinglot.ParkingLot),
+	}
+	for i := 0; i < 3; i++ {
+		lot := parkinglot.New(1+s.rand.Intn(3), parkinglot.WithClock(s.clock))
+		s.lots = append(s.lots, lot.(*parkinglot.ParkingLot))
+	}
+	s.attendant = NewParkingAttendant("John", s.lots[:1+s.rand.Intn(len(s.lots))])
+	return s
+}
+
+func (s *availabilityScenario) newCar() *models.Car {
+	s.plates++
+	return car.NewCar(fmt.Sprintf("CAR%d", s.plates))
+}
+
+func (s *availabilityScenario) randomLot() *parkinglot.ParkingLot {
+	return s.lots[s.rand.Intn(len(s.lots))]
+}
+
+// takeTicket removes a random ticket from the ones handed out, or returns
+// nil when there are none
+func (s *availabilityScenario) takeTicket() *models.Ticket {
+	if len(s.tickets) == 0 {
+		return nil
+	}
+	i := s.rand.Intn(len(s.tickets))
+	ticket := s.tickets[i]
+	s.tickets = append(s.tickets[:i], s.tickets[i+1:]...)
+	return ticket
+}
+
+func (s *availabilityScenario) lotOf(ticket *models.Ticket) *parkinglot.ParkingLot {
+	for _, lot := range s.lots {
+		if lot.GetParkedCars(ticket) != nil {
+			return lot
+		}
+	}
+	return nil
+}
+
+func (s *availabilityScenario) step() {
+	switch s.rand.Intn(11) {
+	case 0:
+		if ticket, err := s.attendant.ParkCar(s.newCar()); err == nil {
+			s.tickets = append(s.tickets, ticket)
+		}
+		s.log = append(s.log, "attendant park")
+	case 1:
+		if ticket := s.takeTicket(); ticket != nil {
+			_, _ = s.attendant.UnparkCar(ticket)
+		}
+		s.log = append(s.log, "attendant unpark")
+	case 2:
+		lot := s.randomLot()
+		if ticket, err := lot.Park(s.newCar()); err == nil {
+			s.tickets = append(s.tickets, ticket)
+		}
+		s.log = append(s.log, "park in "+lot.ID)
+	case 3:
+		if ticket := s.takeTicket(); ticket != nil {
+			if lot := s.lotOf(ticket); lot != nil {
+				_, _ = lot.Unpark(ticket)
+			}
+		}
+		s.log = append(s.log, "unpark from lot")
+	case 4:
+		if ticket := s.takeTicket(); ticket != nil {
+			if lot := s.lotOf(ticket); lot != nil {
+				_, _ = lot.UnparkLostTicket(lot.GetParkedCars(ticket).LicensePlate)
+			}
+		}
+		s.log = append(s.log, "unpark lost ticket from lot")
+	case 5:
+		lot := s.randomLot()
+		start := s.clock.Now().Add(time.Duration(s.rand.Intn(120)) * time.Minute)
+		if reservation, err := lot.Reserve(s.newCar(), start, start.Add(time.Hour)); err == nil {
+			s.reservations[reservation.Code] = lot
+		}
+		s.log = append(s.log, "reserve in "+lot.ID)
+	case 6:
+		for code, lot := range s.reservations {
+			_ = lot.CancelReservation(code)
+			delete(s.reservations, code)
+			break
+		}
+		s.log = append(s.log, "cancel reservation")
+	case 7:
+		d := time.Duration(s.rand.Intn(90)) * time.Minute
+		s.clock.Advance(d)
+		s.log = append(s.log, "advance "+d.String())
+	case 8:
+		lot := s.randomLot()
+		lot.ExpireReservations()
+		s.log = append(s.log, "expire reservations in "+lot.ID)
+	case 9:
+		lot := s.randomLot()
+		s.attendant.AssignParkingLot(lot)
+		s.log = append(s.log, "assign "+lot.ID)
+	case 10:
+		lot := s.randomLot()
+		_ = s.attendant.UnassignParkingLot(lot.ID)
+		s.log = append(s.log, "unassign "+lot.ID)
+	}
+}
+
+// check reports how the available lots the attendant keeps from its lots'
+// events differ from the lots it is assigned that aren't full, if they do
+func (s *availabilityScenario) check() error {
+	assigned := make(map[string]bool)
+	for _, lot := range s.attendant.GetParkingLots() {
+		assigned[lot.ID] = true
+	}
+
+	available := s.attendant.GetAllAvailableLots()
+	for _, lot := range s.lots {
+		want := assigned[lot.ID] && !lot.IsFull()
+		if available[lot.ID] != want {
+			return fmt.Errorf("lot %s: available %v, want %v (assigned %v)", lot.ID, available[lot.ID], want, assigned[lot.ID])
+		}
+	}
+	for id := range available {
+		if !assigned[id] {
+			return fmt.Errorf("lot %s is available but not assigned", id)
+		}
+	}
+	if n := s.attendant.GetAvailableLotsLen(); n != len(available) {
+		return fmt.Errorf("%d available lots, GetAllAvailableLots has %d", n, len(available))
+	}
+	return nil
+}
+
+func TestAttendantAvailabilityProperties(t *testing.T) {
+	t.Run("should always report the assigned lots that aren't full as available", func(t *testing.T) {
+		property := func(seed int64) bool {
+			s := newAvailabilityScenario(seed)
+			if err := s.check(); err != nil {
+				t.Logf("seed %d, after creating the attendant: %v", seed, err)
+				return false
+			}
+			for i := 0; i < 60; i++ {
+				s.step()
+				if err := s.check(); err != nil {
+					t.Logf("seed %d, after %s: %v", seed, strings.Join(s.log, ", "), err)
+					return false
+				}
+			}
+			return true
+		}
+
+		if err := quick.Check(property, &quick.Config{MaxCount: 300}); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -1,11 +1,19 @@
 package attendant
 
 import (
+	"bytes"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/natanaelrusli/parking-lot/car"
+	"github.com/natanaelrusli/parking-lot/clock"
 	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/event"
+	"github.com/natanaelrusli/parking-lot/fee"
+	"github.com/natanaelrusli/parking-lot/logging"
 	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/money"
 	"github.com/natanaelrusli/parking-lot/parkinglot"
 	parking_styles "github.com/natanaelrusli/parking-lot/parkingstyles"
 	"github.com/stretchr/testify/assert"
@@ -43,6 +51,47 @@ func TestParkingAttendant(t *testing.T) {
 		}
 	})
 
+	t.Run("should bill the car with the fee strategy of the lot it was parked in", func(t *testing.T) {
+		lot1 := parkinglot.New(1)
+		lot2 := parkinglot.New(1)
+		lot1.ChangeFeeStrategy(fee.NewFlatFeeStrategy(money.FromMajor(5, money.USD)))
+		lot2.ChangeFeeStrategy(fee.NewFlatFeeStrategy(money.FromMajor(7, money.USD)))
+		attendant := NewParkingAttendant("John", []*parkinglot.ParkingLot{
+			lot1.(*parkinglot.ParkingLot),
+			lot2.(*parkinglot.ParkingLot),
+		})
+
+		_, _ = attendant.ParkCar(car.NewCar("AAA111"))
+		ticket, _ := attendant.ParkCar(car.NewCar("BBB222"))
+
+		receipt, err := attendant.UnparkCar(ticket)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "BBB222", receipt.Car.LicensePlate)
+		assert.Equal(t, lot2.GetId(), receipt.LotID)
+		assert.Equal(t, money.FromMajor(7, money.USD), receipt.Fee)
+	})
+
+	t.Run("should unpark a car with a lost ticket from the lot it is parked in", func(t *testing.T) {
+		lot1 := parkinglot.New(1)
+		lot2 := parkinglot.New(1, parkinglot.WithLostTicketFeeStrategy(fee.NewFlatFeeStrategy(money.FromMajor(40, money.USD))))
+		attendant := NewParkingAttendant("John", []*parkinglot.ParkingLot{
+			lot1.(*parkinglot.ParkingLot),
+			lot2.(*parkinglot.ParkingLot),
+		})
+		_, _ = attendant.ParkCar(car.NewCar("AAA111"))
+		_, _ = attendant.ParkCar(car.NewCar("BBB222"))
+
+		receipt, err := attendant.UnparkLostTicket("BBB222")
+
+		assert.NoError(t, err)
+		assert.Equal(t, lot2.GetId(), receipt.LotID)
+		assert.True(t, receipt.LostTicket)
+		assert.Equal(t, money.FromMajor(40, money.USD), receipt.Fee)
+		_, err = attendant.UnparkLostTicket("BBB222")
+		assert.Equal(t, errors.ErrCarNotFound, err)
+	})
+
 	t.Run("should park cars in next lot when first lot is full", func(t *testing.T) {
 		lot1 := parkinglot.New(1)
 		lot2 := parkinglot.New(1)
@@ -79,6 +128,23 @@ func TestParkingAttendant(t *testing.T) {
 			t.Errorf("Expected error %v when all lots are full, got %v", errors.ErrAllLotsAreFull, err)
 		}
 	})
+
+	t.Run("should park vehicles in the next lot when the first has no slot that fits", func(t *testing.T) {
+		lot1 := parkinglot.New(1)
+		lot2 := parkinglot.New(0, parkinglot.WithSlotSizes(models.SlotSizeLarge))
+		attendant := NewParkingAttendant("John", []*parkinglot.ParkingLot{
+			lot1.(*parkinglot.ParkingLot),
+			lot2.(*parkinglot.ParkingLot),
+		})
+
+		ticket, err := attendant.ParkCar(car.NewVehicle("VAN111", models.VehicleTypeVan))
+
+		assert.NoError(t, err)
+		assert.NotNil(t, lot2.GetParkedCars(ticket))
+
+		_, err = attendant.ParkCar(car.NewVehicle("BUS111", models.VehicleTypeBus))
+		assert.Equal(t, errors.ErrAllLotsAreFull, err)
+	})
 }
 
 func TestPreventDoubleParking(t *testing.T) {
@@ -204,22 +270,135 @@ func TestAttendantAvailableLots(t *testing.T) {
 		assert.Equal(t, al, 1)
 	})
 
-	t.Run("should not notify a non subscriber", func(t *testing.T) {
+	t.Run("should not track lots it isn't assigned", func(t *testing.T) {
 		// arrange
 		pl1 := parkinglot.New(1)
+		pl2 := parkinglot.New(1)
 		c1 := car.NewCar("B8888POP")
 		at1 := NewParkingAttendant("sule", []*parkinglot.ParkingLot{pl1.(*parkinglot.ParkingLot)})
-		at2 := NewParkingAttendant("sule", []*parkinglot.ParkingLot{pl1.(*parkinglot.ParkingLot)})
-		pl1.AddObserver(at1)
+		at2 := NewParkingAttendant("sule", []*parkinglot.ParkingLot{pl2.(*parkinglot.ParkingLot)})
+		pl1.AddObserver(at2)
 
 		// act
 		at1.ParkCar(c1)
-		al1 := at1.GetAvailableLotsLen()
-		al2 := at2.GetAvailableLotsLen()
 
 		// assert
-		assert.Equal(t, al1, 0)
-		assert.Equal(t, al2, 1)
+		assert.Equal(t, map[string]bool{}, at1.GetAllAvailableLots())
+		assert.Equal(t, map[string]bool{pl2.GetId(): true}, at2.GetAllAvailableLots())
+	})
+
+	t.Run("should share the availability of lots assigned to several attendants", func(t *testing.T) {
+		// arrange
+		pl1 := parkinglot.New(1)
+		at1 := NewParkingAttendant("sule", []*parkinglot.ParkingLot{pl1.(*parkinglot.ParkingLot)})
+		at2 := NewParkingAttendant("john", []*parkinglot.ParkingLot{pl1.(*parkinglot.ParkingLot)})
+
+		// act
+		_, err := at1.ParkCar(car.NewCar("B8888POP"))
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 0, at1.GetAvailableLotsLen())
+		assert.Equal(t, 0, at2.GetAvailableLotsLen())
+	})
+
+	t.Run("should start out with the lots that are already full unavailable", func(t *testing.T) {
+		// arrange
+		pl1 := parkinglot.New(1)
+		pl2 := parkinglot.New(1)
+		_, _ = pl1.Park(car.NewCar("B8888POP"))
+
+		// act
+		at := NewParkingAttendant("sule", []*parkinglot.ParkingLot{pl1.(*parkinglot.ParkingLot)})
+		at.AssignParkingLot(pl2.(*parkinglot.ParkingLot))
+
+		// assert
+		assert.Equal(t, map[string]bool{pl2.GetId(): true}, at.GetAllAvailableLots())
+	})
+
+	t.Run("should observe the lots it is assigned", func(t *testing.T) {
+		// arrange
+		pl1 := parkinglot.New(2)
+		at := NewParkingAttendant("sule", []*parkinglot.ParkingLot{})
+		at.AssignParkingLot(pl1.(*parkinglot.ParkingLot))
+
+		// act
+		_, _ = pl1.Park(car.NewCar("AAA111"))
+		ticket, _ := pl1.Park(car.NewCar("BBB222"))
+
+		// assert
+		assert.Equal(t, 0, at.GetAvailableLotsLen())
+
+		// act
+		_, _ = pl1.Unpark(ticket)
+
+		// assert
+		assert.Equal(t, 1, at.GetAvailableLotsLen())
+	})
+
+	t.Run("should become available again whenever a full lot frees a space", func(t *testing.T) {
+		// arrange
+		pl1 := parkinglot.New(3)
+		at := NewParkingAttendant("sule", []*parkinglot.ParkingLot{pl1.(*parkinglot.ParkingLot)})
+		var tickets []*models.Ticket
+		for _, plate := range []string{"AAA111", "BBB222", "CCC333"} {
+			ticket, _ := at.ParkCar(car.NewCar(plate))
+			tickets = append(tickets, ticket)
+		}
+		// a status with more than one space free used to be ignored
+		_, _ = pl1.Unpark(tickets[0])
+		_, _ = pl1.Park(car.NewCar("DDD444"))
+
+		// act
+		_, _ = pl1.Unpark(tickets[1])
+		_, _ = pl1.Unpark(tickets[2])
+
+		// assert
+		assert.Equal(t, map[string]bool{pl1.GetId(): true}, at.GetAllAvailableLots())
+	})
+
+	t.Run("should assign a lot only once", func(t *testing.T) {
+		// arrange
+		pl1 := parkinglot.New(1)
+		at := NewParkingAttendant("sule", []*parkinglot.ParkingLot{pl1.(*parkinglot.ParkingLot)})
+
+		// act
+		at.AssignParkingLot(pl1.(*parkinglot.ParkingLot))
+
+		// assert
+		assert.Len(t, at.GetParkingLots(), 1)
+	})
+
+	t.Run("should stop tracking unassigned lots", func(t *testing.T) {
+		// arrange
+		pl1 := parkinglot.New(1)
+		pl2 := parkinglot.New(1)
+		lots := []*parkinglot.ParkingLot{pl1.(*parkinglot.ParkingLot), pl2.(*parkinglot.ParkingLot)}
+		at := NewParkingAttendant("sule", lots)
+
+		// act
+		err := at.UnassignParkingLot(pl1.GetId())
+		_, _ = pl1.Park(car.NewCar("AAA111"))
+		_, parkErr := at.ParkCar(car.NewCar("BBB222"))
+
+		// assert
+		assert.NoError(t, err)
+		assert.NoError(t, parkErr)
+		assert.True(t, pl2.IsCarParked(car.NewCar("BBB222")))
+		assert.Equal(t, []*parkinglot.ParkingLot{pl2.(*parkinglot.ParkingLot)}, at.GetParkingLots())
+		assert.Equal(t, map[string]bool{}, at.GetAllAvailableLots())
+		assert.Same(t, pl1, lots[0], "the caller's slice is left untouched")
+	})
+
+	t.Run("should not unassign a lot it isn't assigned", func(t *testing.T) {
+		// arrange
+		at := NewParkingAttendant("sule", []*parkinglot.ParkingLot{})
+
+		// act
+		err := at.UnassignParkingLot("unknown")
+
+		// assert
+		assert.Equal(t, errors.ErrLotNotAssigned, err)
 	})
 
 	t.Run("should be notified when a parking lot become available or at least have 1 available space", func(t *testing.T) {
@@ -356,3 +535,58 @@ func TestParkingStyles(t *testing.T) {
 		attendant.UnparkCar(ticket2)
 	})
 }
+
+func TestAttendantEvents(t *testing.T) {
+	t.Run("should publish parking style changes on the bus shared with its lots", func(t *testing.T) {
+		bus := event.NewBus()
+		lot := parkinglot.New(5, parkinglot.WithEventBus(bus))
+		attendant := NewParkingAttendant("John", []*parkinglot.ParkingLot{lot.(*parkinglot.ParkingLot)}, WithEventBus(bus))
+		var events []event.Event
+		bus.Subscribe(event.HandlerFunc(func(e event.Event) {
+			events = append(events, e)
+		}))
+
+		attendant.ChangeParkingStrategy(parking_styles.NewMostCapacityStrategy())
+		_, _ = attendant.ParkCar(car.NewCar("ABC123"))
+
+		assert.Same(t, bus, attendant.Events())
+		assert.Len(t, events, 2)
+		changed := events[0].(*event.StrategyChanged)
+		assert.Equal(t, event.StrategyKindParkingStyle, changed.Kind)
+		assert.Equal(t, "John", changed.Attendant)
+		assert.Equal(t, "", changed.Previous)
+		assert.Equal(t, "MostCapacityStrategy", changed.Current)
+		assert.Equal(t, event.TypeCarParked, events[1].Type())
+		assert.Equal(t, uint64(2), events[1].Meta().Sequence)
+	})
+
+	t.Run("should stamp parking style changes with its clock", func(t *testing.T) {
+		now := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+		attendant := NewParkingAttendant("John", []*parkinglot.ParkingLot{}, WithClock(clock.NewFakeClock(now)))
+		var events []event.Event
+		attendant.Events().Subscribe(event.HandlerFunc(func(e event.Event) {
+			events = append(events, e)
+		}))
+
+		attendant.ChangeParkingStrategy(parking_styles.NewMostCapacityStrategy())
+
+		assert.Len(t, events, 1)
+		assert.Equal(t, now, events[0].Meta().Time)
+	})
+}
+
+func TestAttendantLogging(t *testing.T) {
+	t.Run("should log the cars it can't find a space for", func(t *testing.T) {
+		var buf bytes.Buffer
+		lot := parkinglot.New(1)
+		attendant := NewParkingAttendant("John", []*parkinglot.ParkingLot{lot.(*parkinglot.ParkingLot)},
+			WithLogger(logging.NewTextLogger(&buf, logging.LevelWarn)))
+
+		_, _ = attendant.ParkCar(car.NewCar("ABC123"))
+		_, err := attendant.ParkCar(car.NewCar("XYZ789"))
+
+		assert.Equal(t, errors.ErrAllLotsAreFull, err)
+		assert.Contains(t, buf.String(), `level=WARN msg="all parking lots are full" attendant=John plate=XYZ789`)
+		assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+	})
+}
@@ -3,7 +3,12 @@ package car
 import "github.com/natanaelrusli/parking-lot/models"
 
 func NewCar(licensePlate string) *models.Car {
+	return NewVehicle(licensePlate, models.VehicleTypeCar)
+}
+
+func NewVehicle(licensePlate string, vehicleType models.VehicleType) *models.Car {
 	return &models.Car{
 		LicensePlate: licensePlate,
+		Type:         vehicleType,
 	}
 }
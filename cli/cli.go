@@ -0,0 +1,362 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natanaelrusli/parking-lot/attendant"
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/logging"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/parkinglot"
+	"github.com/natanaelrusli/parking-lot/repository"
+)
+
+const usage = `Commands:
+  create_lot <capacity> [lot_id]  create a parking lot
+  park <license_plate> [lot_id]   park a car, in the given lot or the first one with space
+  leave <ticket_number>           unpark the car holding the ticket and print its receipt
+  leave_lost <license_plate>      unpark a car whose ticket was lost, billed with the lost ticket fee
+  status [lot_id]                 show the status of one or all lots
+  fee <lot_id> <duration> [type]  quote the fee for a stay, e.g. fee lot1 2h30m van
+  reserve <lot_id> <license_plate> <start> <end>
+                                  reserve a space, times in RFC 3339 e.g. 2024-03-01T08:00:00Z
+  cancel_reservation <lot_id> <code>
+                                  cancel a reservation
+  help                            show this help
+  exit                            leave interactive mode`
+
+// CLI operates parking lots through text commands, one per line
+type CLI struct {
+	out       io.Writer
+	repo      repository.ParkingLotRepository
+	lots      map[string]parkinglot.ParkingLotItf
+	attendant attendant.ParkingAttendantItf
+	lotOpts   []parkinglot.Option
+}
+
+// New creates a CLI writing to out that restores every lot already stored in
+// repo. opts are applied to every lot the CLI opens or creates. The lots and
+// the attendant log to logger.
+func New(out io.Writer, repo repository.ParkingLotRepository, logger logging.Logger, opts ...parkinglot.Option) (*CLI, error) {
+	opts = append([]parkinglot.Option{parkinglot.WithLogger(logger)}, opts...)
+	lots, err := parkinglot.OpenAll(repo, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CLI{
+		out:       out,
+		repo:      repo,
+		lots:      make(map[string]parkinglot.ParkingLotItf, len(lots)),
+		attendant: attendant.NewParkingAttendant("cli", []*parkinglot.ParkingLot{}, attendant.WithLogger(logger)),
+		lotOpts:   opts,
+	}
+	for _, lot := range lots {
+		c.addLot(lot)
+	}
+
+	return c, nil
+}
+
+// Run executes the commands read from in. In interactive mode a prompt is
+// printed and failed commands are reported without stopping; otherwise the
+// first failing command stops the run and its error is returned.
+func (c *CLI) Run(in io.Reader, interactive bool) error {
+	scanner := bufio.NewScanner(in)
+
+	for line := 1; ; line++ {
+		if interactive {
+			fmt.Fprint(c.out, "> ")
+		}
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 || strings.HasPrefix(args[0], "#") {
+			continue
+		}
+		if args[0] == "exit" {
+			return nil
+		}
+
+		if err := c.Execute(args); err != nil {
+			if !interactive {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			fmt.Fprintf(c.out, "Error: %v\n", err)
+		}
+	}
+}
+
+// Execute runs a single command, e.g. []string{"park", "B1234XYZ"}
+func (c *CLI) Execute(args []string) error {
+	if len(args) == 0 {
+		return errors.ErrUnknownCommand
+	}
+
+	switch args[0] {
+	case "create_lot":
+		return c.createLot(args[1:])
+	case "park":
+		return c.park(args[1:])
+	case "leave":
+		return c.leave(args[1:])
+	case "leave_lost":
+		return c.leaveLostTicket(args[1:])
+	case "status":
+		return c.status(args[1:])
+	case "fee":
+		return c.fee(args[1:])
+	case "reserve":
+		return c.reserve(args[1:])
+	case "cancel_reservation":
+		return c.cancelReservation(args[1:])
+	case "help":
+		fmt.Fprintln(c.out, usage)
+		return nil
+	default:
+		return fmt.Errorf("%w %q, run help for the list of commands", errors.ErrUnknownCommand, args[0])
+	}
+}
+
+func (c *CLI) addLot(lot parkinglot.ParkingLotItf) {
+	c.lots[lot.GetId()] = lot
+	c.attendant.AssignParkingLot(lot.(*parkinglot.ParkingLot))
+}
+
+func (c *CLI) getLot(id string) (parkinglot.ParkingLotItf, error) {
+	lot, exists := c.lots[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", errors.ErrLotNotFound, id)
+	}
+	return lot, nil
+}
+
+func (c *CLI) createLot(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("%w: usage: create_lot <capacity> [lot_id]", errors.ErrInvalidRequest)
+	}
+
+	capacity, err := strconv.Atoi(args[0])
+	if err != nil || capacity <= 0 {
+		return fmt.Errorf("%w: capacity must be a positive number", errors.ErrInvalidRequest)
+	}
+
+	id := uuid.New().String()[:8]
+	if len(args) == 2 {
+		id = args[1]
+	}
+	if _, exists := c.lots[id]; exists {
+		return fmt.Errorf("%w: %s", errors.ErrLotAlreadyExists, id)
+	}
+
+	lot, err := parkinglot.Open(c.repo, id, capacity, c.lotOpts...)
+	if err != nil {
+		return err
+	}
+	c.addLot(lot)
+
+	fmt.Fprintf(c.out, "Created parking lot %s with %d slots\n", id, capacity)
+	return nil
+}
+
+func (c *CLI) park(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("%w: usage: park <license_plate> [lot_id]", errors.ErrInvalidRequest)
+	}
+
+	car := &models.Car{LicensePlate: args[0]}
+
+	var lot parkinglot.ParkingLotItf
+	var ticket *models.Ticket
+	var err error
+	if len(args) == 2 {
+		if lot, err = c.getLot(args[1]); err != nil {
+			return err
+		}
+		ticket, err = lot.Park(car)
+	} else {
+		ticket, err = c.attendant.ParkCar(car)
+	}
+	if err != nil {
+		return err
+	}
+
+	if lot == nil {
+		for _, l := range c.lots {
+			if l.GetParkedCars(ticket) != nil {
+				lot = l
+				break
+			}
+		}
+	}
+
+	fmt.Fprintf(c.out, "Parked %s in lot %s slot %d, ticket %s at %s\n",
+		car.LicensePlate, lot.GetId(), ticket.SlotNumber, ticket.TicketNumber, ticket.EntryTime.Format(time.RFC3339))
+	return nil
+}
+
+func (c *CLI) leave(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: usage: leave <ticket_number>", errors.ErrInvalidRequest)
+	}
+
+	// the ticket is looked up in the repository so cars parked by a previous
+	// run can leave too
+	ticket, err := c.findTicket(args[0])
+	if err != nil {
+		return err
+	}
+
+	receipt, err := c.attendant.UnparkCar(ticket)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "%s left lot %s slot %d after %s, fee %s\n",
+		receipt.Car.LicensePlate, receipt.LotID, receipt.SlotNumber, receipt.Duration.Round(time.Second), receipt.Fee)
+	return nil
+}
+
+func (c *CLI) leaveLostTicket(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: usage: leave_lost <license_plate>", errors.ErrInvalidRequest)
+	}
+
+	receipt, err := c.attendant.UnparkLostTicket(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "%s left lot %s slot %d without a ticket after %s, fee %s\n",
+		receipt.Car.LicensePlate, receipt.LotID, receipt.SlotNumber, receipt.Duration.Round(time.Second), receipt.Fee)
+	return nil
+}
+
+func (c *CLI) findTicket(ticketNumber string) (*models.Ticket, error) {
+	records, err := c.repo.FindAllLots()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if session, exists := record.Sessions[ticketNumber]; exists {
+			ticket := session.Ticket
+			return &ticket, nil
+		}
+	}
+	return nil, errors.ErrTicketNotFound
+}
+
+func (c *CLI) status(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("%w: usage: status [lot_id]", errors.ErrInvalidRequest)
+	}
+
+	var lots []parkinglot.ParkingLotItf
+	if len(args) == 1 {
+		lot, err := c.getLot(args[0])
+		if err != nil {
+			return err
+		}
+		lots = append(lots, lot)
+	} else {
+		for _, lot := range c.lots {
+			lots = append(lots, lot)
+		}
+		sort.Slice(lots, func(i, j int) bool {
+			return lots[i].GetId() < lots[j].GetId()
+		})
+	}
+
+	if len(lots) == 0 {
+		fmt.Fprintln(c.out, "No parking lots")
+		return nil
+	}
+
+	fmt.Fprintf(c.out, "%-10s %8s %9s %s\n", "Lot", "Capacity", "Available", "Full")
+	for _, lot := range lots {
+		status := lot.GetStatus()
+		fmt.Fprintf(c.out, "%-10s %8d %9d %t\n", status.LotID, status.Capacity, status.Available, status.IsFull)
+	}
+	return nil
+}
+
+func (c *CLI) fee(args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("%w: usage: fee <lot_id> <duration> [vehicle_type]", errors.ErrInvalidRequest)
+	}
+
+	lot, err := c.getLot(args[0])
+	if err != nil {
+		return err
+	}
+
+	duration, err := time.ParseDuration(args[1])
+	if err != nil || duration < 0 {
+		return fmt.Errorf("%w: duration must be a non-negative Go duration such as 90m", errors.ErrInvalidRequest)
+	}
+
+	vehicleType := models.VehicleTypeCar
+	if len(args) == 3 {
+		vehicleType = models.VehicleType(args[2])
+	}
+	if !vehicleType.IsValid() {
+		return fmt.Errorf("%w: %q", errors.ErrInvalidVehicleType, vehicleType)
+	}
+
+	fmt.Fprintf(c.out, "Fee for %s in lot %s: %s\n", duration, lot.GetId(), lot.CalculateFee(vehicleType, duration))
+	return nil
+}
+
+func (c *CLI) reserve(args []string) error {
+	if len(args) != 4 {
+		return fmt.Errorf("%w: usage: reserve <lot_id> <license_plate> <start> <end>", errors.ErrInvalidRequest)
+	}
+
+	lot, err := c.getLot(args[0])
+	if err != nil {
+		return err
+	}
+
+	start, startErr := time.Parse(time.RFC3339, args[2])
+	end, endErr := time.Parse(time.RFC3339, args[3])
+	if startErr != nil || endErr != nil {
+		return fmt.Errorf("%w: start and end must be RFC 3339 times such as 2024-03-01T08:00:00Z", errors.ErrInvalidRequest)
+	}
+
+	reservation, err := lot.Reserve(&models.Car{LicensePlate: args[1]}, start, end)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "Reserved a space in lot %s for %s from %s to %s, code %s\n",
+		lot.GetId(), reservation.LicensePlate, start.Format(time.RFC3339), end.Format(time.RFC3339), reservation.Code)
+	return nil
+}
+
+func (c *CLI) cancelReservation(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("%w: usage: cancel_reservation <lot_id> <code>", errors.ErrInvalidRequest)
+	}
+
+	lot, err := c.getLot(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := lot.CancelReservation(args[1]); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "Cancelled reservation %s in lot %s\n", args[1], lot.GetId())
+	return nil
+}
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"bytes"
+	goerrors "errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/clock"
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/logging"
+	"github.com/natanaelrusli/parking-lot/parkinglot"
+	"github.com/natanaelrusli/parking-lot/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+var ticketPattern = regexp.MustCompile(`ticket (\S+)`)
+
+func newTestCLI(t *testing.T, opts ...parkinglot.Option) (*CLI, *bytes.Buffer) {
+	var out bytes.Buffer
+	c, err := New(&out, repository.NewInMemoryRepository(), logging.Nop(), opts...)
+	assert.NoError(t, err)
+	return c, &out
+}
+
+func TestCLICommands(t *testing.T) {
+	t.Run("should create a lot, park a car and show the status", func(t *testing.T) {
+		c, out := newTestCLI(t)
+
+		assert.NoError(t, c.Execute([]string{"create_lot", "2", "lot1"}))
+		assert.NoError(t, c.Execute([]string{"park", "AAA111"}))
+		assert.NoError(t, c.Execute([]string{"status"}))
+
+		assert.Contains(t, out.String(), "Created parking lot lot1 with 2 slots")
+		assert.Contains(t, out.String(), "Parked AAA111 in lot lot1 slot 1")
+		assert.Regexp(t, `lot1\s+2\s+1\s+false`, out.String())
+	})
+
+	t.Run("should bill the car when it leaves", func(t *testing.T) {
+		c0 := clock.NewFakeClock(time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC))
+		c, out := newTestCLI(t, parkinglot.WithClock(c0))
+		_ = c.Execute([]string{"create_lot", "2", "lot1"})
+		_ = c.Execute([]string{"park", "AAA111", "lot1"})
+		ticketNumber := ticketPattern.FindStringSubmatch(out.String())[1]
+
+		c0.Advance(3 * time.Hour)
+		err := c.Execute([]string{"leave", ticketNumber})
+
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "AAA111 left lot lot1 slot 1 after 3h0m0s, fee 30.00")
+	})
+
+	t.Run("should bill a car that lost its ticket for a full day", func(t *testing.T) {
+		c0 := clock.NewFakeClock(time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC))
+		c, out := newTestCLI(t, parkinglot.WithClock(c0))
+		_ = c.Execute([]string{"create_lot", "2", "lot1"})
+		_ = c.Execute([]string{"park", "AAA111", "lot1"})
+
+		c0.Advance(time.Hour)
+		err := c.Execute([]string{"leave_lost", "AAA111"})
+
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "AAA111 left lot lot1 slot 1 without a ticket after 1h0m0s, fee 240.00")
+		assert.True(t, goerrors.Is(c.Execute([]string{"leave_lost", "AAA111"}), errors.ErrCarNotFound))
+	})
+
+	t.Run("should quote a fee", func(t *testing.T) {
+		c, out := newTestCLI(t)
+		_ = c.Execute([]string{"create_lot", "2", "lot1"})
+
+		err := c.Execute([]string{"fee", "lot1", "2h"})
+
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "Fee for 2h0m0s in lot lot1: 20.00")
+		assert.True(t, goerrors.Is(c.Execute([]string{"fee", "lot1", "2h", "tank"}), errors.ErrInvalidVehicleType))
+	})
+
+	t.Run("should reserve a space and park the car holding it", func(t *testing.T) {
+		c0 := clock.NewFakeClock(time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC))
+		c, out := newTestCLI(t, parkinglot.WithClock(c0))
+		_ = c.Execute([]string{"create_lot", "1", "lot1"})
+
+		err := c.Execute([]string{"reserve", "lot1", "AAA111", "2024-03-01T08:00:00Z", "2024-03-01T10:00:00Z"})
+		walkInErr := c.Execute([]string{"park", "BBB222", "lot1"})
+		parkErr := c.Execute([]string{"park", "AAA111", "lot1"})
+
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "Reserved a space in lot lot1 for AAA111 from 2024-03-01T08:00:00Z to 2024-03-01T10:00:00Z")
+		assert.True(t, goerrors.Is(walkInErr, errors.ErrNoAvailablePosition))
+		assert.NoError(t, parkErr)
+	})
+
+	t.Run("should cancel a reservation", func(t *testing.T) {
+		c0 := clock.NewFakeClock(time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC))
+		c, out := newTestCLI(t, parkinglot.WithClock(c0))
+		_ = c.Execute([]string{"create_lot", "1", "lot1"})
+		_ = c.Execute([]string{"reserve", "lot1", "AAA111", "2024-03-01T08:00:00Z", "2024-03-01T10:00:00Z"})
+		code := regexp.MustCompile(`code (\S+)`).FindStringSubmatch(out.String())[1]
+
+		err := c.Execute([]string{"cancel_reservation", "lot1", code})
+
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "Cancelled reservation "+code)
+		assert.NoError(t, c.Execute([]string{"park", "BBB222", "lot1"}))
+		assert.True(t, goerrors.Is(c.Execute([]string{"cancel_reservation", "lot1", code}), errors.ErrReservationNotFound))
+	})
+
+	t.Run("should return errors for invalid commands", func(t *testing.T) {
+		c, _ := newTestCLI(t)
+		_ = c.Execute([]string{"create_lot", "1", "lot1"})
+		_ = c.Execute([]string{"park", "AAA111"})
+
+		assert.True(t, goerrors.Is(c.Execute([]string{"fly"}), errors.ErrUnknownCommand))
+		assert.True(t, goerrors.Is(c.Execute([]string{"create_lot", "zero"}), errors.ErrInvalidRequest))
+		assert.True(t, goerrors.Is(c.Execute([]string{"create_lot", "1", "lot1"}), errors.ErrLotAlreadyExists))
+		assert.True(t, goerrors.Is(c.Execute([]string{"park", "BBB222"}), errors.ErrAllLotsAreFull))
+		assert.True(t, goerrors.Is(c.Execute([]string{"park", "BBB222", "lot2"}), errors.ErrLotNotFound))
+		assert.True(t, goerrors.Is(c.Execute([]string{"leave", "INVALID"}), errors.ErrTicketNotFound))
+		assert.True(t, goerrors.Is(c.Execute([]string{"fee", "lot1", "soon"}), errors.ErrInvalidRequest))
+		assert.True(t, goerrors.Is(c.Execute([]string{"reserve", "lot1", "BBB222", "now", "later"}), errors.ErrInvalidRequest))
+	})
+}
+
+func TestCLIRun(t *testing.T) {
+	t.Run("should run a command file and stop at the first error", func(t *testing.T) {
+		c, out := newTestCLI(t)
+		script := strings.Join([]string{
+			"# a comment",
+			"create_lot 1 lot1",
+			"",
+			"park AAA111",
+			"park BBB222",
+			"status",
+		}, "\n")
+
+		err := c.Run(strings.NewReader(script), false)
+
+		assert.True(t, goerrors.Is(err, errors.ErrAllLotsAreFull))
+		assert.Contains(t, err.Error(), "line 5")
+		assert.NotContains(t, out.String(), "Capacity")
+	})
+
+	t.Run("should keep going after errors in interactive mode", func(t *testing.T) {
+		c, out := newTestCLI(t)
+
+		err := c.Run(strings.NewReader("fly\ncreate_lot 1 lot1\nexit\nstatus\n"), true)
+
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "> Error: unknown command")
+		assert.Contains(t, out.String(), "Created parking lot lot1")
+		assert.NotContains(t, out.String(), "Capacity")
+	})
+
+	t.Run("should let cars parked in a previous run leave", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "parking.log")
+		repo, _ := repository.NewFileRepository(path)
+		var out bytes.Buffer
+		c, _ := New(&out, repo, logging.Nop())
+		_ = c.Run(strings.NewReader("create_lot 2 lot1\npark AAA111\n"), false)
+		ticketNumber := ticketPattern.FindStringSubmatch(out.String())[1]
+		_ = repo.Close()
+
+		repo, _ = repository.NewFileRepository(path)
+		defer repo.Close()
+		c, err := New(&out, repo, logging.Nop())
+		assert.NoError(t, err)
+
+		err = c.Execute([]string{"leave", ticketNumber})
+
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "AAA111 left lot lot1")
+	})
+}
@@ -0,0 +1,34 @@
+package clock
+
+import "time"
+
+// Clock is the source of the current time for anything time-dependent,
+// such as ticket entry times and receipt exit times.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f once the clock has moved on by d, like
+	// time.AfterFunc
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a call scheduled with Clock.AfterFunc
+type Timer interface {
+	// Stop cancels the call, it reports false if the call was already
+	// made or cancelled
+	Stop() bool
+}
+
+type RealClock struct{}
+
+func NewRealClock() Clock {
+	return &RealClock{}
+}
+
+func (c *RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// AfterFunc calls f from a goroutine of its own after d
+func (c *RealClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
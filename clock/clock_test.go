@@ -0,0 +1,74 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealClock(t *testing.T) {
+	t.Run("should return the current time", func(t *testing.T) {
+		before := time.Now()
+		now := NewRealClock().Now()
+		after := time.Now()
+
+		assert.False(t, now.Before(before))
+		assert.False(t, now.After(after))
+	})
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 22, 0, 0, 0, time.UTC)
+
+	t.Run("should not move on its own", func(t *testing.T) {
+		c := NewFakeClock(start)
+
+		assert.Equal(t, start, c.Now())
+		assert.Equal(t, start, c.Now())
+	})
+
+	t.Run("should advance by the given duration", func(t *testing.T) {
+		c := NewFakeClock(start)
+
+		c.Advance(3 * time.Hour)
+
+		assert.Equal(t, time.Date(2024, time.January, 2, 1, 0, 0, 0, time.UTC), c.Now())
+	})
+
+	t.Run("should be set to the given time", func(t *testing.T) {
+		c := NewFakeClock(start)
+		later := start.Add(48 * time.Hour)
+
+		c.Set(later)
+
+		assert.Equal(t, later, c.Now())
+	})
+
+	t.Run("should call the timers due once moved, in the order they are due", func(t *testing.T) {
+		c := NewFakeClock(start)
+		var called []string
+		c.AfterFunc(2*time.Hour, func() { called = append(called, "second") })
+		c.AfterFunc(time.Hour, func() { called = append(called, "first") })
+		c.AfterFunc(3*time.Hour, func() { called = append(called, "third") })
+
+		c.Advance(30 * time.Minute)
+		assert.Empty(t, called)
+		c.Advance(90 * time.Minute)
+		assert.Equal(t, []string{"first", "second"}, called)
+		c.Set(start.Add(3 * time.Hour))
+		assert.Equal(t, []string{"first", "second", "third"}, called)
+	})
+
+	t.Run("should not call stopped timers", func(t *testing.T) {
+		c := NewFakeClock(start)
+		called := false
+		timer := c.AfterFunc(time.Hour, func() { called = true })
+
+		assert.True(t, timer.Stop())
+		c.Advance(time.Hour)
+
+		assert.False(t, called)
+		assert.False(t, timer.Stop())
+	})
+}
@@ -0,0 +1,105 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// FakeClock is a Clock that only moves when told to. It is meant for tests
+// that need deterministic entry and exit times, e.g. overnight stays.
+type FakeClock struct {
+	mu     sync.RWMutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.now
+}
+
+// AfterFunc calls f from the goroutine moving the clock, once it is moved
+// to d from now or later
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timer := &fakeTimer{clock: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+// Advance moves the clock forward by d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	due := c.due()
+	c.mu.Unlock()
+
+	fire(due)
+}
+
+// Set moves the clock to t
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	due := c.due()
+	c.mu.Unlock()
+
+	fire(due)
+}
+
+// due removes the timers due by now and returns them in the order they
+// are due.
+// It must be called with c.mu held.
+func (c *FakeClock) due() []*fakeTimer {
+	var due, pending []*fakeTimer
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+		} else {
+			due = append(due, timer)
+		}
+	}
+	c.timers = pending
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].at.Before(due[j].at)
+	})
+	return due
+}
+
+// fire calls the timers once the clock is unlocked, as they may read it or
+// schedule more timers
+func fire(timers []*fakeTimer) {
+	for _, timer := range timers {
+		timer.f()
+	}
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	at    time.Time
+	f     func()
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/natanaelrusli/parking-lot/api"
+	"github.com/natanaelrusli/parking-lot/fee"
+	"github.com/natanaelrusli/parking-lot/logging"
+	"github.com/natanaelrusli/parking-lot/parkinglot"
+	"github.com/natanaelrusli/parking-lot/repository"
+	"github.com/natanaelrusli/parking-lot/ticket"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	dataFile := flag.String("data", "", "append-only log to persist lots in, in-memory when empty")
+	pricingFile := flag.String("pricing", "", "YAML or JSON pricing config to bill every lot with")
+	ticketKeyFile := flag.String("ticket-key", "", "file holding the key to sign ticket numbers with, unsigned when empty")
+	ticketFormat := flag.String("ticket-format", "random", "how unsigned tickets are numbered: random, sequential or friendly")
+	logLevel := flag.String("log-level", "info", "lowest level logged to stderr: debug, info, warn or error")
+	flag.Parse()
+
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := logging.NewTextLogger(os.Stderr, level)
+
+	var opts []parkinglot.Option
+
+	if *pricingFile != "" {
+		strategy, err := fee.LoadConfig(*pricingFile)
+		if err != nil {
+			log.Fatalf("failed to load %s: %v", *pricingFile, err)
+		}
+		opts = append(opts, parkinglot.WithFeeStrategy(strategy))
+	}
+
+	if *ticketKeyFile != "" {
+		key, err := os.ReadFile(*ticketKeyFile)
+		if err != nil {
+			log.Fatalf("failed to read %s: %v", *ticketKeyFile, err)
+		}
+		signer, err := ticket.NewSigner(bytes.TrimSpace(key))
+		if err != nil {
+			log.Fatalf("failed to use the key in %s: %v", *ticketKeyFile, err)
+		}
+		opts = append(opts, parkinglot.WithTicketSigner(signer))
+	}
+
+	switch *ticketFormat {
+	case "random":
+	case "sequential":
+		opts = append(opts, parkinglot.WithTicketGenerator(ticket.NewSequentialGenerator()))
+	case "friendly":
+		opts = append(opts, parkinglot.WithTicketGenerator(ticket.NewFriendlyGenerator()))
+	default:
+		log.Fatalf("unknown ticket format %q", *ticketFormat)
+	}
+
+	var repo repository.Repository = repository.NewInMemoryRepository()
+	if *dataFile != "" {
+		fileRepo, err := repository.NewFileRepository(*dataFile)
+		if err != nil {
+			log.Fatalf("failed to open %s: %v", *dataFile, err)
+		}
+		defer fileRepo.Close()
+		repo = fileRepo
+	}
+
+	server, err := api.NewServer(repo, logger, opts...)
+	if err != nil {
+		log.Fatalf("failed to restore parking lots: %v", err)
+	}
+
+	logger.Info("parking lot server listening", "addr", *addr)
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -11,8 +11,71 @@ var (
 	ErrNilTicket           = errors.New("cannot unpark without ticket")
 	ErrEmptyTicketNumber   = errors.New("cannot unpark without ticket number")
 	ErrUnrecognizedTicket  = errors.New("unrecognized parking ticket")
+	ErrCarNotFound         = errors.New("car not found in parking lot")
+	ErrInvalidVehicleType  = errors.New("invalid vehicle type")
+	ErrInvalidSlotSize     = errors.New("invalid slot size")
+	ErrNoCompatibleSlot    = errors.New("no available slot fits the vehicle")
+
+	// Ticket errors
+	ErrMalformedTicket        = errors.New("malformed ticket number")
+	ErrForgedTicket           = errors.New("ticket signature does not match, it was forged or altered")
+	ErrInvalidCheckDigit      = errors.New("ticket number check digit does not match")
+	ErrTicketNumbersExhausted = errors.New("could not generate a ticket number that isn't taken")
+	ErrInvalidTicketPayload   = errors.New("invalid ticket payload")
+	ErrTicketPayloadTooLong   = errors.New("ticket payload is too long for the barcode")
+	ErrInvalidScale           = errors.New("barcode scale must be positive")
+	ErrTicketKeyTooShort      = errors.New("ticket signing key is too short")
+
+	// Reservation errors
+	ErrReservationNotFound      = errors.New("reservation not found")
+	ErrInvalidReservationWindow = errors.New("reservation must end after it starts and in the future")
+	ErrFullyBooked              = errors.New("no space left to reserve for the requested time")
+
+	// Permit errors
+	ErrPermitNotFound        = errors.New("permit not found")
+	ErrInvalidPermit         = errors.New("permit needs a license plate and must end after it starts")
+	ErrInvalidPermitCapacity = errors.New("permit capacity must be between zero and the lot capacity")
+
+	// Event errors
+	ErrEventDropped     = errors.New("event dropped, the handler queue is full")
+	ErrDispatcherClosed = errors.New("event dispatcher is closed")
+	ErrHandlerPanicked  = errors.New("event handler panicked")
+
+	// Logging errors
+	ErrInvalidLogLevel = errors.New("invalid log level")
 
 	// Parking attendant errors
 	ErrAllLotsAreFull = errors.New("all parking lots are full")
 	ErrTicketNotFound = errors.New("ticket not found in any parking lot")
+	ErrLotNotAssigned = errors.New("parking lot is not assigned to the attendant")
+
+	// Repository errors
+	ErrLotNotFound     = errors.New("parking lot not found")
+	ErrSessionNotFound = errors.New("parking session not found")
+	ErrCorruptLog      = errors.New("parking lot log is corrupt")
+
+	// API errors
+	ErrLotAlreadyExists       = errors.New("parking lot already exists")
+	ErrAttendantNotFound      = errors.New("parking attendant not found")
+	ErrAttendantAlreadyExists = errors.New("parking attendant already exists")
+	ErrInvalidRequest         = errors.New("invalid request")
+
+	// CLI errors
+	ErrUnknownCommand = errors.New("unknown command")
+
+	// Money errors
+	ErrInvalidCurrency     = errors.New("invalid currency code")
+	ErrInvalidAmount       = errors.New("invalid money amount")
+	ErrCurrencyMismatch    = errors.New("money amounts have different currencies")
+	ErrInvalidRoundingMode = errors.New("invalid rounding mode")
+
+	// Fee errors
+	ErrNoPriceTiers        = errors.New("a tiered fee needs at least one price tier")
+	ErrInvalidTierDuration = errors.New("price tier duration must be positive")
+	ErrNegativeRate        = errors.New("fee rate must not be negative")
+	ErrNegativeIncrement   = errors.New("billing increment must not be negative")
+	ErrNoFeeStrategy       = errors.New("a fee strategy is required")
+
+	// Pricing errors
+	ErrInvalidPricingConfig = errors.New("invalid pricing config")
 )
@@ -46,6 +46,26 @@ func TestErrorMessages(t *testing.T) {
 			err:      ErrUnrecognizedTicket,
 			expected: "unrecognized parking ticket",
 		},
+		{
+			name:     "ErrCarNotFound message",
+			err:      ErrCarNotFound,
+			expected: "car not found in parking lot",
+		},
+		{
+			name:     "ErrInvalidVehicleType message",
+			err:      ErrInvalidVehicleType,
+			expected: "invalid vehicle type",
+		},
+		{
+			name:     "ErrInvalidSlotSize message",
+			err:      ErrInvalidSlotSize,
+			expected: "invalid slot size",
+		},
+		{
+			name:     "ErrNoCompatibleSlot message",
+			err:      ErrNoCompatibleSlot,
+			expected: "no available slot fits the vehicle",
+		},
 		{
 			name:     "ErrAllLotsAreFull message",
 			err:      ErrAllLotsAreFull,
@@ -56,6 +76,191 @@ func TestErrorMessages(t *testing.T) {
 			err:      ErrTicketNotFound,
 			expected: "ticket not found in any parking lot",
 		},
+		{
+			name:     "ErrLotNotFound message",
+			err:      ErrLotNotFound,
+			expected: "parking lot not found",
+		},
+		{
+			name:     "ErrSessionNotFound message",
+			err:      ErrSessionNotFound,
+			expected: "parking session not found",
+		},
+		{
+			name:     "ErrCorruptLog message",
+			err:      ErrCorruptLog,
+			expected: "parking lot log is corrupt",
+		},
+		{
+			name:     "ErrLotAlreadyExists message",
+			err:      ErrLotAlreadyExists,
+			expected: "parking lot already exists",
+		},
+		{
+			name:     "ErrAttendantNotFound message",
+			err:      ErrAttendantNotFound,
+			expected: "parking attendant not found",
+		},
+		{
+			name:     "ErrAttendantAlreadyExists message",
+			err:      ErrAttendantAlreadyExists,
+			expected: "parking attendant already exists",
+		},
+		{
+			name:     "ErrInvalidRequest message",
+			err:      ErrInvalidRequest,
+			expected: "invalid request",
+		},
+		{
+			name:     "ErrMalformedTicket message",
+			err:      ErrMalformedTicket,
+			expected: "malformed ticket number",
+		},
+		{
+			name:     "ErrForgedTicket message",
+			err:      ErrForgedTicket,
+			expected: "ticket signature does not match, it was forged or altered",
+		},
+		{
+			name:     "ErrReservationNotFound message",
+			err:      ErrReservationNotFound,
+			expected: "reservation not found",
+		},
+		{
+			name:     "ErrInvalidReservationWindow message",
+			err:      ErrInvalidReservationWindow,
+			expected: "reservation must end after it starts and in the future",
+		},
+		{
+			name:     "ErrFullyBooked message",
+			err:      ErrFullyBooked,
+			expected: "no space left to reserve for the requested time",
+		},
+		{
+			name:     "ErrPermitNotFound message",
+			err:      ErrPermitNotFound,
+			expected: "permit not found",
+		},
+		{
+			name:     "ErrInvalidPermit message",
+			err:      ErrInvalidPermit,
+			expected: "permit needs a license plate and must end after it starts",
+		},
+		{
+			name:     "ErrInvalidPermitCapacity message",
+			err:      ErrInvalidPermitCapacity,
+			expected: "permit capacity must be between zero and the lot capacity",
+		},
+		{
+			name:     "ErrUnknownCommand message",
+			err:      ErrUnknownCommand,
+			expected: "unknown command",
+		},
+		{
+			name:     "ErrInvalidCurrency message",
+			err:      ErrInvalidCurrency,
+			expected: "invalid currency code",
+		},
+		{
+			name:     "ErrInvalidAmount message",
+			err:      ErrInvalidAmount,
+			expected: "invalid money amount",
+		},
+		{
+			name:     "ErrCurrencyMismatch message",
+			err:      ErrCurrencyMismatch,
+			expected: "money amounts have different currencies",
+		},
+		{
+			name:     "ErrInvalidRoundingMode message",
+			err:      ErrInvalidRoundingMode,
+			expected: "invalid rounding mode",
+		},
+		{
+			name:     "ErrInvalidPricingConfig message",
+			err:      ErrInvalidPricingConfig,
+			expected: "invalid pricing config",
+		},
+		{
+			name:     "ErrInvalidCheckDigit message",
+			err:      ErrInvalidCheckDigit,
+			expected: "ticket number check digit does not match",
+		},
+		{
+			name:     "ErrTicketNumbersExhausted message",
+			err:      ErrTicketNumbersExhausted,
+			expected: "could not generate a ticket number that isn't taken",
+		},
+		{
+			name:     "ErrInvalidTicketPayload message",
+			err:      ErrInvalidTicketPayload,
+			expected: "invalid ticket payload",
+		},
+		{
+			name:     "ErrTicketPayloadTooLong message",
+			err:      ErrTicketPayloadTooLong,
+			expected: "ticket payload is too long for the barcode",
+		},
+		{
+			name:     "ErrInvalidScale message",
+			err:      ErrInvalidScale,
+			expected: "barcode scale must be positive",
+		},
+		{
+			name:     "ErrEventDropped message",
+			err:      ErrEventDropped,
+			expected: "event dropped, the handler queue is full",
+		},
+		{
+			name:     "ErrDispatcherClosed message",
+			err:      ErrDispatcherClosed,
+			expected: "event dispatcher is closed",
+		},
+		{
+			name:     "ErrHandlerPanicked message",
+			err:      ErrHandlerPanicked,
+			expected: "event handler panicked",
+		},
+		{
+			name:     "ErrInvalidLogLevel message",
+			err:      ErrInvalidLogLevel,
+			expected: "invalid log level",
+		},
+		{
+			name:     "ErrLotNotAssigned message",
+			err:      ErrLotNotAssigned,
+			expected: "parking lot is not assigned to the attendant",
+		},
+		{
+			name:     "ErrTicketKeyTooShort message",
+			err:      ErrTicketKeyTooShort,
+			expected: "ticket signing key is too short",
+		},
+		{
+			name:     "ErrNoPriceTiers message",
+			err:      ErrNoPriceTiers,
+			expected: "a tiered fee needs at least one price tier",
+		},
+		{
+			name:     "ErrInvalidTierDuration message",
+			err:      ErrInvalidTierDuration,
+			expected: "price tier duration must be positive",
+		},
+		{
+			name:     "ErrNegativeRate message",
+			err:      ErrNegativeRate,
+			expected: "fee rate must not be negative",
+		},
+		{
+			name:     "ErrNegativeIncrement message",
+			err:      ErrNegativeIncrement,
+			expected: "billing increment must not be negative",
+		},
+		{
+			name:     "ErrNoFeeStrategy message",
+			err:      ErrNoFeeStrategy,
+			expected: "a fee strategy is required",
+		},
 	}
 
 	for _, tt := range tests {
@@ -129,8 +334,49 @@ func TestErrorsAreDistinct(t *testing.T) {
 		ErrNilTicket,
 		ErrEmptyTicketNumber,
 		ErrUnrecognizedTicket,
+		ErrCarNotFound,
+		ErrInvalidVehicleType,
+		ErrInvalidSlotSize,
+		ErrNoCompatibleSlot,
 		ErrAllLotsAreFull,
 		ErrTicketNotFound,
+		ErrLotNotFound,
+		ErrSessionNotFound,
+		ErrCorruptLog,
+		ErrLotAlreadyExists,
+		ErrAttendantNotFound,
+		ErrAttendantAlreadyExists,
+		ErrInvalidRequest,
+		ErrUnknownCommand,
+		ErrMalformedTicket,
+		ErrForgedTicket,
+		ErrReservationNotFound,
+		ErrInvalidReservationWindow,
+		ErrFullyBooked,
+		ErrPermitNotFound,
+		ErrInvalidPermit,
+		ErrInvalidPermitCapacity,
+		ErrInvalidCurrency,
+		ErrInvalidAmount,
+		ErrCurrencyMismatch,
+		ErrInvalidRoundingMode,
+		ErrInvalidPricingConfig,
+		ErrInvalidCheckDigit,
+		ErrTicketNumbersExhausted,
+		ErrInvalidTicketPayload,
+		ErrTicketPayloadTooLong,
+		ErrInvalidScale,
+		ErrEventDropped,
+		ErrDispatcherClosed,
+		ErrHandlerPanicked,
+		ErrInvalidLogLevel,
+		ErrLotNotAssigned,
+		ErrTicketKeyTooShort,
+		ErrNoPriceTiers,
+		ErrInvalidTierDuration,
+		ErrNegativeRate,
+		ErrNegativeIncrement,
+		ErrNoFeeStrategy,
 	}
 
 	// Check for duplicate error messages
@@ -0,0 +1,203 @@
+package event
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/logging"
+)
+
+// Handler receives the events published on a Bus
+type Handler interface {
+	HandleEvent(e Event)
+}
+
+// HandlerFunc lets an ordinary function be used as a Handler
+type HandlerFunc func(e Event)
+
+func (f HandlerFunc) HandleEvent(e Event) {
+	f(e)
+}
+
+// ErrorHandler is told about the events a handler panicked on or missed.
+// It may be called from several goroutines at once.
+type ErrorHandler func(err error)
+
+// LogErrors returns an ErrorHandler that logs the errors to the logger
+func LogErrors(logger logging.Logger) ErrorHandler {
+	return func(err error) {
+		logger.Error("event delivery failed", logging.KeyError, err)
+	}
+}
+
+// logError is the error handler of buses and dispatchers given none, it
+// logs to stderr
+var logError = LogErrors(logging.NewTextLogger(os.Stderr, logging.LevelError))
+
+// Bus delivers the events published on it to every subscribed handler.
+// It is safe for concurrent use.
+//
+// Events are delivered one at a time, in sequence order, so a handler
+// never receives two events at once. A handler may publish, or call back into a
+// lot that does: the events are queued and delivered once it returns.
+//
+// Handlers are called by the publisher, so a slow one holds up the gate
+// that parked the car. Publish returns without delivering when another
+// goroutine is already delivering: that goroutine delivers the event too,
+// and keeps delivering as long as events keep being queued. On a bus
+// shared by busy lots, one gate's goroutine may end up delivering the
+// events of every gate, and a handler may not have received an event yet
+// when Publish returns. Give the bus a Dispatcher to call handlers from
+// goroutines of their own instead.
+type Bus struct {
+	mu            sync.Mutex
+	subscriptions []*Subscription
+	sequence      uint64
+	queue         []Event
+	// set while a goroutine delivers the queued events
+	delivering bool
+	// dispatcher set with WithDispatcher, nil to deliver synchronously
+	dispatcher *Dispatcher
+	onError    ErrorHandler
+}
+
+// BusOption configures a Bus created with NewBus
+type BusOption func(*Bus)
+
+// WithDispatcher makes the bus hand the events to the dispatcher, which
+// delivers them to each handler asynchronously
+func WithDispatcher(dispatcher *Dispatcher) BusOption {
+	return func(b *Bus) {
+		b.dispatcher = dispatcher
+	}
+}
+
+// WithErrorHandler sets what the bus does with the panics of handlers it
+// calls synchronously, e.g. LogErrors. They are logged to stderr by default.
+func WithErrorHandler(onError ErrorHandler) BusOption {
+	return func(b *Bus) {
+		b.onError = onError
+	}
+}
+
+func NewBus(opts ...BusOption) *Bus {
+	b := &Bus{onError: logError}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Subscribe makes the handler receive the events published from now on
+// that pass every filter. Subscribing a handler already subscribed returns
+// its subscription unchanged, so it doesn't receive events twice.
+func (b *Bus) Subscribe(handler Handler, filters ...Filter) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, subscription := range b.subscriptions {
+		if sameHandler(subscription.handler, handler) {
+			return subscription
+		}
+	}
+
+	subscription := &Subscription{bus: b, handler: handler, filters: filters}
+	subscription.delivery = subscription
+	if b.dispatcher != nil {
+		subscription.delivery = b.dispatcher.Wrap(subscription)
+	}
+
+	b.subscriptions = append(b.subscriptions, subscription)
+	return subscription
+}
+
+func (b *Bus) unsubscribe(subscription *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, s := range b.subscriptions {
+		if s == subscription {
+			b.subscriptions = append(b.subscriptions[:i:i], b.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+// Publish numbers the events, stamps the current time on those without
+// one, and delivers them to the subscribed handlers. A handler that panics
+// is reported to the error handler and doesn't keep the others from
+// receiving the event.
+//
+// When called from a handler, or while another goroutine is delivering,
+// Publish only queues the events and returns before they are delivered.
+func (b *Bus) Publish(events ...Event) {
+	b.Enqueue(events...)
+	b.Deliver()
+}
+
+// Enqueue numbers the events, stamps the current time on those without
+// one, and queues them without delivering them. It never calls a handler,
+// so a publisher can enqueue the events of a change under the lock guarding
+// its state: the events of concurrent changes are then numbered and
+// delivered in the order the changes were made. Call Deliver once the lock
+// is released.
+func (b *Bus) Enqueue(events ...Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, e := range events {
+		b.sequence++
+		meta := e.Meta()
+		meta.Sequence = b.sequence
+		if meta.Time.IsZero() {
+			meta.Time = time.Now()
+		}
+		b.queue = append(b.queue, e)
+	}
+}
+
+// Deliver delivers the queued events to the subscribed handlers, or
+// returns at once when another goroutine is already delivering them, see
+// Publish.
+func (b *Bus) Deliver() {
+	b.mu.Lock()
+	if b.delivering {
+		b.mu.Unlock()
+		return
+	}
+	b.delivering = true
+
+	for len(b.queue) > 0 {
+		e := b.queue[0]
+		b.queue = b.queue[1:]
+		subscriptions := make([]*Subscription, len(b.subscriptions))
+		copy(subscriptions, b.subscriptions)
+		b.mu.Unlock()
+
+		for _, subscription := range subscriptions {
+			deliver(subscription.delivery, e, b.onError)
+		}
+
+		b.mu.Lock()
+	}
+
+	b.delivering = false
+	b.mu.Unlock()
+}
+
+// deliver hands the event to the handler, reporting a panic to onError
+// instead of letting it unwind into the publisher
+func deliver(handler Handler, e Event, onError ErrorHandler) {
+	defer func() {
+		if r := recover(); r != nil {
+			onError(fmt.Errorf("%w handling %s event %d: %v", errors.ErrHandlerPanicked, e.Type(), e.Meta().Sequence, r))
+		}
+	}()
+
+	handler.HandleEvent(e)
+}
@@ -0,0 +1,142 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	events []Event
+}
+
+func (r *recorder) HandleEvent(e Event) {
+	r.events = append(r.events, e)
+}
+
+type statusRecorder struct {
+	statuses []models.ParkingLotStatus
+}
+
+func (r *statusRecorder) OnParkingLotStatusChanged(status models.ParkingLotStatus) {
+	r.statuses = append(r.statuses, status)
+}
+
+func TestBus(t *testing.T) {
+	t.Run("should deliver events to every handler in sequence", func(t *testing.T) {
+		bus := NewBus()
+		first, second := &recorder{}, &recorder{}
+		bus.Subscribe(first)
+		bus.Subscribe(second)
+
+		bus.Publish(&LotFull{}, &LotAvailable{})
+		bus.Publish(&CarParked{})
+
+		assert.Len(t, first.events, 3)
+		assert.Equal(t, first.events, second.events)
+		for i, e := range first.events {
+			assert.Equal(t, uint64(i+1), e.Meta().Sequence)
+		}
+		assert.Equal(t, TypeLotFull, first.events[0].Type())
+		assert.Equal(t, TypeCarParked, first.events[2].Type())
+	})
+
+	t.Run("should stamp the time of events without one", func(t *testing.T) {
+		bus := NewBus()
+		at := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+		stamped, unstamped := &LotFull{Metadata: Metadata{Time: at}}, &LotFull{}
+
+		bus.Publish(stamped, unstamped)
+
+		assert.Equal(t, at, stamped.Time)
+		assert.False(t, unstamped.Time.IsZero())
+	})
+
+	t.Run("should deliver events published by a handler after the current one", func(t *testing.T) {
+		bus := NewBus()
+		var delivered []Type
+		bus.Subscribe(HandlerFunc(func(e Event) {
+			delivered = append(delivered, e.Type())
+			if e.Type() == TypeCarParked {
+				bus.Publish(&LotFull{})
+			}
+		}))
+		bus.Subscribe(HandlerFunc(func(e Event) {
+			delivered = append(delivered, e.Type())
+		}))
+
+		bus.Publish(&CarParked{})
+
+		assert.Equal(t, []Type{TypeCarParked, TypeCarParked, TypeLotFull, TypeLotFull}, delivered)
+	})
+
+	t.Run("should only deliver enqueued events once told to", func(t *testing.T) {
+		bus := NewBus()
+		received := &recorder{}
+		bus.Subscribe(received)
+
+		bus.Enqueue(&LotFull{})
+		bus.Enqueue(&LotAvailable{})
+		assert.Empty(t, received.events)
+		bus.Deliver()
+
+		assert.Len(t, received.events, 2)
+		assert.Equal(t, TypeLotFull, received.events[0].Type())
+		assert.Equal(t, uint64(2), received.events[1].Meta().Sequence)
+	})
+
+	t.Run("should leave the events published meanwhile to the goroutine delivering", func(t *testing.T) {
+		bus := NewBus()
+		entered, release := make(chan struct{}), make(chan struct{})
+		var delivered []Type
+		bus.Subscribe(HandlerFunc(func(e Event) {
+			if e.Type() == TypeCarParked {
+				close(entered)
+				<-release
+			}
+			delivered = append(delivered, e.Type())
+		}))
+		done := make(chan struct{})
+		go func() {
+			bus.Publish(&CarParked{})
+			close(done)
+		}()
+		<-entered
+
+		bus.Publish(&LotFull{})
+		assert.Empty(t, delivered)
+		close(release)
+		<-done
+
+		assert.Equal(t, []Type{TypeCarParked, TypeLotFull}, delivered)
+	})
+}
+
+func TestStatusObserver(t *testing.T) {
+	status := models.ParkingLotStatus{LotID: "lot1", Capacity: 2, Available: 1}
+
+	t.Run("should hand the status of cars moving and reservations to the observer", func(t *testing.T) {
+		observer := &statusRecorder{}
+		adapter := NewStatusObserver("lot1", observer)
+		meta := Metadata{LotID: "lot1"}
+
+		adapter.HandleEvent(&CarParked{Metadata: meta, Status: status})
+		adapter.HandleEvent(&CarUnparked{Metadata: meta, Status: status})
+		adapter.HandleEvent(&StatusChanged{Metadata: meta, Status: status})
+		adapter.HandleEvent(&LotFull{Metadata: meta, Status: status})
+		adapter.HandleEvent(&FeeCharged{Metadata: meta})
+
+		assert.Equal(t, []models.ParkingLotStatus{status, status, status}, observer.statuses)
+	})
+
+	t.Run("should ignore other lots", func(t *testing.T) {
+		observer := &statusRecorder{}
+		adapter := NewStatusObserver("lot1", observer)
+
+		adapter.HandleEvent(&CarParked{Metadata: Metadata{LotID: "lot2"}, Status: status})
+
+		assert.Empty(t, observer.statuses)
+	})
+}
@@ -0,0 +1,288 @@
+package event
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+)
+
+// OverflowPolicy is what a Dispatcher does with an event for a handler
+// whose queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes the publisher wait until the handler catches up.
+	// The handler itself can't wait for itself, the events it publishes to
+	// its own full queue are dropped.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest event queued for the handler to
+	// make room for the new one
+	OverflowDropOldest
+	// OverflowDropNewest drops the new event
+	OverflowDropNewest
+)
+
+// DefaultQueueSize is how many events a Dispatcher queues for each handler
+// unless WithQueueSize is given
+const DefaultQueueSize = 64
+
+// Dispatcher delivers events to each handler from a goroutine of its own,
+// through a bounded queue, so a slow handler only holds up itself and a
+// panicking one is recovered and reported. Each handler still receives the
+// events in sequence order, one at a time.
+//
+// Dropped events and panics are reported to the error handler.
+//
+// A handler may publish on the bus, or call into a lot that does. When no
+// other goroutine is delivering, the handler's goroutine delivers its own
+// events, and only it can make room in its queue. With OverflowBlock, the
+// events it publishes to its own full queue are dropped and reported
+// instead of waiting forever.
+type Dispatcher struct {
+	queueSize int
+	policy    OverflowPolicy
+	onError   ErrorHandler
+
+	mu     sync.Mutex
+	queues []*queue
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// DispatcherOption configures a Dispatcher created with NewDispatcher
+type DispatcherOption func(*Dispatcher)
+
+// WithQueueSize sets how many events are queued for each handler
+func WithQueueSize(size int) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.queueSize = size
+	}
+}
+
+// WithOverflowPolicy sets what happens to events for a handler whose queue
+// is full, OverflowBlock by default
+func WithOverflowPolicy(policy OverflowPolicy) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.policy = policy
+	}
+}
+
+// WithDispatchErrorHandler sets what the dispatcher does with dropped
+// events and panics, e.g. LogErrors. They are logged to stderr by default.
+func WithDispatchErrorHandler(onError ErrorHandler) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.onError = onError
+	}
+}
+
+func NewDispatcher(opts ...DispatcherOption) *Dispatcher {
+	d := &Dispatcher{
+		queueSize: DefaultQueueSize,
+		policy:    OverflowBlock,
+		onError:   logError,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if d.queueSize < 1 {
+		d.queueSize = 1
+	}
+
+	return d
+}
+
+// Wrap returns a handler that queues the events for the handler, which
+// receives them from a goroutine of its own. Events handed to the
+// returned handler once the dispatcher is closed are dropped.
+func (d *Dispatcher) Wrap(handler Handler) Handler {
+	q := &queue{dispatcher: d, handler: handler}
+	q.cond = sync.NewCond(&q.mu)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		q.closed = true
+		return q
+	}
+
+	d.queues = append(d.queues, q)
+	d.wg.Add(1)
+	go q.run()
+	return q
+}
+
+// Flush waits until every event queued so far is delivered. It must not be
+// called from a handler of the dispatcher, which would wait for itself.
+func (d *Dispatcher) Flush() {
+	for _, q := range d.getQueues() {
+		q.flush()
+	}
+}
+
+// Close delivers the events already queued, then stops the handler
+// goroutines. It must not be called from a handler of the dispatcher.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	for _, q := range d.getQueues() {
+		q.close()
+	}
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) getQueues() []*queue {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	queues := make([]*queue, len(d.queues))
+	copy(queues, d.queues)
+	return queues
+}
+
+// queue holds the events waiting for a handler. cond is signalled whenever
+// an event is queued or delivered.
+type queue struct {
+	dispatcher *Dispatcher
+	handler    Handler
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	events []Event
+	// id of the goroutine calling the handler
+	goroutine uint64
+	// set while the handler is called
+	busy   bool
+	closed bool
+	// set once the subscription is cancelled
+	stopped bool
+}
+
+func (q *queue) HandleEvent(e Event) {
+	d := q.dispatcher
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for !q.closed && len(q.events) >= d.queueSize {
+		switch d.policy {
+		case OverflowDropOldest:
+			d.onError(dropped(q.events[0]))
+			q.events = q.events[1:]
+		case OverflowDropNewest:
+			d.onError(dropped(e))
+			return
+		default:
+			if q.busy && goroutineID() == q.goroutine {
+				d.onError(fmt.Errorf("%w: %s event %d was published by the handler to its own full queue", errors.ErrEventDropped, e.Type(), e.Meta().Sequence))
+				return
+			}
+			q.cond.Wait()
+		}
+	}
+
+	if q.stopped {
+		return
+	}
+	if q.closed {
+		d.onError(fmt.Errorf("%w: %s event %d was not delivered", errors.ErrDispatcherClosed, e.Type(), e.Meta().Sequence))
+		return
+	}
+
+	q.events = append(q.events, e)
+	q.cond.Broadcast()
+}
+
+func dropped(e Event) error {
+	return fmt.Errorf("%w: %s event %d", errors.ErrEventDropped, e.Type(), e.Meta().Sequence)
+}
+
+// run delivers the queued events until the queue is closed and empty
+func (q *queue) run() {
+	defer q.dispatcher.wg.Done()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.goroutine = goroutineID()
+
+	for {
+		for len(q.events) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.events) == 0 {
+			return
+		}
+
+		e := q.events[0]
+		q.events = q.events[1:]
+		q.busy = true
+		q.cond.Broadcast()
+		q.mu.Unlock()
+
+		deliver(q.handler, e, q.dispatcher.onError)
+
+		q.mu.Lock()
+		q.busy = false
+		q.cond.Broadcast()
+	}
+}
+
+func (q *queue) flush() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.events) > 0 || q.busy {
+		q.cond.Wait()
+	}
+}
+
+// stop drops the queued events and stops the handler goroutine
+func (q *queue) stop() {
+	q.mu.Lock()
+	q.events = nil
+	q.closed = true
+	q.stopped = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	d := q.dispatcher
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, other := range d.queues {
+		if other == q {
+			d.queues = append(d.queues[:i:i], d.queues[i+1:]...)
+			return
+		}
+	}
+}
+
+func (q *queue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// goroutineID returns the id of the calling goroutine, read from the
+// header of its stack trace, e.g. "goroutine 18 [running]:"
+func goroutineID() uint64 {
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+	header = bytes.TrimPrefix(header, []byte("goroutine "))
+	if i := bytes.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+	id, _ := strconv.ParseUint(string(header), 10, 64)
+	return id
+}
@@ -0,0 +1,262 @@
+package event
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// errorRecorder records the errors reported by a bus or dispatcher
+type errorRecorder struct {
+	mu     sync.Mutex
+	errors []error
+}
+
+func (r *errorRecorder) report(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.errors = append(r.errors, err)
+}
+
+func (r *errorRecorder) get() []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]error(nil), r.errors...)
+}
+
+// sequences records the sequence numbers of the events a handler received
+type sequences struct {
+	mu   sync.Mutex
+	seen []uint64
+}
+
+func (s *sequences) HandleEvent(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seen = append(s.seen, e.Meta().Sequence)
+}
+
+func (s *sequences) get() []uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]uint64(nil), s.seen...)
+}
+
+// gated blocks in HandleEvent until the gate is opened
+func gated(gate chan struct{}, handler Handler) Handler {
+	return HandlerFunc(func(e Event) {
+		<-gate
+		handler.HandleEvent(e)
+	})
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("should deliver every event in order", func(t *testing.T) {
+		dispatcher := NewDispatcher()
+		defer dispatcher.Close()
+		bus := NewBus(WithDispatcher(dispatcher))
+		received := &sequences{}
+		bus.Subscribe(received)
+
+		for i := 0; i < 100; i++ {
+			bus.Publish(&CarParked{})
+		}
+		dispatcher.Flush()
+
+		assert.Len(t, received.get(), 100)
+		for i, sequence := range received.get() {
+			assert.Equal(t, uint64(i+1), sequence)
+		}
+	})
+
+	t.Run("should not let a slow handler hold up the others", func(t *testing.T) {
+		dispatcher := NewDispatcher()
+		bus := NewBus(WithDispatcher(dispatcher))
+		gate := make(chan struct{})
+		slow, fast := &sequences{}, &sequences{}
+		bus.Subscribe(gated(gate, slow))
+		bus.Subscribe(fast)
+
+		bus.Publish(&CarParked{}, &CarUnparked{})
+		assert.Eventually(t, func() bool { return len(fast.get()) == 2 }, time.Second, time.Millisecond)
+		assert.Empty(t, slow.get())
+
+		close(gate)
+		dispatcher.Close()
+		assert.Equal(t, []uint64{1, 2}, slow.get())
+	})
+
+	t.Run("should block the publisher until a full queue has room", func(t *testing.T) {
+		dispatcher := NewDispatcher(WithQueueSize(1), WithOverflowPolicy(OverflowBlock))
+		bus := NewBus(WithDispatcher(dispatcher))
+		gate := make(chan struct{})
+		received := &sequences{}
+		bus.Subscribe(gated(gate, received))
+
+		published := make(chan struct{})
+		go func() {
+			// the first event is being handled, the second fills the queue
+			bus.Publish(&CarParked{}, &CarParked{}, &CarParked{})
+			close(published)
+		}()
+
+		select {
+		case <-published:
+			t.Fatal("publish returned while the queue was full")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(gate)
+		<-published
+		dispatcher.Close()
+		assert.Equal(t, []uint64{1, 2, 3}, received.get())
+	})
+
+	t.Run("should drop rather than deadlock when a handler publishes to its own full queue", func(t *testing.T) {
+		reported := &errorRecorder{}
+		dispatcher := NewDispatcher(WithQueueSize(1), WithDispatchErrorHandler(reported.report))
+		bus := NewBus(WithDispatcher(dispatcher))
+		received := &sequences{}
+		bus.Subscribe(HandlerFunc(func(e Event) {
+			received.HandleEvent(e)
+			if e.Type() == TypeCarParked {
+				// the first event fills the queue, the handler delivers
+				// the second to itself as no one else is delivering
+				bus.Publish(&LotFull{}, &LotFull{})
+			}
+		}))
+
+		bus.Publish(&CarParked{})
+		done := make(chan struct{})
+		go func() {
+			dispatcher.Flush()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("the handler publishing to its own queue deadlocked")
+		}
+		dispatcher.Close()
+		assert.Equal(t, []uint64{1, 2}, received.get())
+		assert.Len(t, reported.get(), 1)
+		assert.ErrorIs(t, reported.get()[0], errors.ErrEventDropped)
+	})
+
+	t.Run("should drop the oldest queued event when the queue is full", func(t *testing.T) {
+		reported := &errorRecorder{}
+		dispatcher := NewDispatcher(WithQueueSize(2), WithOverflowPolicy(OverflowDropOldest), WithDispatchErrorHandler(reported.report))
+		bus := NewBus(WithDispatcher(dispatcher))
+		gate := make(chan struct{})
+		received := &sequences{}
+		bus.Subscribe(gated(gate, received))
+
+		bus.Publish(&CarParked{})
+		// wait for the first event to be taken off the queue
+		time.Sleep(10 * time.Millisecond)
+		bus.Publish(&CarParked{}, &CarParked{}, &CarParked{})
+
+		close(gate)
+		dispatcher.Close()
+		assert.Equal(t, []uint64{1, 3, 4}, received.get())
+		assert.Len(t, reported.get(), 1)
+		assert.ErrorIs(t, reported.get()[0], errors.ErrEventDropped)
+	})
+
+	t.Run("should drop the newest event when the queue is full", func(t *testing.T) {
+		reported := &errorRecorder{}
+		dispatcher := NewDispatcher(WithQueueSize(2), WithOverflowPolicy(OverflowDropNewest), WithDispatchErrorHandler(reported.report))
+		bus := NewBus(WithDispatcher(dispatcher))
+		gate := make(chan struct{})
+		received := &sequences{}
+		bus.Subscribe(gated(gate, received))
+
+		bus.Publish(&CarParked{})
+		time.Sleep(10 * time.Millisecond)
+		bus.Publish(&CarParked{}, &CarParked{}, &CarParked{})
+
+		close(gate)
+		dispatcher.Close()
+		assert.Equal(t, []uint64{1, 2, 3}, received.get())
+		assert.Len(t, reported.get(), 1)
+		assert.ErrorIs(t, reported.get()[0], errors.ErrEventDropped)
+	})
+
+	t.Run("should recover and report panicking handlers", func(t *testing.T) {
+		reported := &errorRecorder{}
+		dispatcher := NewDispatcher(WithDispatchErrorHandler(reported.report))
+		bus := NewBus(WithDispatcher(dispatcher))
+		received := &sequences{}
+		bus.Subscribe(HandlerFunc(func(e Event) {
+			if e.Meta().Sequence == 1 {
+				panic("display board offline")
+			}
+		}))
+		bus.Subscribe(received)
+
+		bus.Publish(&CarParked{}, &CarUnparked{})
+		dispatcher.Close()
+
+		assert.Equal(t, []uint64{1, 2}, received.get())
+		assert.Len(t, reported.get(), 1)
+		assert.ErrorIs(t, reported.get()[0], errors.ErrHandlerPanicked)
+		assert.Contains(t, reported.get()[0].Error(), "display board offline")
+	})
+
+	t.Run("should drop events published once closed", func(t *testing.T) {
+		reported := &errorRecorder{}
+		dispatcher := NewDispatcher(WithDispatchErrorHandler(reported.report))
+		bus := NewBus(WithDispatcher(dispatcher))
+		received := &sequences{}
+		bus.Subscribe(received)
+
+		dispatcher.Close()
+		bus.Publish(&CarParked{})
+
+		assert.Empty(t, received.get())
+		assert.Len(t, reported.get(), 1)
+		assert.ErrorIs(t, reported.get()[0], errors.ErrDispatcherClosed)
+	})
+}
+
+func TestBusPanics(t *testing.T) {
+	t.Run("should recover panicking handlers called synchronously", func(t *testing.T) {
+		reported := &errorRecorder{}
+		bus := NewBus(WithErrorHandler(reported.report))
+		received := &sequences{}
+		bus.Subscribe(HandlerFunc(func(e Event) {
+			panic("boom")
+		}))
+		bus.Subscribe(received)
+
+		bus.Publish(&CarParked{})
+		bus.Publish(&CarParked{})
+
+		assert.Equal(t, []uint64{1, 2}, received.get())
+		assert.Len(t, reported.get(), 2)
+		assert.ErrorIs(t, reported.get()[0], errors.ErrHandlerPanicked)
+	})
+
+	t.Run("should log the panics to the logger given", func(t *testing.T) {
+		var buf bytes.Buffer
+		bus := NewBus(WithErrorHandler(LogErrors(logging.NewTextLogger(&buf, logging.LevelInfo))))
+		bus.Subscribe(HandlerFunc(func(e Event) {
+			panic("boom")
+		}))
+
+		bus.Publish(&CarParked{})
+
+		assert.Contains(t, buf.String(), `level=ERROR msg="event delivery failed" err=`)
+		assert.Contains(t, buf.String(), "boom")
+	})
+}
@@ -0,0 +1,141 @@
+package event
+
+import (
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/models"
+)
+
+// Type names a kind of domain event
+type Type string
+
+const (
+	TypeCarParked       Type = "car_parked"
+	TypeCarUnparked     Type = "car_unparked"
+	TypeLotFull         Type = "lot_full"
+	TypeLotAvailable    Type = "lot_available"
+	TypeFeeCharged      Type = "fee_charged"
+	TypeStrategyChanged Type = "strategy_changed"
+	TypeStatusChanged   Type = "status_changed"
+)
+
+// Event is a domain event published on a Bus. Events are published as
+// pointers to the types below, use a type switch to tell them apart.
+type Event interface {
+	Type() Type
+	Meta() *Metadata
+}
+
+// Metadata is common to every event
+type Metadata struct {
+	// Sequence numbers the events published on a bus from 1, in the order
+	// they are delivered
+	Sequence uint64
+	// Time the event happened at, by the clock of the lot it happened in
+	Time time.Time
+	// LotID is the lot the event happened in, empty for attendant events
+	LotID string
+}
+
+func (m *Metadata) Meta() *Metadata {
+	return m
+}
+
+// CarParked is published when a car parks in a lot
+type CarParked struct {
+	Metadata
+	Car    models.Car
+	Ticket models.Ticket
+	// Status of the lot once the car parked
+	Status models.ParkingLotStatus
+}
+
+func (e *CarParked) Type() Type {
+	return TypeCarParked
+}
+
+// CarUnparked is published when a car leaves a lot, with or without its
+// ticket
+type CarUnparked struct {
+	Metadata
+	Car        models.Car
+	Ticket     models.Ticket
+	SlotNumber int
+	LostTicket bool
+	// Status of the lot once the car left
+	Status models.ParkingLotStatus
+}
+
+func (e *CarUnparked) Type() Type {
+	return TypeCarUnparked
+}
+
+// LotFull is published when the last space walk-ins can take in a lot is
+// taken
+type LotFull struct {
+	Metadata
+	Status models.ParkingLotStatus
+}
+
+func (e *LotFull) Type() Type {
+	return TypeLotFull
+}
+
+// LotAvailable is published when a space frees up in a full lot
+type LotAvailable struct {
+	Metadata
+	Status models.ParkingLotStatus
+}
+
+func (e *LotAvailable) Type() Type {
+	return TypeLotAvailable
+}
+
+// FeeCharged is published when a car leaving a lot is billed, including
+// the zero fee of cars parked with a permit
+type FeeCharged struct {
+	Metadata
+	Receipt models.Receipt
+}
+
+func (e *FeeCharged) Type() Type {
+	return TypeFeeCharged
+}
+
+// StatusChanged is published when the spaces available in a lot change
+// without a car moving, e.g. when a reservation is made, cancelled or
+// expires
+type StatusChanged struct {
+	Metadata
+	Status models.ParkingLotStatus
+}
+
+func (e *StatusChanged) Type() Type {
+	return TypeStatusChanged
+}
+
+// StrategyKind tells apart the strategies a StrategyChanged event is about
+type StrategyKind string
+
+const (
+	// StrategyKindFee is the fee strategy of a lot
+	StrategyKindFee StrategyKind = "fee"
+	// StrategyKindParkingStyle is the parking style of an attendant
+	StrategyKindParkingStyle StrategyKind = "parking_style"
+)
+
+// StrategyChanged is published when a lot changes its fee strategy or an
+// attendant its parking style
+type StrategyChanged struct {
+	Metadata
+	Kind StrategyKind
+	// Attendant that changed its parking style, empty for fee strategies
+	Attendant string
+	// Names of the strategies before and after the change
+	Previous string
+	Current  string
+}
+
+func (e *StrategyChanged) Type() Type {
+	return TypeStrategyChanged
+}
@@ -0,0 +1,33 @@
+package event
+
+import "github.com/natanaelrusli/parking-lot/models"
+
+// StatusObserver adapts a models.ParkingLotObserver to the bus: the
+// observer is handed the lot status after every car that parks in or
+// leaves the lot, and every reservation change, as before events were
+// introduced
+type StatusObserver struct {
+	lotID    string
+	observer models.ParkingLotObserver
+}
+
+// NewStatusObserver adapts the observer to receive the status of the lot
+// with the given ID, or of every lot publishing on the bus when it is empty
+func NewStatusObserver(lotID string, observer models.ParkingLotObserver) *StatusObserver {
+	return &StatusObserver{lotID: lotID, observer: observer}
+}
+
+func (s *StatusObserver) HandleEvent(e Event) {
+	if s.lotID != "" && e.Meta().LotID != s.lotID {
+		return
+	}
+
+	switch e := e.(type) {
+	case *CarParked:
+		s.observer.OnParkingLotStatusChanged(e.Status)
+	case *CarUnparked:
+		s.observer.OnParkingLotStatusChanged(e.Status)
+	case *StatusChanged:
+		s.observer.OnParkingLotStatusChanged(e.Status)
+	}
+}
@@ -0,0 +1,88 @@
+package event
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// Filter selects the events a subscription receives
+type Filter func(e Event) bool
+
+// OfType selects the events of the given types
+func OfType(types ...Type) Filter {
+	return func(e Event) bool {
+		for _, t := range types {
+			if e.Type() == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ForLot selects the events that happened in the given lots
+func ForLot(lotIDs ...string) Filter {
+	return func(e Event) bool {
+		for _, id := range lotIDs {
+			if e.Meta().LotID == id {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Subscription is a handler subscribed to a Bus. Cancel it to stop the
+// handler receiving events.
+type Subscription struct {
+	bus *Bus
+	// handler as given to Subscribe
+	handler Handler
+	filters []Filter
+	// what the bus delivers to, the subscription itself or the dispatcher
+	// queue wrapping it
+	delivery  Handler
+	cancelled atomic.Bool
+}
+
+// HandleEvent hands the event to the handler when it passes every filter
+// and the subscription wasn't cancelled
+func (s *Subscription) HandleEvent(e Event) {
+	if s.cancelled.Load() {
+		return
+	}
+	for _, filter := range s.filters {
+		if !filter(e) {
+			return
+		}
+	}
+	s.handler.HandleEvent(e)
+}
+
+// Cancel unsubscribes the handler. Events queued for it are dropped, but
+// an event it is handling right now is handled to the end. Cancelling
+// twice does nothing.
+func (s *Subscription) Cancel() {
+	if s.cancelled.Swap(true) {
+		return
+	}
+
+	s.bus.unsubscribe(s)
+	if q, ok := s.delivery.(*queue); ok {
+		q.stop()
+	}
+}
+
+// Active reports whether the subscription wasn't cancelled
+func (s *Subscription) Active() bool {
+	return !s.cancelled.Load()
+}
+
+// sameHandler reports whether a and b are the same handler. Handlers
+// whose type can't be compared, e.g. HandlerFunc, are never the same.
+func sameHandler(a, b Handler) bool {
+	if !reflect.TypeOf(a).Comparable() || !reflect.TypeOf(b).Comparable() {
+		return false
+	}
+	return a == b
+}
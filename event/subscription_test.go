@@ -0,0 +1,95 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscription(t *testing.T) {
+	t.Run("should stop delivering events once cancelled", func(t *testing.T) {
+		bus := NewBus()
+		received := &sequences{}
+		subscription := bus.Subscribe(received)
+
+		bus.Publish(&CarParked{})
+		subscription.Cancel()
+		subscription.Cancel()
+		bus.Publish(&CarParked{})
+
+		assert.Equal(t, []uint64{1}, received.get())
+		assert.False(t, subscription.Active())
+	})
+
+	t.Run("should stop a cancelled handler from inside its handler", func(t *testing.T) {
+		bus := NewBus()
+		calls := 0
+		var subscription *Subscription
+		subscription = bus.Subscribe(HandlerFunc(func(e Event) {
+			calls++
+			subscription.Cancel()
+		}))
+
+		bus.Publish(&CarParked{}, &CarParked{})
+
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should drop the events queued for a cancelled async handler", func(t *testing.T) {
+		reported := &errorRecorder{}
+		dispatcher := NewDispatcher(WithDispatchErrorHandler(reported.report))
+		bus := NewBus(WithDispatcher(dispatcher))
+		gate := make(chan struct{})
+		received := &sequences{}
+		subscription := bus.Subscribe(gated(gate, received))
+
+		bus.Publish(&CarParked{}, &CarParked{}, &CarParked{})
+		subscription.Cancel()
+		close(gate)
+		bus.Publish(&CarParked{})
+		dispatcher.Close()
+
+		// only the event being handled when it was cancelled gets through
+		assert.LessOrEqual(t, len(received.get()), 1)
+		assert.Empty(t, reported.get())
+	})
+
+	t.Run("should only deliver the events passing every filter", func(t *testing.T) {
+		bus := NewBus()
+		received := &sequences{}
+		bus.Subscribe(received, OfType(TypeCarParked, TypeCarUnparked), ForLot("lot1"))
+
+		bus.Publish(
+			&CarParked{Metadata: Metadata{LotID: "lot1"}},
+			&LotFull{Metadata: Metadata{LotID: "lot1"}},
+			&CarUnparked{Metadata: Metadata{LotID: "lot2"}},
+			&CarUnparked{Metadata: Metadata{LotID: "lot1"}},
+		)
+
+		assert.Equal(t, []uint64{1, 4}, received.get())
+	})
+
+	t.Run("should not subscribe the same handler twice", func(t *testing.T) {
+		bus := NewBus()
+		received := &sequences{}
+
+		first := bus.Subscribe(received)
+		second := bus.Subscribe(received)
+		bus.Publish(&CarParked{})
+
+		assert.Same(t, first, second)
+		assert.Equal(t, []uint64{1}, received.get())
+	})
+
+	t.Run("should subscribe handler funcs every time", func(t *testing.T) {
+		bus := NewBus()
+		calls := 0
+		handler := HandlerFunc(func(e Event) { calls++ })
+
+		bus.Subscribe(handler)
+		bus.Subscribe(handler)
+		bus.Publish(&CarParked{})
+
+		assert.Equal(t, 2, calls)
+	})
+}
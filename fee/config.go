@@ -0,0 +1,380 @@
+package fee
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/money"
+	"gopkg.in/yaml.v3"
+)
+
+// PricingConfig describes a fee strategy declaratively, so tariffs can be
+// changed by editing a file. It is written in YAML, or in JSON which is
+// read as YAML. Amounts are decimals such as "12.50" and durations are Go
+// durations such as "1h30m". For example:
+//
+//	currency: USD
+//	rounding: half_up
+//	strategy:
+//	  type: tiered
+//	  tiers:
+//	    - {duration: 1h, rate_per_hour: "5.00", increment: 1h}
+//	    - {duration: 3h, rate_per_hour: "3.00", increment: 1h}
+//	    - {rate_per_hour: "2.00", increment: 1h}
+//	  grace_period: 15m
+//	  daily_maximum: "25.00"
+type PricingConfig struct {
+	// ISO 4217 code all amounts are in
+	Currency string `yaml:"currency"`
+	// half_up, half_even, up or down; half_up when empty
+	Rounding string `yaml:"rounding"`
+	// IANA time zone tariff bands are evaluated in; the time zone of the
+	// entry time when empty
+	Location string         `yaml:"location"`
+	Strategy StrategyConfig `yaml:"strategy"`
+}
+
+// StrategyConfig is a strategy of the given type. Which fields are used
+// depends on the type:
+//
+//	flat          amount
+//	hourly        rate_per_hour
+//	tariff        default_rate, bands
+//	tiered        tiers
+//	vehicle_type  default, vehicles
+//
+// Every type can be wrapped in fee modifiers, applied from the inside out:
+// billing_increment, daily_maximum, minimum_charge then grace_period.
+type StrategyConfig struct {
+	Type        string                     `yaml:"type"`
+	Amount      string                     `yaml:"amount"`
+	RatePerHour string                     `yaml:"rate_per_hour"`
+	DefaultRate string                     `yaml:"default_rate"`
+	Bands       []BandConfig               `yaml:"bands"`
+	Tiers       []TierConfig               `yaml:"tiers"`
+	Default     *StrategyConfig            `yaml:"default"`
+	Vehicles    map[string]*StrategyConfig `yaml:"vehicles"`
+
+	BillingIncrement string `yaml:"billing_increment"`
+	DailyMaximum     string `yaml:"daily_maximum"`
+	MinimumCharge    string `yaml:"minimum_charge"`
+	GracePeriod      string `yaml:"grace_period"`
+}
+
+// BandConfig is a TariffBand. Days are mon to sun, or weekdays and weekend,
+// every day when empty. Start and End are times of day such as 07:30, End
+// may be 24:00.
+type BandConfig struct {
+	Name        string   `yaml:"name"`
+	Days        []string `yaml:"days"`
+	Start       string   `yaml:"start"`
+	End         string   `yaml:"end"`
+	RatePerHour string   `yaml:"rate_per_hour"`
+}
+
+// TierConfig is a PriceTier. Duration may be left out on the last tier.
+type TierConfig struct {
+	Duration    string `yaml:"duration"`
+	RatePerHour string `yaml:"rate_per_hour"`
+	Increment   string `yaml:"increment"`
+}
+
+// ConfigError lists every problem found in a pricing config. It wraps
+// errors.ErrInvalidPricingConfig.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%v: %s", errors.ErrInvalidPricingConfig, strings.Join(e.Problems, "; "))
+}
+
+func (e *ConfigError) Unwrap() error {
+	return errors.ErrInvalidPricingConfig
+}
+
+// LoadConfig reads the pricing config file at path and builds its strategy
+func LoadConfig(path string) (ParkingFeeStrategy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig parses a YAML or JSON pricing config and builds its strategy.
+// Unknown fields are rejected so typos don't go unnoticed.
+func ParseConfig(data []byte) (ParkingFeeStrategy, error) {
+	var config PricingConfig
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, &ConfigError{Problems: []string{err.Error()}}
+	}
+
+	return config.Build()
+}
+
+// Build validates the config and builds its strategy. All problems are
+// reported at once in a ConfigError.
+func (c PricingConfig) Build() (ParkingFeeStrategy, error) {
+	b := &configBuilder{rounding: money.RoundHalfUp}
+
+	currency, err := money.ParseCurrency(c.Currency)
+	if err != nil {
+		b.problem("currency", "%q is not an ISO 4217 code", c.Currency)
+	}
+	b.currency = currency
+
+	if c.Rounding != "" {
+		if b.rounding, err = money.ParseRoundingMode(c.Rounding); err != nil {
+			b.problem("rounding", "%q is not one of half_up, half_even, up or down", c.Rounding)
+		}
+	}
+
+	if c.Location != "" {
+		if b.location, err = time.LoadLocation(c.Location); err != nil {
+			b.problem("location", "unknown time zone %q", c.Location)
+		}
+	}
+
+	strategy := b.strategy("strategy", &c.Strategy)
+	if len(b.problems) > 0 {
+		return nil, &ConfigError{Problems: b.problems}
+	}
+	return strategy, nil
+}
+
+type configBuilder struct {
+	currency money.Currency
+	rounding money.RoundingMode
+	location *time.Location
+	problems []string
+}
+
+func (b *configBuilder) problem(path string, format string, args ...interface{}) {
+	b.problems = append(b.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (b *configBuilder) strategy(path string, c *StrategyConfig) ParkingFeeStrategy {
+	if c == nil {
+		b.problem(path, "missing strategy")
+		return nil
+	}
+
+	var strategy ParkingFeeStrategy
+	switch c.Type {
+	case "flat":
+		strategy = NewFlatFeeStrategy(b.amount(path+".amount", c.Amount))
+	case "hourly":
+		strategy = NewHourlyFeeStrategy(b.amount(path+".rate_per_hour", c.RatePerHour), b.rounding)
+	case "tariff":
+		strategy = b.tariff(path, c)
+	case "tiered":
+		strategy = b.tiered(path, c)
+	case "vehicle_type":
+		strategy = b.vehicleType(path, c)
+	case "":
+		b.problem(path+".type", "missing strategy type")
+	default:
+		b.problem(path+".type", "unknown strategy type %q, expected flat, hourly, tariff, tiered or vehicle_type", c.Type)
+	}
+
+	if c.BillingIncrement != "" {
+		strategy = NewBillingIncrementStrategy(strategy, b.duration(path+".billing_increment", c.BillingIncrement))
+	}
+	if c.DailyMaximum != "" {
+		strategy = b.bound(path+".daily_maximum", strategy, c.DailyMaximum, NewDailyMaximumStrategy)
+	}
+	if c.MinimumCharge != "" {
+		strategy = b.bound(path+".minimum_charge", strategy, c.MinimumCharge, NewMinimumChargeStrategy)
+	}
+	if c.GracePeriod != "" {
+		strategy = NewGracePeriodStrategy(strategy, b.duration(path+".grace_period", c.GracePeriod))
+	}
+	return strategy
+}
+
+// bound wraps the strategy in a daily maximum or minimum charge of the
+// amount, unless the strategy or the amount already have problems
+func (b *configBuilder) bound(path string, strategy ParkingFeeStrategy, value string, wrap func(ParkingFeeStrategy, money.Money) (ParkingFeeStrategy, error)) ParkingFeeStrategy {
+	problems := len(b.problems)
+	amount := b.amount(path, value)
+	if strategy == nil || len(b.problems) > problems || !b.currency.IsValid() {
+		return nil
+	}
+
+	bounded, err := wrap(strategy, amount)
+	if err != nil {
+		b.problem(path, "%v", err)
+	}
+	return bounded
+}
+
+func (b *configBuilder) tariff(path string, c *StrategyConfig) ParkingFeeStrategy {
+	defaultRate := b.amount(path+".default_rate", c.DefaultRate)
+
+	bands := make([]TariffBand, len(c.Bands))
+	for i, band := range c.Bands {
+		bandPath := fmt.Sprintf("%s.bands[%d]", path, i)
+		bands[i] = TariffBand{
+			Name:        band.Name,
+			Days:        b.weekdays(bandPath+".days", band.Days),
+			Start:       b.timeOfDay(bandPath+".start", band.Start),
+			End:         b.timeOfDay(bandPath+".end", band.End),
+			RatePerHour: b.amount(bandPath+".rate_per_hour", band.RatePerHour),
+		}
+	}
+
+	strategy, err := NewTariffScheduleStrategy(b.location, defaultRate, b.rounding, bands...)
+	if err != nil {
+		b.problem(path, "%v", err)
+	}
+	return strategy
+}
+
+func (b *configBuilder) tiered(path string, c *StrategyConfig) ParkingFeeStrategy {
+	if len(c.Tiers) == 0 {
+		b.problem(path+".tiers", "a tiered strategy needs at least one tier")
+		return nil
+	}
+
+	problems := len(b.problems)
+	tiers := make([]PriceTier, len(c.Tiers))
+	for i, tier := range c.Tiers {
+		tierPath := fmt.Sprintf("%s.tiers[%d]", path, i)
+		last := i == len(c.Tiers)-1
+
+		if tier.Duration != "" || !last {
+			tiers[i].Duration = b.duration(tierPath+".duration", tier.Duration)
+		}
+		if tier.Increment != "" {
+			tiers[i].Increment = b.duration(tierPath+".increment", tier.Increment)
+		}
+		tiers[i].RatePerHour = b.amount(tierPath+".rate_per_hour", tier.RatePerHour)
+	}
+	// the tiers are already reported, don't report them again as a whole
+	if len(b.problems) > problems {
+		return nil
+	}
+
+	strategy, err := NewTieredFeeStrategy(b.rounding, tiers...)
+	if err != nil {
+		b.problem(path, "%v", err)
+	}
+	return strategy
+}
+
+func (b *configBuilder) vehicleType(path string, c *StrategyConfig) ParkingFeeStrategy {
+	problems := len(b.problems)
+	fallback := b.strategy(path+".default", c.Default)
+
+	// build in a stable order so problems are always reported the same way
+	names := make([]string, 0, len(c.Vehicles))
+	for name := range c.Vehicles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	strategies := make(map[models.VehicleType]ParkingFeeStrategy, len(c.Vehicles))
+	for _, name := range names {
+		vehiclePath := path + ".vehicles." + name
+		vehicleType := models.VehicleType(name)
+		if !vehicleType.IsValid() {
+			b.problem(vehiclePath, "unknown vehicle type %q", name)
+			continue
+		}
+		strategies[vehicleType] = b.strategy(vehiclePath, c.Vehicles[name])
+	}
+
+	// the strategies are already reported, don't report them again as a whole
+	if len(b.problems) > problems {
+		return nil
+	}
+
+	strategy, err := NewVehicleTypeFeeStrategy(fallback, strategies)
+	if err != nil {
+		b.problem(path, "%v", err)
+	}
+	return strategy
+}
+
+// amount parses a non-negative amount in the config currency
+func (b *configBuilder) amount(path string, value string) money.Money {
+	if value == "" {
+		b.problem(path, "missing amount")
+		return money.Zero(b.currency)
+	}
+	if !b.currency.IsValid() {
+		// already reported, the amount can't be checked without it
+		return money.Money{}
+	}
+
+	m, err := money.Parse(value, b.currency)
+	if err != nil {
+		b.problem(path, "%q is not a valid %s amount", value, b.currency)
+		return money.Zero(b.currency)
+	}
+	if m.IsNegative() {
+		b.problem(path, "amount %s must not be negative", value)
+	}
+	return m
+}
+
+// duration parses a positive duration
+func (b *configBuilder) duration(path string, value string) time.Duration {
+	d, err := time.ParseDuration(value)
+	switch {
+	case value == "":
+		b.problem(path, "missing duration")
+	case err != nil:
+		b.problem(path, "%q is not a duration such as 15m or 1h30m", value)
+	case d <= 0:
+		b.problem(path, "duration %s must be positive", value)
+	}
+	return d
+}
+
+// timeOfDay parses HH:MM into an offset from midnight
+func (b *configBuilder) timeOfDay(path string, value string) time.Duration {
+	var hours, minutes int
+	_, err := fmt.Sscanf(value, "%2d:%2d", &hours, &minutes)
+	offset := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+	if err != nil || len(value) != 5 || hours < 0 || minutes < 0 || minutes > 59 || offset > 24*time.Hour {
+		b.problem(path, "%q is not a time of day between 00:00 and 24:00", value)
+		return 0
+	}
+	return offset
+}
+
+var weekdayNames = map[string][]time.Weekday{
+	"mon":      {time.Monday},
+	"tue":      {time.Tuesday},
+	"wed":      {time.Wednesday},
+	"thu":      {time.Thursday},
+	"fri":      {time.Friday},
+	"sat":      {time.Saturday},
+	"sun":      {time.Sunday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekend":  {time.Saturday, time.Sunday},
+}
+
+func (b *configBuilder) weekdays(path string, names []string) []time.Weekday {
+	var days []time.Weekday
+	for i, name := range names {
+		d, exists := weekdayNames[strings.ToLower(name)]
+		if !exists {
+			b.problem(fmt.Sprintf("%s[%d]", path, i), "unknown day %q, expected mon to sun, weekdays or weekend", name)
+			continue
+		}
+		days = append(days, d...)
+	}
+	return days
+}
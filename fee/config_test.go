@@ -0,0 +1,182 @@
+package fee_test
+
+import (
+	goerrors "errors"
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/car"
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/fee"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	strategy, err := fee.LoadConfig("testdata/pricing.yaml")
+	assert.NoError(t, err)
+
+	// 2024-03-04 is a Monday
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+	bill := func(vehicleType models.VehicleType, entry time.Time, exit time.Time) money.Money {
+		return strategy.CalculateFee(fee.NewStay(car.NewVehicle("AAA111", vehicleType), &models.Ticket{EntryTime: entry}, exit))
+	}
+
+	tests := []struct {
+		name        string
+		vehicleType models.VehicleType
+		entry       time.Time
+		exit        time.Time
+		expected    money.Money
+	}{
+		{"should let short stays park for free", models.VehicleTypeCar, at(4, 12, 0), at(4, 12, 10), usd(0)},
+		{"should bill started quarters", models.VehicleTypeCar, at(4, 12, 0), at(4, 12, 20), money.FromMinor(200, money.USD)},
+		{"should bill peak hours", models.VehicleTypeCar, at(4, 6, 0), at(4, 11, 0), usd(4 + 3*6 + 4)},
+		{"should bill weekends", models.VehicleTypeVan, at(2, 10, 0), at(2, 13, 0), usd(3 * 2)},
+		{"should cap every day", models.VehicleTypeCar, at(4, 10, 0), at(4, 22, 0), usd(40)},
+		{"should bill motorcycles a flat fee", models.VehicleTypeMotorcycle, at(4, 6, 0), at(4, 11, 0), money.FromMinor(250, money.USD)},
+		{"should bill buses by the hour", models.VehicleTypeBus, at(4, 6, 0), at(4, 11, 0), usd(60)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, bill(tt.vehicleType, tt.entry, tt.exit))
+		})
+	}
+
+	t.Run("should report a missing file", func(t *testing.T) {
+		_, err := fee.LoadConfig("testdata/missing.yaml")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Run("should parse JSON", func(t *testing.T) {
+		strategy, err := fee.ParseConfig([]byte(`{
+			"currency": "idr",
+			"strategy": {
+				"type": "tiered",
+				"tiers": [
+					{"duration": "1h", "rate_per_hour": 5000, "increment": "1h"},
+					{"duration": "3h", "rate_per_hour": "3000", "increment": "1h"},
+					{"rate_per_hour": "2000", "increment": "1h"}
+				],
+				"minimum_charge": "6000"
+			}
+		}`))
+		assert.NoError(t, err)
+
+		entry := time.Date(2024, time.March, 4, 8, 0, 0, 0, time.UTC)
+		assert.Equal(t, money.FromMajor(6000, money.IDR), strategy.CalculateFee(stayUntil(entry, entry.Add(time.Hour))))
+		assert.Equal(t, money.FromMajor(5000+3*3000+2000, money.IDR), strategy.CalculateFee(stayUntil(entry, entry.Add(5*time.Hour))))
+	})
+
+	t.Run("should default to rounding half up", func(t *testing.T) {
+		strategy, err := fee.ParseConfig([]byte("currency: USD\nstrategy: {type: tariff, default_rate: 10}\n"))
+		assert.NoError(t, err)
+
+		entry := time.Date(2024, time.March, 4, 8, 0, 0, 0, time.UTC)
+		assert.Equal(t, money.FromMinor(333, money.USD), strategy.CalculateFee(stayUntil(entry, entry.Add(20*time.Minute))))
+	})
+
+	tests := []struct {
+		name     string
+		config   string
+		problems []string
+	}{
+		{
+			name:     "malformed YAML",
+			config:   "currency: [USD",
+			problems: []string{"yaml: line 1: did not find expected ',' or ']'"},
+		},
+		{
+			name:     "unknown field",
+			config:   "currency: USD\nstrategy: {type: hourly, rate: 10}",
+			problems: []string{"yaml: unmarshal errors:\n  line 2: field rate not found in type fee.StrategyConfig"},
+		},
+		{
+			name:   "invalid header",
+			config: "currency: dollars\nrounding: sideways\nlocation: Mars/Olympus\nstrategy: {type: flat, amount: 1}",
+			problems: []string{
+				`currency: "dollars" is not an ISO 4217 code`,
+				`rounding: "sideways" is not one of half_up, half_even, up or down`,
+				`location: unknown time zone "Mars/Olympus"`,
+			},
+		},
+		{
+			name:     "missing strategy type",
+			config:   "currency: USD",
+			problems: []string{"strategy.type: missing strategy type"},
+		},
+		{
+			name:     "unknown strategy type",
+			config:   "currency: USD\nstrategy: {type: free}",
+			problems: []string{`strategy.type: unknown strategy type "free", expected flat, hourly, tariff, tiered or vehicle_type`},
+		},
+		{
+			name:   "invalid amounts",
+			config: "currency: USD\nstrategy: {type: hourly, rate_per_hour: '1.005', daily_maximum: '-5', minimum_charge: lots}",
+			problems: []string{
+				`strategy.rate_per_hour: "1.005" is not a valid USD amount`,
+				"strategy.daily_maximum: amount -5 must not be negative",
+				`strategy.minimum_charge: "lots" is not a valid USD amount`,
+			},
+		},
+		{
+			name:   "invalid bands",
+			config: "currency: USD\nstrategy:\n  type: tariff\n  default_rate: 2\n  bands:\n    - {days: [mon, someday], start: '7:00', end: '25:00', rate_per_hour: 4}",
+			problems: []string{
+				`strategy.bands[0].days[1]: unknown day "someday", expected mon to sun, weekdays or weekend`,
+				`strategy.bands[0].start: "7:00" is not a time of day between 00:00 and 24:00`,
+				`strategy.bands[0].end: "25:00" is not a time of day between 00:00 and 24:00`,
+			},
+		},
+		{
+			name:   "invalid tiers",
+			config: "currency: USD\nstrategy:\n  type: tiered\n  tiers:\n    - {rate_per_hour: 5}\n    - {duration: soon, rate_per_hour: 3, increment: -1h}\n    - {}",
+			problems: []string{
+				"strategy.tiers[0].duration: missing duration",
+				`strategy.tiers[1].duration: "soon" is not a duration such as 15m or 1h30m`,
+				"strategy.tiers[1].increment: duration -1h must be positive",
+				"strategy.tiers[2].rate_per_hour: missing amount",
+			},
+		},
+		{
+			name:     "no tiers",
+			config:   "currency: USD\nstrategy: {type: tiered, grace_period: 0s}",
+			problems: []string{"strategy.tiers: a tiered strategy needs at least one tier", "strategy.grace_period: duration 0s must be positive"},
+		},
+		{
+			name:   "invalid vehicle strategies",
+			config: "currency: USD\nstrategy:\n  type: vehicle_type\n  vehicles:\n    tank: {type: flat, amount: 9}\n    bus: {type: hourly}",
+			problems: []string{
+				"strategy.default: missing strategy",
+				"strategy.vehicles.bus.rate_per_hour: missing amount",
+				`strategy.vehicles.tank: unknown vehicle type "tank"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("should report "+tt.name, func(t *testing.T) {
+			strategy, err := fee.ParseConfig([]byte(tt.config))
+
+			assert.Nil(t, strategy)
+			assert.True(t, goerrors.Is(err, errors.ErrInvalidPricingConfig))
+			var configErr *fee.ConfigError
+			if assert.True(t, goerrors.As(err, &configErr)) {
+				assert.Equal(t, tt.problems, configErr.Problems)
+			}
+		})
+	}
+
+	t.Run("should list every problem in the error message", func(t *testing.T) {
+		_, err := fee.ParseConfig([]byte("currency: USD\nstrategy: {type: hourly, grace_period: soon}"))
+
+		assert.EqualError(t, err, `invalid pricing config: strategy.rate_per_hour: missing amount; strategy.grace_period: "soon" is not a duration such as 15m or 1h30m`)
+	})
+}
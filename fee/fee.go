@@ -1,7 +1,69 @@
 package fee
 
-import "time"
+import (
+	"reflect"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/money"
+)
 
 type ParkingFeeStrategy interface {
-	CalculateFee(duration time.Duration) float64
+	CalculateFee(stay Stay) money.Money
+}
+
+// Stay is what a fee strategy gets to know about the stay it bills
+type Stay struct {
+	Car       *models.Car
+	Ticket    *models.Ticket
+	EntryTime time.Time
+	ExitTime  time.Time
+	Duration  time.Duration
+}
+
+// NewStay describes the stay of a car holding ticket that ends at exitTime
+func NewStay(car *models.Car, ticket *models.Ticket, exitTime time.Time) Stay {
+	return Stay{
+		Car:       car,
+		Ticket:    ticket,
+		EntryTime: ticket.EntryTime,
+		ExitTime:  exitTime,
+		Duration:  exitTime.Sub(ticket.EntryTime),
+	}
+}
+
+// between returns the part of the stay from entry to exit
+func (s Stay) between(entry time.Time, exit time.Time) Stay {
+	s.EntryTime = entry
+	s.ExitTime = exit
+	s.Duration = exit.Sub(entry)
+	return s
+}
+
+// VehicleType returns the type of the parked vehicle, stays without a car
+// are billed as regular cars
+func (s Stay) VehicleType() models.VehicleType {
+	if s.Car == nil {
+		return models.VehicleTypeCar
+	}
+	return s.Car.GetVehicleType()
+}
+
+// currency returns the currency the strategy bills in, read from what it
+// charges for an empty stay
+func currency(strategy ParkingFeeStrategy) money.Currency {
+	return strategy.CalculateFee(Stay{}).Currency()
+}
+
+// Name returns the type name of a fee strategy, e.g. HourlyFeeStrategy
+func Name(strategy ParkingFeeStrategy) string {
+	if strategy == nil {
+		return ""
+	}
+
+	t := reflect.TypeOf(strategy)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
 }
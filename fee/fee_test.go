@@ -1,17 +1,23 @@
 package fee_test
 
 import (
+	goerrors "errors"
 	"testing"
 	"time"
 
+	"github.com/natanaelrusli/parking-lot/car"
+	"github.com/natanaelrusli/parking-lot/clock"
+	"github.com/natanaelrusli/parking-lot/errors"
 	"github.com/natanaelrusli/parking-lot/fee"
 	"github.com/natanaelrusli/parking-lot/mocks"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/money"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewFlatFeeStrategy(t *testing.T) {
 	t.Run("should return a flat fee strategy", func(t *testing.T) {
-		strategy := fee.NewFlatFeeStrategy(10.0)
+		strategy := fee.NewFlatFeeStrategy(usd(10))
 
 		assert.NotNil(t, strategy)
 	})
@@ -19,16 +25,426 @@ func TestNewFlatFeeStrategy(t *testing.T) {
 	t.Run("mock fee strategy should be called with correct duration", func(t *testing.T) {
 		// Arrange
 		mockStrategy := mocks.NewParkingFeeStrategy(t)
-		duration := 2 * time.Hour
+		stay := fee.Stay{Duration: 2 * time.Hour}
 
 		// Setup expectation
-		mockStrategy.On("CalculateFee", duration).Return(20.0).Once()
+		mockStrategy.On("CalculateFee", stay).Return(usd(20)).Once()
 
 		// Act
-		fee := mockStrategy.CalculateFee(duration)
+		fee := mockStrategy.CalculateFee(stay)
 
 		// Assert
-		assert.Equal(t, 20.0, fee)
+		assert.Equal(t, usd(20), fee)
 		mockStrategy.AssertExpectations(t)
 	})
 }
+
+func usd(amount int64) money.Money {
+	return money.FromMajor(amount, money.USD)
+}
+
+func stayUntil(entry time.Time, exit time.Time) fee.Stay {
+	return fee.NewStay(car.NewCar("AAA111"), &models.Ticket{TicketNumber: "T1", EntryTime: entry}, exit)
+}
+
+func tariffSchedule(t *testing.T, location *time.Location, defaultRate money.Money, rounding money.RoundingMode, bands ...fee.TariffBand) fee.ParkingFeeStrategy {
+	strategy, err := fee.NewTariffScheduleStrategy(location, defaultRate, rounding, bands...)
+	assert.NoError(t, err)
+	return strategy
+}
+
+func dailyMaximum(t *testing.T, strategy fee.ParkingFeeStrategy, maximum money.Money) fee.ParkingFeeStrategy {
+	capped, err := fee.NewDailyMaximumStrategy(strategy, maximum)
+	assert.NoError(t, err)
+	return capped
+}
+
+func minimumCharge(t *testing.T, strategy fee.ParkingFeeStrategy, minimum money.Money) fee.ParkingFeeStrategy {
+	charged, err := fee.NewMinimumChargeStrategy(strategy, minimum)
+	assert.NoError(t, err)
+	return charged
+}
+
+func TestHourlyFeeStrategy(t *testing.T) {
+	entry := time.Date(2024, time.March, 1, 23, 0, 0, 0, time.UTC)
+
+	t.Run("should charge a full hour for a stay under one hour", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		c.Advance(15 * time.Minute)
+
+		f := fee.NewHourlyFeeStrategy(usd(10), money.RoundHalfUp).CalculateFee(stayUntil(entry, c.Now()))
+
+		assert.Equal(t, usd(10), f)
+	})
+
+	t.Run("should charge an overnight stay by the hour", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		c.Advance(9*time.Hour + 30*time.Minute)
+
+		f := fee.NewHourlyFeeStrategy(usd(10), money.RoundHalfUp).CalculateFee(stayUntil(entry, c.Now()))
+
+		assert.Equal(t, usd(95), f)
+	})
+
+	t.Run("should charge a multi-day stay by the hour", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		c.Set(time.Date(2024, time.March, 4, 23, 0, 0, 0, time.UTC))
+
+		f := fee.NewHourlyFeeStrategy(usd(10), money.RoundHalfUp).CalculateFee(stayUntil(entry, c.Now()))
+
+		assert.Equal(t, usd(720), f)
+	})
+}
+
+func TestVehicleTypeFeeStrategy(t *testing.T) {
+	entry := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+	exit := entry.Add(3 * time.Hour)
+	strategy, err := fee.NewVehicleTypeFeeStrategy(fee.NewHourlyFeeStrategy(usd(10), money.RoundHalfUp), map[models.VehicleType]fee.ParkingFeeStrategy{
+		models.VehicleTypeMotorcycle: fee.NewFlatFeeStrategy(usd(5)),
+		models.VehicleTypeBus:        fee.NewHourlyFeeStrategy(usd(50), money.RoundHalfUp),
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		vehicleType models.VehicleType
+		expected    int64
+	}{
+		{"should bill motorcycles a flat fee", models.VehicleTypeMotorcycle, 5},
+		{"should bill buses their own hourly rate", models.VehicleTypeBus, 150},
+		{"should bill other vehicles with the fallback strategy", models.VehicleTypeVan, 30},
+		{"should bill cars without a type as cars", "", 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stay := fee.NewStay(car.NewVehicle("AAA111", tt.vehicleType), &models.Ticket{EntryTime: entry}, exit)
+
+			assert.Equal(t, usd(tt.expected), strategy.CalculateFee(stay))
+		})
+	}
+
+	t.Run("should bill a stay without a car as a car", func(t *testing.T) {
+		assert.Equal(t, usd(30), strategy.CalculateFee(fee.Stay{Duration: 3 * time.Hour}))
+	})
+
+	t.Run("should name the strategy", func(t *testing.T) {
+		assert.Equal(t, "VehicleTypeFeeStrategy", fee.Name(strategy))
+		assert.Equal(t, "", fee.Name(nil))
+	})
+
+	t.Run("should reject missing strategies and strategies in another currency", func(t *testing.T) {
+		hourly := fee.NewHourlyFeeStrategy(usd(10), money.RoundHalfUp)
+		tests := []struct {
+			name       string
+			fallback   fee.ParkingFeeStrategy
+			strategies map[models.VehicleType]fee.ParkingFeeStrategy
+			err        error
+		}{
+			{"no fallback", nil, map[models.VehicleType]fee.ParkingFeeStrategy{models.VehicleTypeBus: hourly}, errors.ErrNoFeeStrategy},
+			{"no strategy for a vehicle type", hourly, map[models.VehicleType]fee.ParkingFeeStrategy{models.VehicleTypeBus: nil}, errors.ErrNoFeeStrategy},
+			{"vehicle type in another currency", hourly, map[models.VehicleType]fee.ParkingFeeStrategy{models.VehicleTypeBus: fee.NewFlatFeeStrategy(money.FromMajor(5, money.EUR))}, errors.ErrCurrencyMismatch},
+		}
+
+		for _, tt := range tests {
+			strategy, err := fee.NewVehicleTypeFeeStrategy(tt.fallback, tt.strategies)
+
+			assert.Nil(t, strategy, tt.name)
+			assert.True(t, goerrors.Is(err, tt.err), "%s: %v", tt.name, err)
+		}
+	})
+}
+
+func TestTariffScheduleStrategy(t *testing.T) {
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	weekend := []time.Weekday{time.Saturday, time.Sunday}
+	strategy := tariffSchedule(t, time.UTC, usd(10), money.RoundHalfUp,
+		fee.TariffBand{Name: "weekend", Days: weekend, Start: 0, End: 0, RatePerHour: usd(4)},
+		fee.TariffBand{Name: "peak", Days: weekdays, Start: 7 * time.Hour, End: 10 * time.Hour, RatePerHour: usd(20)},
+		fee.TariffBand{Name: "night", Start: 22 * time.Hour, End: 6 * time.Hour, RatePerHour: usd(2)},
+	)
+
+	// 2024-03-01 is a Friday
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		entry    time.Time
+		exit     time.Time
+		expected int64
+	}{
+		{"should bill a stay within one band", at(4, 11, 0), at(4, 13, 0), 20},
+		{"should bill a stay within peak hours", at(4, 7, 30), at(4, 9, 0), 30},
+		{"should split a stay across bands", at(4, 6, 0), at(4, 11, 0), 10 + 3*20 + 10},
+		{"should bill part of an hour pro rata", at(4, 21, 30), at(4, 22, 30), 5 + 1},
+		{"should split a stay crossing midnight", at(5, 20, 0), at(6, 8, 0), 2*10 + 8*2 + 10 + 20},
+		{"should bill a weekend night at the weekend rate", at(1, 23, 0), at(2, 1, 0), 2 + 4},
+		{"should split a stay crossing the weekend into the week", at(3, 23, 0), at(4, 8, 0), 4 + 6*2 + 10 + 20},
+		{"should bill a whole week", at(1, 0, 0), at(8, 0, 0), 5*(8*2+3*20+13*10) + 2*24*4},
+		{"should not bill an empty stay", at(4, 12, 0), at(4, 12, 0), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stay := fee.NewStay(car.NewCar("AAA111"), &models.Ticket{EntryTime: tt.entry}, tt.exit)
+
+			assert.Equal(t, usd(tt.expected), strategy.CalculateFee(stay))
+		})
+	}
+
+	t.Run("should use the stay duration when the exit time is unknown", func(t *testing.T) {
+		stay := fee.Stay{EntryTime: at(4, 11, 0), Duration: 2 * time.Hour}
+
+		assert.Equal(t, usd(20), strategy.CalculateFee(stay))
+	})
+
+	t.Run("should evaluate bands in the schedule location", func(t *testing.T) {
+		jakarta := time.FixedZone("WIB", 7*60*60)
+		local := tariffSchedule(t, jakarta, usd(10), money.RoundHalfUp,
+			fee.TariffBand{Name: "night", Start: 22 * time.Hour, End: 6 * time.Hour, RatePerHour: usd(2)},
+		)
+		// 16:00 to 17:00 UTC is 23:00 to 00:00 in Jakarta
+		stay := fee.NewStay(nil, &models.Ticket{EntryTime: at(4, 16, 0)}, at(4, 17, 0))
+
+		assert.Equal(t, usd(2), local.CalculateFee(stay))
+	})
+}
+
+func TestFeeModifiers(t *testing.T) {
+	entry := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+	stayFor := func(d time.Duration) fee.Stay {
+		return stayUntil(entry, entry.Add(d))
+	}
+
+	t.Run("should not charge stays within the grace period", func(t *testing.T) {
+		strategy := fee.NewGracePeriodStrategy(fee.NewHourlyFeeStrategy(usd(10), money.RoundHalfUp), 15*time.Minute)
+
+		assert.Equal(t, usd(0), strategy.CalculateFee(stayFor(15*time.Minute)))
+		assert.Equal(t, usd(10), strategy.CalculateFee(stayFor(16*time.Minute)))
+		assert.Equal(t, usd(20), strategy.CalculateFee(stayFor(2*time.Hour)))
+	})
+
+	t.Run("should cap the fee for every 24 hours", func(t *testing.T) {
+		strategy := dailyMaximum(t, fee.NewHourlyFeeStrategy(usd(10), money.RoundHalfUp), usd(50))
+
+		assert.Equal(t, usd(30), strategy.CalculateFee(stayFor(3*time.Hour)))
+		assert.Equal(t, usd(50), strategy.CalculateFee(stayFor(8*time.Hour)))
+		assert.Equal(t, usd(50), strategy.CalculateFee(stayFor(24*time.Hour)))
+		assert.Equal(t, usd(50+30), strategy.CalculateFee(stayFor(27*time.Hour)))
+		assert.Equal(t, usd(3*50), strategy.CalculateFee(stayFor(60*time.Hour)))
+	})
+
+	t.Run("should cap every day of a tariff schedule separately", func(t *testing.T) {
+		schedule := tariffSchedule(t, time.UTC, usd(10), money.RoundHalfUp,
+			fee.TariffBand{Name: "night", Start: 22 * time.Hour, End: 6 * time.Hour, RatePerHour: usd(0)},
+		)
+		strategy := dailyMaximum(t, schedule, usd(100))
+
+		// every day from 08:00 bills 14 hours at 10 then 8 free night hours
+		assert.Equal(t, usd(2*100), strategy.CalculateFee(stayFor(48*time.Hour)))
+	})
+
+	t.Run("should charge at least the minimum", func(t *testing.T) {
+		strategy := minimumCharge(t, fee.NewFlatFeeStrategy(usd(2)), usd(5))
+
+		assert.Equal(t, usd(5), strategy.CalculateFee(stayFor(time.Minute)))
+		assert.Equal(t, usd(30), minimumCharge(t, fee.NewHourlyFeeStrategy(usd(10), money.RoundHalfUp), usd(5)).CalculateFee(stayFor(3*time.Hour)))
+	})
+
+	t.Run("should round the stay up to billing increments", func(t *testing.T) {
+		schedule := tariffSchedule(t, time.UTC, usd(4), money.RoundHalfUp)
+		strategy := fee.NewBillingIncrementStrategy(schedule, 15*time.Minute)
+
+		assert.Equal(t, usd(1), strategy.CalculateFee(stayFor(time.Minute)))
+		assert.Equal(t, usd(1), strategy.CalculateFee(stayFor(15*time.Minute)))
+		assert.Equal(t, usd(3), strategy.CalculateFee(stayFor(31*time.Minute)))
+		assert.Equal(t, usd(0), strategy.CalculateFee(stayFor(0)))
+	})
+
+	t.Run("should bill a lost ticket as at least the minimum stay", func(t *testing.T) {
+		strategy := fee.NewLostTicketStrategy(dailyMaximum(t, fee.NewHourlyFeeStrategy(usd(10), money.RoundHalfUp), usd(50)), 24*time.Hour)
+
+		assert.Equal(t, usd(50), strategy.CalculateFee(stayFor(time.Hour)))
+		assert.Equal(t, usd(50+20), strategy.CalculateFee(stayFor(26*time.Hour)))
+	})
+
+	t.Run("should compose modifiers", func(t *testing.T) {
+		strategy := fee.NewGracePeriodStrategy(
+			minimumCharge(t,
+				dailyMaximum(t,
+					fee.NewBillingIncrementStrategy(tariffSchedule(t, time.UTC, usd(8), money.RoundHalfUp), 30*time.Minute),
+					usd(50)),
+				usd(3)),
+			15*time.Minute)
+
+		assert.Equal(t, usd(0), strategy.CalculateFee(stayFor(10*time.Minute)))
+		assert.Equal(t, usd(4), strategy.CalculateFee(stayFor(20*time.Minute)))
+		assert.Equal(t, usd(20), strategy.CalculateFee(stayFor(2*time.Hour+10*time.Minute)))
+		assert.Equal(t, usd(50), strategy.CalculateFee(stayFor(10*time.Hour)))
+	})
+
+	t.Run("should reject a maximum or minimum in another currency than the fee", func(t *testing.T) {
+		hourly := fee.NewHourlyFeeStrategy(usd(10), money.RoundHalfUp)
+
+		capped, err := fee.NewDailyMaximumStrategy(hourly, money.FromMajor(50, money.EUR))
+		assert.Nil(t, capped)
+		assert.True(t, goerrors.Is(err, errors.ErrCurrencyMismatch), "%v", err)
+
+		charged, err := fee.NewMinimumChargeStrategy(hourly, money.FromMajor(5, money.EUR))
+		assert.Nil(t, charged)
+		assert.True(t, goerrors.Is(err, errors.ErrCurrencyMismatch), "%v", err)
+	})
+}
+
+func TestFeeRounding(t *testing.T) {
+	entry := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+	cents := func(amount int64) money.Money {
+		return money.FromMinor(amount, money.USD)
+	}
+
+	tests := []struct {
+		name     string
+		rate     money.Money
+		duration time.Duration
+		rounding money.RoundingMode
+		expected money.Money
+	}{
+		{"should round a third down to the nearest cent", usd(10), 20 * time.Minute, money.RoundHalfUp, cents(333)},
+		{"should round a third up to the next cent", usd(10), 20 * time.Minute, money.RoundUp, cents(334)},
+		{"should round half a cent up", cents(5), 30 * time.Minute, money.RoundHalfUp, cents(3)},
+		{"should round half a cent to even", cents(5), 30 * time.Minute, money.RoundHalfEven, cents(2)},
+		{"should round half a cent down", cents(5), 30 * time.Minute, money.RoundDown, cents(2)},
+		{"should bill 2.5 hours exactly", usd(10), 150 * time.Minute, money.RoundHalfUp, usd(25)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := tariffSchedule(t, time.UTC, tt.rate, tt.rounding)
+
+			assert.Equal(t, tt.expected, strategy.CalculateFee(stayUntil(entry, entry.Add(tt.duration))))
+		})
+	}
+
+	t.Run("should round a stay split across bands once", func(t *testing.T) {
+		// 20 minutes at each rate, a third of a cent is lost if every band
+		// is rounded on its own
+		strategy := tariffSchedule(t, time.UTC, usd(10), money.RoundHalfUp,
+			fee.TariffBand{Start: 8*time.Hour + 20*time.Minute, End: 8*time.Hour + 40*time.Minute, RatePerHour: usd(10)},
+		)
+
+		f := strategy.CalculateFee(stayUntil(entry, entry.Add(time.Hour)))
+
+		assert.Equal(t, usd(10), f)
+	})
+
+	t.Run("should reject bands priced in another currency", func(t *testing.T) {
+		strategy, err := fee.NewTariffScheduleStrategy(time.UTC, usd(10), money.RoundHalfUp,
+			fee.TariffBand{Start: 0, End: 0, RatePerHour: money.FromMajor(10, money.EUR)},
+		)
+
+		assert.Nil(t, strategy)
+		assert.True(t, goerrors.Is(err, errors.ErrCurrencyMismatch))
+	})
+}
+
+func TestTieredFeeStrategy(t *testing.T) {
+	entry := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+	// 5 for the first hour, 3 for each of the next three, 2 thereafter
+	strategy, err := fee.NewTieredFeeStrategy(money.RoundHalfUp,
+		fee.PriceTier{Duration: time.Hour, RatePerHour: usd(5), Increment: time.Hour},
+		fee.PriceTier{Duration: 3 * time.Hour, RatePerHour: usd(3), Increment: time.Hour},
+		fee.PriceTier{RatePerHour: usd(2), Increment: time.Hour},
+	)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		duration time.Duration
+		expected int64
+	}{
+		{"should bill a short stay as the first hour", 10 * time.Minute, 5},
+		{"should bill the first hour", time.Hour, 5},
+		{"should round up within the second tier", 61 * time.Minute, 5 + 3},
+		{"should bill the first four hours", 4 * time.Hour, 5 + 3*3},
+		{"should bill the last tier for the rest of the stay", 10*time.Hour + 30*time.Minute, 5 + 3*3 + 7*2},
+		{"should not bill an empty stay", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := strategy.CalculateFee(stayUntil(entry, entry.Add(tt.duration)))
+
+			assert.Equal(t, usd(tt.expected), f)
+		})
+	}
+
+	t.Run("should round every tier to its own increment", func(t *testing.T) {
+		strategy, err := fee.NewTieredFeeStrategy(money.RoundHalfUp,
+			fee.PriceTier{Duration: 30 * time.Minute, RatePerHour: usd(6), Increment: 30 * time.Minute},
+			fee.PriceTier{Duration: 2 * time.Hour, RatePerHour: usd(4), Increment: 15 * time.Minute},
+			fee.PriceTier{RatePerHour: usd(2)},
+		)
+		assert.NoError(t, err)
+
+		// 30 minutes at 6, 2 hours at 4, then 10 minutes billed exactly
+		f := strategy.CalculateFee(stayUntil(entry, entry.Add(2*time.Hour+40*time.Minute)))
+		assert.Equal(t, money.FromMinor(300+800+33, money.USD), f)
+
+		// 30 minutes at 6, then 20 minutes rounded up to 30 at 4
+		f = strategy.CalculateFee(stayUntil(entry, entry.Add(50*time.Minute)))
+		assert.Equal(t, usd(3+2), f)
+	})
+
+	t.Run("should not round past the end of a tier", func(t *testing.T) {
+		strategy, err := fee.NewTieredFeeStrategy(money.RoundHalfUp,
+			fee.PriceTier{Duration: 90 * time.Minute, RatePerHour: usd(4), Increment: time.Hour},
+			fee.PriceTier{RatePerHour: usd(2), Increment: time.Hour},
+		)
+		assert.NoError(t, err)
+
+		f := strategy.CalculateFee(stayUntil(entry, entry.Add(2*time.Hour)))
+
+		assert.Equal(t, usd(6+2), f)
+	})
+
+	t.Run("should reject tiers that can't be billed", func(t *testing.T) {
+		none, noneErr := fee.NewTieredFeeStrategy(money.RoundHalfUp)
+		mixed, mixedErr := fee.NewTieredFeeStrategy(money.RoundHalfUp,
+			fee.PriceTier{Duration: time.Hour, RatePerHour: usd(5)},
+			fee.PriceTier{RatePerHour: money.FromMajor(2, money.EUR)},
+		)
+
+		assert.Nil(t, none)
+		assert.Equal(t, errors.ErrNoPriceTiers, noneErr)
+		assert.Nil(t, mixed)
+		assert.True(t, goerrors.Is(mixedErr, errors.ErrCurrencyMismatch))
+	})
+
+	t.Run("should reject tiers that don't last or bill negative amounts", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			tiers []fee.PriceTier
+			err   error
+		}{
+			{"a tier that doesn't last", []fee.PriceTier{{Duration: -time.Hour, RatePerHour: usd(5)}, {RatePerHour: usd(2)}}, errors.ErrInvalidTierDuration},
+			{"a tier without a duration", []fee.PriceTier{{RatePerHour: usd(5)}, {RatePerHour: usd(2)}}, errors.ErrInvalidTierDuration},
+			{"a negative rate", []fee.PriceTier{{Duration: time.Hour, RatePerHour: usd(-5)}, {RatePerHour: usd(2)}}, errors.ErrNegativeRate},
+			{"a negative increment", []fee.PriceTier{{RatePerHour: usd(2), Increment: -time.Minute}}, errors.ErrNegativeIncrement},
+		}
+
+		for _, tt := range tests {
+			strategy, err := fee.NewTieredFeeStrategy(money.RoundHalfUp, tt.tiers...)
+
+			assert.Nil(t, strategy, tt.name)
+			assert.True(t, goerrors.Is(err, tt.err), "%s: %v", tt.name, err)
+		}
+	})
+
+	t.Run("should compose with fee modifiers", func(t *testing.T) {
+		capped := dailyMaximum(t, strategy, usd(25))
+
+		assert.Equal(t, usd(25+14), capped.CalculateFee(stayUntil(entry, entry.Add(28*time.Hour))))
+	})
+}
@@ -1,15 +1,15 @@
 package fee
 
-import "time"
+import "github.com/natanaelrusli/parking-lot/money"
 
 type FlatFeeStrategy struct {
-	flatFee float64
+	flatFee money.Money
 }
 
-func NewFlatFeeStrategy(flatFee float64) ParkingFeeStrategy {
+func NewFlatFeeStrategy(flatFee money.Money) ParkingFeeStrategy {
 	return &FlatFeeStrategy{flatFee: flatFee}
 }
 
-func (s *FlatFeeStrategy) CalculateFee(duration time.Duration) float64 {
+func (s *FlatFeeStrategy) CalculateFee(stay Stay) money.Money {
 	return s.flatFee
 }
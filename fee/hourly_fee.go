@@ -1,21 +1,26 @@
 package fee
 
-import "time"
+import (
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/money"
+)
 
 type HourlyFeeStrategy struct {
-	ratePerHour float64
+	ratePerHour money.Money
+	rounding    money.RoundingMode
 }
 
-func NewHourlyFeeStrategy(ratePerHour float64) ParkingFeeStrategy {
-	return &HourlyFeeStrategy{ratePerHour: ratePerHour}
+// NewHourlyFeeStrategy bills stays pro rata of the hourly rate, at least
+// one hour, rounding to the cent with the given mode
+func NewHourlyFeeStrategy(ratePerHour money.Money, rounding money.RoundingMode) ParkingFeeStrategy {
+	return &HourlyFeeStrategy{ratePerHour: ratePerHour, rounding: rounding}
 }
 
-func (s *HourlyFeeStrategy) CalculateFee(duration time.Duration) float64 {
-	hours := float64(duration.Hours())
-
-	if hours < 1 {
+func (s *HourlyFeeStrategy) CalculateFee(stay Stay) money.Money {
+	if stay.Duration < time.Hour {
 		return s.ratePerHour
 	}
 
-	return float64(duration.Hours()) * s.ratePerHour
+	return s.ratePerHour.Prorate(stay.Duration, time.Hour, s.rounding)
 }
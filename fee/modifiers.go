@@ -0,0 +1,128 @@
+package fee
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+
+	"github.com/natanaelrusli/parking-lot/money"
+)
+
+// The strategies in this file modify what another strategy charges, and
+// can wrap each other, e.g. a grace period around a daily maximum around
+// an hourly rate. They are applied from the outside in.
+
+// GracePeriodStrategy lets stays up to the grace period park for free.
+// Longer stays are billed in full by the wrapped strategy.
+type GracePeriodStrategy struct {
+	strategy ParkingFeeStrategy
+	grace    time.Duration
+}
+
+func NewGracePeriodStrategy(strategy ParkingFeeStrategy, grace time.Duration) ParkingFeeStrategy {
+	return &GracePeriodStrategy{strategy: strategy, grace: grace}
+}
+
+func (s *GracePeriodStrategy) CalculateFee(stay Stay) money.Money {
+	fee := s.strategy.CalculateFee(stay)
+	if stay.Duration <= s.grace {
+		return money.Zero(fee.Currency())
+	}
+	return fee
+}
+
+// DailyMaximumStrategy caps what the wrapped strategy charges for every 24
+// hours of a stay, counted from the entry time. Every 24 hours are billed
+// separately, so a stay of a day and a half pays at most twice the cap.
+type DailyMaximumStrategy struct {
+	strategy ParkingFeeStrategy
+	maximum  money.Money
+}
+
+// NewDailyMaximumStrategy returns ErrCurrencyMismatch when the maximum
+// isn't in the currency the wrapped strategy bills in
+func NewDailyMaximumStrategy(strategy ParkingFeeStrategy, maximum money.Money) (ParkingFeeStrategy, error) {
+	if c := currency(strategy); maximum.Currency() != c {
+		return nil, fmt.Errorf("%w: daily maximum in %s, fee in %s", errors.ErrCurrencyMismatch, maximum.Currency(), c)
+	}
+	return &DailyMaximumStrategy{strategy: strategy, maximum: maximum}, nil
+}
+
+func (s *DailyMaximumStrategy) CalculateFee(stay Stay) money.Money {
+	const day = 24 * time.Hour
+
+	if stay.Duration <= day {
+		return money.Min(s.strategy.CalculateFee(stay), s.maximum)
+	}
+
+	total := money.Zero(s.maximum.Currency())
+	exit := stay.EntryTime.Add(stay.Duration)
+	for start := stay.EntryTime; start.Before(exit); start = start.Add(day) {
+		end := start.Add(day)
+		if end.After(exit) {
+			end = exit
+		}
+		total = total.Add(money.Min(s.strategy.CalculateFee(stay.between(start, end)), s.maximum))
+	}
+	return total
+}
+
+// MinimumChargeStrategy charges at least the minimum for every stay
+type MinimumChargeStrategy struct {
+	strategy ParkingFeeStrategy
+	minimum  money.Money
+}
+
+// NewMinimumChargeStrategy returns ErrCurrencyMismatch when the minimum
+// isn't in the currency the wrapped strategy bills in
+func NewMinimumChargeStrategy(strategy ParkingFeeStrategy, minimum money.Money) (ParkingFeeStrategy, error) {
+	if c := currency(strategy); minimum.Currency() != c {
+		return nil, fmt.Errorf("%w: minimum charge in %s, fee in %s", errors.ErrCurrencyMismatch, minimum.Currency(), c)
+	}
+	return &MinimumChargeStrategy{strategy: strategy, minimum: minimum}, nil
+}
+
+func (s *MinimumChargeStrategy) CalculateFee(stay Stay) money.Money {
+	return money.Max(s.strategy.CalculateFee(stay), s.minimum)
+}
+
+// BillingIncrementStrategy rounds the stay up to a whole number of billing
+// increments before the wrapped strategy bills it, e.g. every started 15
+// minutes is billed in full
+type BillingIncrementStrategy struct {
+	strategy  ParkingFeeStrategy
+	increment time.Duration
+}
+
+func NewBillingIncrementStrategy(strategy ParkingFeeStrategy, increment time.Duration) ParkingFeeStrategy {
+	return &BillingIncrementStrategy{strategy: strategy, increment: increment}
+}
+
+func (s *BillingIncrementStrategy) CalculateFee(stay Stay) money.Money {
+	if s.increment <= 0 || stay.Duration%s.increment == 0 {
+		return s.strategy.CalculateFee(stay)
+	}
+
+	rounded := roundUp(stay.Duration, s.increment)
+	return s.strategy.CalculateFee(stay.between(stay.EntryTime, stay.EntryTime.Add(rounded)))
+}
+
+// LostTicketStrategy bills cars leaving without their ticket as if they
+// stayed at least the minimum stay, e.g. a full day, with the wrapped
+// strategy. Longer stays are billed as they are.
+type LostTicketStrategy struct {
+	strategy    ParkingFeeStrategy
+	minimumStay time.Duration
+}
+
+func NewLostTicketStrategy(strategy ParkingFeeStrategy, minimumStay time.Duration) ParkingFeeStrategy {
+	return &LostTicketStrategy{strategy: strategy, minimumStay: minimumStay}
+}
+
+func (s *LostTicketStrategy) CalculateFee(stay Stay) money.Money {
+	if stay.Duration >= s.minimumStay {
+		return s.strategy.CalculateFee(stay)
+	}
+	return s.strategy.CalculateFee(stay.between(stay.EntryTime, stay.EntryTime.Add(s.minimumStay)))
+}
@@ -0,0 +1,142 @@
+package fee
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/money"
+)
+
+// TariffBand is an hourly rate that applies during part of the day, e.g.
+// peak hours on weekdays. Start and End are offsets from midnight; a band
+// whose End is not after its Start wraps past midnight, so 22:00 to 06:00
+// covers the night. Days are matched against the calendar day of each
+// instant, an empty Days matches every day.
+type TariffBand struct {
+	Name        string
+	Days        []time.Weekday
+	Start       time.Duration
+	End         time.Duration
+	RatePerHour money.Money
+}
+
+func (b TariffBand) covers(t time.Time) bool {
+	if len(b.Days) > 0 && !containsWeekday(b.Days, t.Weekday()) {
+		return false
+	}
+
+	offset := sinceMidnight(t)
+	if b.Start < b.End {
+		return offset >= b.Start && offset < b.End
+	}
+	return offset >= b.Start || offset < b.End
+}
+
+// TariffScheduleStrategy splits a stay across time bands using its entry and
+// exit times and sums what every band charges for the part of the stay it
+// covers. The first band covering an instant applies, time no band covers
+// is billed at the default rate. Stays are billed pro rata and the total is
+// rounded once, so splitting a stay doesn't add rounding errors.
+type TariffScheduleStrategy struct {
+	location    *time.Location
+	defaultRate money.Money
+	rounding    money.RoundingMode
+	bands       []TariffBand
+}
+
+// NewTariffScheduleStrategy creates a schedule evaluated in location, or in
+// the location of the entry time when location is nil. It returns
+// ErrCurrencyMismatch when a band isn't priced in the currency of the
+// default rate.
+func NewTariffScheduleStrategy(location *time.Location, defaultRate money.Money, rounding money.RoundingMode, bands ...TariffBand) (ParkingFeeStrategy, error) {
+	for _, band := range bands {
+		if band.RatePerHour.Currency() != defaultRate.Currency() {
+			return nil, fmt.Errorf("%w: tariff band priced in %s, schedule in %s", errors.ErrCurrencyMismatch, band.RatePerHour.Currency(), defaultRate.Currency())
+		}
+	}
+
+	return &TariffScheduleStrategy{
+		location:    location,
+		defaultRate: defaultRate,
+		rounding:    rounding,
+		bands:       append([]TariffBand(nil), bands...),
+	}, nil
+}
+
+func (s *TariffScheduleStrategy) CalculateFee(stay Stay) money.Money {
+	entry, exit := stay.EntryTime, stay.ExitTime
+	if exit.IsZero() {
+		exit = entry.Add(stay.Duration)
+	}
+	if s.location != nil {
+		entry, exit = entry.In(s.location), exit.In(s.location)
+	}
+
+	currency := s.defaultRate.Currency()
+	total := new(big.Rat)
+	for cursor := entry; cursor.Before(exit); {
+		next := s.nextBoundary(cursor)
+		if next.After(exit) {
+			next = exit
+		}
+
+		rate := s.rateAt(cursor)
+		hours := big.NewRat(int64(next.Sub(cursor)), int64(time.Hour))
+		total.Add(total, hours.Mul(hours, rate.Rat()))
+		cursor = next
+	}
+	return money.FromRat(total, currency, s.rounding)
+}
+
+func (s *TariffScheduleStrategy) rateAt(t time.Time) money.Money {
+	for _, band := range s.bands {
+		if band.covers(t) {
+			return band.RatePerHour
+		}
+	}
+	return s.defaultRate
+}
+
+// nextBoundary returns the first instant after t at which the rate may
+// change: the next band start or end, or midnight when the day changes
+func (s *TariffScheduleStrategy) nextBoundary(t time.Time) time.Time {
+	next := midnightAfter(t)
+	for _, band := range s.bands {
+		for _, offset := range []time.Duration{band.Start, band.End} {
+			boundary := atOffset(t, offset)
+			if boundary.After(t) && boundary.Before(next) {
+				next = boundary
+			}
+		}
+	}
+	return next
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second +
+		time.Duration(t.Nanosecond())
+}
+
+// atOffset returns the wall clock time offset from midnight on the day of t
+func atOffset(t time.Time, offset time.Duration) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, int(offset), t.Location())
+}
+
+func midnightAfter(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+}
+
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package fee
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/money"
+)
+
+// PriceTier is a band of a tiered price, e.g. 3 per hour for the second to
+// the fourth hour. The part of a stay that falls in the tier is rounded up
+// to whole billing increments, never past the end of the tier, and billed
+// pro rata of the hourly rate. A zero Increment bills it exactly.
+type PriceTier struct {
+	Duration    time.Duration
+	RatePerHour money.Money
+	Increment   time.Duration
+}
+
+// TieredFeeStrategy bills a stay progressively: the first tier bills the
+// start of the stay, the next tier what follows and so on. The last tier
+// bills the rest of the stay whatever its Duration.
+type TieredFeeStrategy struct {
+	tiers    []PriceTier
+	rounding money.RoundingMode
+}
+
+// NewTieredFeeStrategy creates a strategy from ordered tiers. It returns
+// ErrNoPriceTiers without tiers, ErrCurrencyMismatch when they are not all
+// priced in the same currency, ErrInvalidTierDuration when a tier other
+// than the last doesn't last, and ErrNegativeRate or ErrNegativeIncrement
+// for a tier with a negative rate or increment.
+func NewTieredFeeStrategy(rounding money.RoundingMode, tiers ...PriceTier) (ParkingFeeStrategy, error) {
+	if len(tiers) == 0 {
+		return nil, errors.ErrNoPriceTiers
+	}
+
+	currency := tiers[0].RatePerHour.Currency()
+	for i, tier := range tiers {
+		switch {
+		case tier.RatePerHour.Currency() != currency:
+			return nil, fmt.Errorf("%w: price tier in %s, first tier in %s", errors.ErrCurrencyMismatch, tier.RatePerHour.Currency(), currency)
+		case i < len(tiers)-1 && tier.Duration <= 0:
+			return nil, fmt.Errorf("%w: tier %d lasts %s", errors.ErrInvalidTierDuration, i+1, tier.Duration)
+		case tier.RatePerHour.IsNegative():
+			return nil, fmt.Errorf("%w: tier %d charges %s per hour", errors.ErrNegativeRate, i+1, tier.RatePerHour)
+		case tier.Increment < 0:
+			return nil, fmt.Errorf("%w: tier %d is billed by %s", errors.ErrNegativeIncrement, i+1, tier.Increment)
+		}
+	}
+
+	return &TieredFeeStrategy{
+		tiers:    append([]PriceTier(nil), tiers...),
+		rounding: rounding,
+	}, nil
+}
+
+func (s *TieredFeeStrategy) CalculateFee(stay Stay) money.Money {
+	currency := s.tiers[0].RatePerHour.Currency()
+	total := new(big.Rat)
+
+	remaining := stay.Duration
+	for i, tier := range s.tiers {
+		if remaining <= 0 {
+			break
+		}
+
+		last := i == len(s.tiers)-1
+		part := remaining
+		if !last && part > tier.Duration {
+			part = tier.Duration
+		}
+		remaining -= part
+
+		billed := roundUp(part, tier.Increment)
+		if !last && billed > tier.Duration {
+			billed = tier.Duration
+		}
+		hours := big.NewRat(int64(billed), int64(time.Hour))
+		total.Add(total, hours.Mul(hours, tier.RatePerHour.Rat()))
+	}
+	return money.FromRat(total, currency, s.rounding)
+}
+
+// roundUp rounds d up to a whole number of increments
+func roundUp(d time.Duration, increment time.Duration) time.Duration {
+	if increment <= 0 || d%increment == 0 {
+		return d
+	}
+	return (d/increment + 1) * increment
+}
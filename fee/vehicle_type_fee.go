@@ -0,0 +1,54 @@
+package fee
+
+import (
+	"fmt"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/money"
+)
+
+// VehicleTypeFeeStrategy bills each vehicle type with its own strategy,
+// e.g. a flat fee for motorcycles and an hourly rate for buses
+type VehicleTypeFeeStrategy struct {
+	strategies map[models.VehicleType]ParkingFeeStrategy
+	fallback   ParkingFeeStrategy
+}
+
+// NewVehicleTypeFeeStrategy bills the vehicle types in strategies with their
+// strategy and every other type with fallback. It returns ErrNoFeeStrategy
+// when the fallback or a vehicle type's strategy is nil, and
+// ErrCurrencyMismatch when they don't all bill in the same currency.
+func NewVehicleTypeFeeStrategy(fallback ParkingFeeStrategy, strategies map[models.VehicleType]ParkingFeeStrategy) (ParkingFeeStrategy, error) {
+	if fallback == nil {
+		return nil, fmt.Errorf("%w: no fallback for other vehicle types", errors.ErrNoFeeStrategy)
+	}
+
+	s := &VehicleTypeFeeStrategy{
+		strategies: make(map[models.VehicleType]ParkingFeeStrategy, len(strategies)),
+		fallback:   fallback,
+	}
+	fallbackCurrency := currency(fallback)
+	for vehicleType, strategy := range strategies {
+		if strategy == nil {
+			return nil, fmt.Errorf("%w: no strategy for %s", errors.ErrNoFeeStrategy, vehicleType)
+		}
+		if c := currency(strategy); c != fallbackCurrency {
+			return nil, fmt.Errorf("%w: %s billed in %s, fallback in %s", errors.ErrCurrencyMismatch, vehicleType, c, fallbackCurrency)
+		}
+		s.strategies[vehicleType] = strategy
+	}
+	return s, nil
+}
+
+func (s *VehicleTypeFeeStrategy) CalculateFee(stay Stay) money.Money {
+	return s.StrategyFor(stay.VehicleType()).CalculateFee(stay)
+}
+
+// StrategyFor returns the strategy vehicles of the given type are billed with
+func (s *VehicleTypeFeeStrategy) StrategyFor(vehicleType models.VehicleType) ParkingFeeStrategy {
+	if strategy, exists := s.strategies[vehicleType]; exists {
+		return strategy
+	}
+	return s.fallback
+}
@@ -0,0 +1,177 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+)
+
+// Level is the importance of a log record, with the values log/slog uses
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+	}
+}
+
+// ParseLevel returns the level with the given name, e.g. "info" or "WARN"
+func ParseLevel(name string) (Level, error) {
+	for _, level := range []Level{LevelDebug, LevelInfo, LevelWarn, LevelError} {
+		if strings.EqualFold(name, level.String()) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", errors.ErrInvalidLogLevel, name)
+}
+
+// Keys of the fields lots and attendants log
+const (
+	KeyLot       = "lot"
+	KeyTicket    = "ticket"
+	KeyPlate     = "plate"
+	KeySlot      = "slot"
+	KeyAttendant = "attendant"
+	KeyError     = "err"
+)
+
+// Logger writes structured log records. Like log/slog, the args after the
+// message are alternating keys and values, e.g.
+//
+//	logger.Info("car parked", logging.KeyLot, "lot1", logging.KeyPlate, "B1234XY")
+//
+// Implementations must be safe for concurrent use.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+	// With returns a logger adding the given keys and values to every
+	// record
+	With(args ...interface{}) Logger
+}
+
+// Nop returns a logger that discards everything, the default of lots and
+// attendants
+func Nop() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+func (l nopLogger) With(args ...interface{}) Logger     { return l }
+
+// TextLogger writes the records at or above its level as key=value lines,
+// like the TextHandler of log/slog:
+//
+//	time=2024-03-01T08:00:00Z level=INFO msg="car parked" lot=lot1 plate=B1234XY
+type TextLogger struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level Level
+	// keys and values added with With
+	args []interface{}
+}
+
+func NewTextLogger(w io.Writer, level Level) *TextLogger {
+	return &TextLogger{mu: &sync.Mutex{}, w: w, level: level}
+}
+
+func (l *TextLogger) Debug(msg string, args ...interface{}) {
+	l.log(LevelDebug, msg, args)
+}
+
+func (l *TextLogger) Info(msg string, args ...interface{}) {
+	l.log(LevelInfo, msg, args)
+}
+
+func (l *TextLogger) Warn(msg string, args ...interface{}) {
+	l.log(LevelWarn, msg, args)
+}
+
+func (l *TextLogger) Error(msg string, args ...interface{}) {
+	l.log(LevelError, msg, args)
+}
+
+func (l *TextLogger) With(args ...interface{}) Logger {
+	with := *l
+	with.args = append(append([]interface{}(nil), l.args...), args...)
+	return &with
+}
+
+func (l *TextLogger) log(level Level, msg string, args []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("time=" + time.Now().Format(time.RFC3339))
+	b.WriteString(" level=" + level.String())
+	b.WriteString(" msg=" + formatValue(msg))
+	writeArgs(&b, l.args)
+	writeArgs(&b, args)
+	b.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	io.WriteString(l.w, b.String())
+}
+
+// writeArgs writes the keys and values, a value without a key is written
+// under !BADKEY as log/slog does
+func writeArgs(b *strings.Builder, args []interface{}) {
+	for i := 0; i < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok || i+1 == len(args) {
+			b.WriteString(" !BADKEY=" + formatValue(args[i]))
+			i--
+			continue
+		}
+		b.WriteString(" " + key + "=" + formatValue(args[i+1]))
+	}
+}
+
+func formatValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case time.Time:
+		s = v.Format(time.RFC3339Nano)
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
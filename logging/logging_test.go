@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextLogger(t *testing.T) {
+	t.Run("should write records as key value lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := NewTextLogger(&buf, LevelInfo)
+
+		logger.Info("car parked", KeyLot, "lot1", KeyPlate, "B 1234 XY", KeySlot, 3)
+
+		line := buf.String()
+		assert.True(t, strings.HasPrefix(line, "time="))
+		assert.True(t, strings.HasSuffix(line, ` level=INFO msg="car parked" lot=lot1 plate="B 1234 XY" slot=3`+"\n"), line)
+	})
+
+	t.Run("should skip records below its level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := NewTextLogger(&buf, LevelWarn)
+
+		logger.Debug("debug")
+		logger.Info("info")
+		logger.Warn("warn")
+		logger.Error("error", KeyError, fmt.Errorf("disk full"))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Contains(t, lines[0], "level=WARN msg=warn")
+		assert.Contains(t, lines[1], `level=ERROR msg=error err="disk full"`)
+	})
+
+	t.Run("should add the fields given to With", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := NewTextLogger(&buf, LevelDebug)
+
+		lotLogger := logger.With(KeyLot, "lot1")
+		lotLogger.Debug("status", "available", 2)
+		logger.Debug("status")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Contains(t, lines[0], "msg=status lot=lot1 available=2")
+		assert.NotContains(t, lines[1], "lot=")
+	})
+
+	t.Run("should log values without a key under !BADKEY", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := NewTextLogger(&buf, LevelInfo)
+
+		logger.Info("oops", 42, KeyLot, "lot1", "dangling")
+
+		assert.Contains(t, buf.String(), "msg=oops !BADKEY=42 lot=lot1 !BADKEY=dangling\n")
+	})
+}
+
+func TestNop(t *testing.T) {
+	logger := Nop().With(KeyLot, "lot1")
+
+	assert.NotPanics(t, func() {
+		logger.Debug("debug")
+		logger.Info("info")
+		logger.Warn("warn")
+		logger.Error("error")
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, expected := range map[string]Level{"debug": LevelDebug, "INFO": LevelInfo, "Warn": LevelWarn, "error": LevelError} {
+		level, err := ParseLevel(name)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, level)
+	}
+
+	_, err := ParseLevel("loud")
+	assert.ErrorIs(t, err, errors.ErrInvalidLogLevel)
+}
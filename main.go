@@ -1,7 +1,74 @@
 package main
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"os"
 
+	"github.com/natanaelrusli/parking-lot/cli"
+	"github.com/natanaelrusli/parking-lot/fee"
+	"github.com/natanaelrusli/parking-lot/logging"
+	"github.com/natanaelrusli/parking-lot/parkinglot"
+	"github.com/natanaelrusli/parking-lot/repository"
+)
+
+// Usage:
+//
+//	parking-lot [-data lots.log]                      interactive mode
+//	parking-lot [-data lots.log] -file commands.txt   run a command file
+//	parking-lot [-data lots.log] <command> [args...]  run a single command
+//
+// -pricing pricing.yaml bills every lot with the strategy of a pricing
+// config, see fee.PricingConfig.
 func main() {
-	fmt.Println("Parking Lot")
+	dataFile := flag.String("data", "", "append-only log to persist lots in, in-memory when empty")
+	commandFile := flag.String("file", "", "file with one command per line to run instead of reading stdin")
+	pricingFile := flag.String("pricing", "", "YAML or JSON pricing config to bill every lot with")
+	flag.Parse()
+
+	if err := run(*dataFile, *commandFile, *pricingFile, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dataFile string, commandFile string, pricingFile string, args []string) error {
+	var opts []parkinglot.Option
+	if pricingFile != "" {
+		strategy, err := fee.LoadConfig(pricingFile)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, parkinglot.WithFeeStrategy(strategy))
+	}
+
+	var repo repository.ParkingLotRepository = repository.NewInMemoryRepository()
+	if dataFile != "" {
+		fileRepo, err := repository.NewFileRepository(dataFile)
+		if err != nil {
+			return err
+		}
+		defer fileRepo.Close()
+		repo = fileRepo
+	}
+
+	c, err := cli.New(os.Stdout, repo, logging.Nop(), opts...)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(args) > 0:
+		return c.Execute(args)
+	case commandFile != "":
+		f, err := os.Open(commandFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return c.Run(f, false)
+	default:
+		fmt.Println("Parking Lot")
+		return c.Run(os.Stdin, true)
+	}
 }
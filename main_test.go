@@ -46,20 +46,20 @@ func TestParkingLotOperations(t *testing.T) {
 		car := car.NewCar("XYZ789")
 		ticket, _ := parkingLot.Park(car)
 
-		unparkedCar, err := parkingLot.Unpark(ticket)
+		receipt, err := parkingLot.Unpark(ticket)
 
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 			return
 		}
 
-		if unparkedCar == nil {
+		if receipt == nil || receipt.Car == nil {
 			t.Error("Expected car, got nil")
 			return
 		}
 
-		if unparkedCar.LicensePlate != "XYZ789" {
-			t.Errorf("Expected license plate XYZ789, got %s", unparkedCar.LicensePlate)
+		if receipt.Car.LicensePlate != "XYZ789" {
+			t.Errorf("Expected license plate XYZ789, got %s", receipt.Car.LicensePlate)
 			return
 		}
 	})
@@ -112,13 +112,13 @@ func TestParkingLotOperations(t *testing.T) {
 
 		// Unpark cars and verify
 		car1Retrieved, _ := parkingLot.Unpark(ticket1)
-		if car1Retrieved.LicensePlate != "AAA111" {
-			t.Errorf("Expected AAA111, got %s", car1Retrieved.LicensePlate)
+		if car1Retrieved.Car.LicensePlate != "AAA111" {
+			t.Errorf("Expected AAA111, got %s", car1Retrieved.Car.LicensePlate)
 		}
 
 		car2Retrieved, _ := parkingLot.Unpark(ticket2)
-		if car2Retrieved.LicensePlate != "BBB222" {
-			t.Errorf("Expected BBB222, got %s", car2Retrieved.LicensePlate)
+		if car2Retrieved.Car.LicensePlate != "BBB222" {
+			t.Errorf("Expected BBB222, got %s", car2Retrieved.Car.LicensePlate)
 		}
 	})
 
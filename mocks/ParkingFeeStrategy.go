@@ -3,9 +3,10 @@
 package mocks
 
 import (
-	time "time"
-
+	fee "github.com/natanaelrusli/parking-lot/fee"
 	mock "github.com/stretchr/testify/mock"
+
+	money "github.com/natanaelrusli/parking-lot/money"
 )
 
 // ParkingFeeStrategy is an autogenerated mock type for the ParkingFeeStrategy type
@@ -13,19 +14,19 @@ type ParkingFeeStrategy struct {
 	mock.Mock
 }
 
-// CalculateFee provides a mock function with given fields: duration
-func (_m *ParkingFeeStrategy) CalculateFee(duration time.Duration) float64 {
-	ret := _m.Called(duration)
+// CalculateFee provides a mock function with given fields: stay
+func (_m *ParkingFeeStrategy) CalculateFee(stay fee.Stay) money.Money {
+	ret := _m.Called(stay)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CalculateFee")
 	}
 
-	var r0 float64
-	if rf, ok := ret.Get(0).(func(time.Duration) float64); ok {
-		r0 = rf(duration)
+	var r0 money.Money
+	if rf, ok := ret.Get(0).(func(fee.Stay) money.Money); ok {
+		r0 = rf(stay)
 	} else {
-		r0 = ret.Get(0).(float64)
+		r0 = ret.Get(0).(money.Money)
 	}
 
 	return r0
@@ -3,7 +3,7 @@ package models
 import (
 	"time"
 
-	"github.com/natanaelrusli/parking-lot/fee"
+	"github.com/natanaelrusli/parking-lot/money"
 )
 
 type ParkingAttendant struct {
@@ -17,6 +17,15 @@ type ParkingLotStatus struct {
 	LotID     string
 	Capacity  int
 	Available int
+	// Number of spaces held for reservations right now
+	Reserved int
+	// Spaces kept for permit holders, and permit holders parked
+	PermitCapacity int
+	PermitParked   int
+	// Number of slots, and of free slots, each vehicle type fits in.
+	// A slot is counted for every type that fits in it.
+	CapacityByType  map[VehicleType]int
+	AvailableByType map[VehicleType]int
 }
 
 // The Observer interface
@@ -24,22 +33,203 @@ type ParkingLotObserver interface {
 	OnParkingLotStatusChanged(status ParkingLotStatus)
 }
 
-// The Subject (ParkingLot) containing list of observers
+// The Subject (ParkingLot), its observers subscribe to its event bus
 type ParkingLot struct {
 	ID          string
 	ParkedCars  map[string]string
 	UsedTickets map[string]bool
 	Capacity    int
-	// List of observers
-	Subscribers []ParkingLotObserver
-	FeeStrategy fee.ParkingFeeStrategy
+	// Slots are numbered from 1, Slots[i] is slot number i+1
+	Slots []Slot
+	// Reservations not redeemed yet, keyed by code
+	Reservations map[string]Reservation
+	// Spaces kept for permit holders, walk-ins can't park in them
+	PermitCapacity int
+	// Permit of each car parked with one, keyed by ticket number
+	PermitTickets map[string]string
+}
+
+type VehicleType string
+
+const (
+	VehicleTypeMotorcycle VehicleType = "motorcycle"
+	VehicleTypeCar        VehicleType = "car"
+	VehicleTypeVan        VehicleType = "van"
+	VehicleTypeBus        VehicleType = "bus"
+)
+
+// VehicleTypes lists every vehicle type from the smallest to the largest
+var VehicleTypes = []VehicleType{
+	VehicleTypeMotorcycle,
+	VehicleTypeCar,
+	VehicleTypeVan,
+	VehicleTypeBus,
+}
+
+func (t VehicleType) IsValid() bool {
+	return t.RequiredSlotSize() != ""
+}
+
+// RequiredSlotSize returns the smallest slot size the vehicle fits in
+func (t VehicleType) RequiredSlotSize() SlotSize {
+	switch t {
+	case VehicleTypeMotorcycle:
+		return SlotSizeSmall
+	case VehicleTypeCar:
+		return SlotSizeMedium
+	case VehicleTypeVan:
+		return SlotSizeLarge
+	case VehicleTypeBus:
+		return SlotSizeExtraLarge
+	default:
+		return ""
+	}
+}
+
+type SlotSize string
+
+const (
+	SlotSizeSmall      SlotSize = "small"
+	SlotSizeMedium     SlotSize = "medium"
+	SlotSizeLarge      SlotSize = "large"
+	SlotSizeExtraLarge SlotSize = "extra_large"
+)
+
+func (s SlotSize) IsValid() bool {
+	return s.rank() > 0
+}
+
+// Fits reports whether a vehicle of the given type can park in a slot of
+// this size. Smaller vehicles fit in bigger slots, but not the other way
+// around.
+func (s SlotSize) Fits(t VehicleType) bool {
+	required := t.RequiredSlotSize()
+	return required != "" && s.rank() >= required.rank()
+}
+
+// Smaller reports whether this size is smaller than the other
+func (s SlotSize) Smaller(other SlotSize) bool {
+	return s.rank() < other.rank()
+}
+
+func (s SlotSize) rank() int {
+	switch s {
+	case SlotSizeSmall:
+		return 1
+	case SlotSizeMedium:
+		return 2
+	case SlotSizeLarge:
+		return 3
+	case SlotSizeExtraLarge:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// Slot is a numbered parking bay. A free slot has no ticket number.
+type Slot struct {
+	Number       int
+	Size         SlotSize
+	TicketNumber string
+	LicensePlate string
+	VehicleType  VehicleType
+}
+
+func (s Slot) IsFree() bool {
+	return s.TicketNumber == ""
 }
 
 type Car struct {
 	LicensePlate string
+	Type         VehicleType
+}
+
+// GetVehicleType returns the car's type, cars without one are regular cars
+func (c Car) GetVehicleType() VehicleType {
+	if c.Type == "" {
+		return VehicleTypeCar
+	}
+	return c.Type
 }
 
 type Ticket struct {
 	TicketNumber string
 	EntryTime    time.Time
+	SlotNumber   int
+	// Code of the reservation the car parked with, if any
+	ReservationCode string
+	// ID of the permit the car parked with, if any
+	PermitID string
+}
+
+// Reservation holds a space in a lot during a time window. It is redeemed
+// by parking a car with its license plate, when it has one, or its code.
+type Reservation struct {
+	Code         string
+	LotID        string
+	LicensePlate string
+	VehicleType  VehicleType
+	Start        time.Time
+	End          time.Time
+}
+
+// IsActive reports whether the reservation holds its space at t
+func (r Reservation) IsActive(t time.Time) bool {
+	return !t.Before(r.Start) && t.Before(r.End)
+}
+
+// Permit lets a car park for free in the lots it covers during its
+// validity period, e.g. a monthly pass. A permit without lot IDs covers
+// every lot.
+type Permit struct {
+	ID           string
+	LicensePlate string
+	LotIDs       []string
+	ValidFrom    time.Time
+	ValidUntil   time.Time
+}
+
+// IsValid reports whether the permit can be used at t
+func (p Permit) IsValid(t time.Time) bool {
+	return !t.Before(p.ValidFrom) && t.Before(p.ValidUntil)
+}
+
+// Covers reports whether the permit can be used in the lot
+func (p Permit) Covers(lotID string) bool {
+	if len(p.LotIDs) == 0 {
+		return true
+	}
+	for _, id := range p.LotIDs {
+		if id == lotID {
+			return true
+		}
+	}
+	return false
+}
+
+// ParkingSession is a car that is currently parked, together with the
+// ticket it was issued
+type ParkingSession struct {
+	Ticket Ticket
+	Car    Car
+}
+
+// Receipt is produced when a car leaves a parking lot
+type Receipt struct {
+	Car        *Car
+	Ticket     *Ticket
+	LotID      string
+	SlotNumber int
+	EntryTime  time.Time
+	ExitTime   time.Time
+	Duration   time.Duration
+	Fee        money.Money
+	// name of the fee strategy the car was billed with
+	FeeStrategy string
+	// ID of the permit the stay was covered by, if any
+	PermitID string
+	// LostTicket is set when the car left without its ticket and was
+	// billed with the lost ticket fee strategy
+	LostTicket bool
 }
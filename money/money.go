@@ -0,0 +1,226 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+)
+
+// Currency is an ISO 4217 currency code, e.g. USD
+type Currency string
+
+const (
+	USD Currency = "USD"
+	EUR Currency = "EUR"
+	IDR Currency = "IDR"
+	SGD Currency = "SGD"
+	JPY Currency = "JPY"
+)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ParseCurrency returns the currency with the given code, in any case
+func ParseCurrency(code string) (Currency, error) {
+	c := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if !c.IsValid() {
+		return "", fmt.Errorf("%w: %q", errors.ErrInvalidCurrency, code)
+	}
+	return c, nil
+}
+
+func (c Currency) IsValid() bool {
+	return currencyPattern.MatchString(string(c))
+}
+
+// MinorUnits returns the number of decimals of the currency, e.g. 2 for
+// cents
+func (c Currency) MinorUnits() int {
+	switch c {
+	case JPY, "KRW", "VND", "CLP", "ISK":
+		return 0
+	case "BHD", "KWD", "OMR", "JOD", "TND":
+		return 3
+	default:
+		return 2
+	}
+}
+
+// Money is an exact amount of a currency, kept as an integer number of
+// minor units so sums reconcile to the cent. Operations that can lose
+// precision, like prorating a rate, take an explicit RoundingMode.
+//
+// Adding, subtracting or comparing amounts of different currencies is a
+// programming error and panics with ErrCurrencyMismatch, use Sum to add up
+// amounts that may not share a currency.
+type Money struct {
+	amount   int64
+	currency Currency
+}
+
+// FromMinor returns amount minor units of the currency, e.g. cents
+func FromMinor(amount int64, currency Currency) Money {
+	return Money{amount: amount, currency: currency}
+}
+
+// FromMajor returns amount whole units of the currency, e.g. dollars
+func FromMajor(amount int64, currency Currency) Money {
+	return Money{amount: amount * pow10(currency.MinorUnits()), currency: currency}
+}
+
+// Zero returns no money of the currency
+func Zero(currency Currency) Money {
+	return Money{currency: currency}
+}
+
+var amountPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Parse reads a decimal amount such as 12.50. Amounts with more decimals
+// than the currency has minor units are rejected rather than rounded.
+func Parse(amount string, currency Currency) (Money, error) {
+	if !currency.IsValid() {
+		return Money{}, fmt.Errorf("%w: %q", errors.ErrInvalidCurrency, currency)
+	}
+
+	r, ok := new(big.Rat).SetString(amount)
+	if !amountPattern.MatchString(amount) || !ok {
+		return Money{}, fmt.Errorf("%w: %q", errors.ErrInvalidAmount, amount)
+	}
+
+	minor := r.Mul(r, new(big.Rat).SetInt64(pow10(currency.MinorUnits())))
+	if !minor.IsInt() || !minor.Num().IsInt64() {
+		return Money{}, fmt.Errorf("%w: %q has too many decimals for %s", errors.ErrInvalidAmount, amount, currency)
+	}
+	return FromMinor(minor.Num().Int64(), currency), nil
+}
+
+// FromRat rounds r, an amount in whole units of the currency, to its minor
+// units
+func FromRat(r *big.Rat, currency Currency, mode RoundingMode) Money {
+	minor := new(big.Rat).Mul(r, new(big.Rat).SetInt64(pow10(currency.MinorUnits())))
+	return FromMinor(mode.round(minor), currency)
+}
+
+// Amount returns the amount in minor units
+func (m Money) Amount() int64 {
+	return m.amount
+}
+
+func (m Money) Currency() Currency {
+	return m.currency
+}
+
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.amount < 0
+}
+
+// Rat returns the amount in whole units of the currency
+func (m Money) Rat() *big.Rat {
+	return big.NewRat(m.amount, pow10(m.currency.MinorUnits()))
+}
+
+func (m Money) Add(other Money) Money {
+	m.mustMatch(other)
+	return FromMinor(m.amount+other.amount, m.currency)
+}
+
+func (m Money) Sub(other Money) Money {
+	m.mustMatch(other)
+	return FromMinor(m.amount-other.amount, m.currency)
+}
+
+// Cmp returns -1, 0 or +1 depending on whether m is less than, equal to or
+// greater than other
+func (m Money) Cmp(other Money) int {
+	m.mustMatch(other)
+	switch {
+	case m.amount < other.amount:
+		return -1
+	case m.amount > other.amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Mul multiplies the amount by factor, rounding to minor units with mode
+func (m Money) Mul(factor *big.Rat, mode RoundingMode) Money {
+	return FromMinor(mode.round(new(big.Rat).Mul(big.NewRat(m.amount, 1), factor)), m.currency)
+}
+
+// Prorate returns the share of the amount for part of a period, e.g. what
+// an hourly rate charges for 20 minutes
+func (m Money) Prorate(part time.Duration, period time.Duration, mode RoundingMode) Money {
+	return m.Mul(big.NewRat(int64(part), int64(period)), mode)
+}
+
+// Decimal formats the amount with the currency's decimals, e.g. 12.50
+func (m Money) Decimal() string {
+	decimals := m.currency.MinorUnits()
+	if decimals == 0 {
+		return fmt.Sprintf("%d", m.amount)
+	}
+
+	sign := ""
+	amount := m.amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	unit := pow10(decimals)
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/unit, decimals, amount%unit)
+}
+
+// String formats the amount and its currency, e.g. 12.50 USD
+func (m Money) String() string {
+	return m.Decimal() + " " + string(m.currency)
+}
+
+func (m Money) mustMatch(other Money) {
+	if m.currency != other.currency {
+		panic(fmt.Errorf("%w: %s and %s", errors.ErrCurrencyMismatch, m.currency, other.currency))
+	}
+}
+
+// Min returns the smaller of two amounts of the same currency
+func Min(a Money, b Money) Money {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+// Max returns the larger of two amounts of the same currency
+func Max(a Money, b Money) Money {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// Sum adds up amounts of the given currency
+func Sum(currency Currency, amounts ...Money) (Money, error) {
+	total := Zero(currency)
+	for _, amount := range amounts {
+		if amount.currency != currency {
+			return Money{}, fmt.Errorf("%w: %s and %s", errors.ErrCurrencyMismatch, currency, amount.currency)
+		}
+		total.amount += amount.amount
+	}
+	return total, nil
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
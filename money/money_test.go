@@ -0,0 +1,133 @@
+package money
+
+import (
+	goerrors "errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		currency Currency
+		expected Money
+		err      error
+	}{
+		{"should parse whole units", "12", USD, FromMinor(1200, USD), nil},
+		{"should parse cents", "12.5", USD, FromMinor(1250, USD), nil},
+		{"should parse negative amounts", "-0.05", USD, FromMinor(-5, USD), nil},
+		{"should parse currencies without minor units", "500", JPY, FromMinor(500, JPY), nil},
+		{"should reject more decimals than the currency has", "0.005", USD, Money{}, errors.ErrInvalidAmount},
+		{"should reject decimals of currencies without minor units", "5.5", JPY, Money{}, errors.ErrInvalidAmount},
+		{"should reject fractions", "1/3", USD, Money{}, errors.ErrInvalidAmount},
+		{"should reject exponents", "1e3", USD, Money{}, errors.ErrInvalidAmount},
+		{"should reject invalid currencies", "1", "dollar", Money{}, errors.ErrInvalidCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(tt.amount, tt.currency)
+
+			assert.True(t, goerrors.Is(err, tt.err), "got %v", err)
+			assert.Equal(t, tt.expected, m)
+		})
+	}
+
+	t.Run("should parse currency codes in any case", func(t *testing.T) {
+		c, err := ParseCurrency(" idr ")
+
+		assert.NoError(t, err)
+		assert.Equal(t, IDR, c)
+	})
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	t.Run("should sum receipts to the cent", func(t *testing.T) {
+		total := Zero(USD)
+		for i := 0; i < 10; i++ {
+			total = total.Add(FromMinor(10, USD))
+		}
+
+		assert.Equal(t, FromMajor(1, USD), total)
+		assert.Equal(t, "1.00 USD", total.String())
+	})
+
+	t.Run("should subtract and compare", func(t *testing.T) {
+		a, b := FromMajor(5, USD), FromMinor(250, USD)
+
+		assert.Equal(t, FromMinor(250, USD), a.Sub(b))
+		assert.Equal(t, 1, a.Cmp(b))
+		assert.Equal(t, b, Min(a, b))
+		assert.Equal(t, a, Max(a, b))
+		assert.True(t, b.Sub(a).IsNegative())
+	})
+
+	t.Run("should panic when mixing currencies", func(t *testing.T) {
+		assert.Panics(t, func() { FromMajor(1, USD).Add(FromMajor(1, EUR)) })
+	})
+
+	t.Run("should refuse to sum mixed currencies", func(t *testing.T) {
+		_, err := Sum(USD, FromMajor(1, USD), FromMajor(1, EUR))
+
+		assert.True(t, goerrors.Is(err, errors.ErrCurrencyMismatch))
+	})
+
+	t.Run("should prorate with the rounding mode", func(t *testing.T) {
+		rate := FromMajor(10, USD)
+
+		assert.Equal(t, FromMinor(333, USD), rate.Prorate(20*time.Minute, time.Hour, RoundHalfUp))
+		assert.Equal(t, FromMinor(334, USD), rate.Prorate(20*time.Minute, time.Hour, RoundUp))
+		assert.Equal(t, FromMajor(25, USD), rate.Prorate(150*time.Minute, time.Hour, RoundDown))
+	})
+
+	t.Run("should convert from whole units", func(t *testing.T) {
+		assert.Equal(t, FromMinor(1234, USD), FromRat(big.NewRat(12345, 1000), USD, RoundDown))
+		assert.Equal(t, big.NewRat(1234, 100), FromMinor(1234, USD).Rat())
+	})
+
+	t.Run("should format amounts", func(t *testing.T) {
+		assert.Equal(t, "-0.05", FromMinor(-5, USD).Decimal())
+		assert.Equal(t, "500 JPY", FromMinor(500, JPY).String())
+		assert.Equal(t, "1.500 KWD", FromMinor(1500, "KWD").String())
+	})
+}
+
+func TestRoundingModes(t *testing.T) {
+	tests := []struct {
+		value    *big.Rat
+		mode     RoundingMode
+		expected int64
+	}{
+		{big.NewRat(5, 2), RoundHalfUp, 3},
+		{big.NewRat(5, 2), RoundHalfEven, 2},
+		{big.NewRat(7, 2), RoundHalfEven, 4},
+		{big.NewRat(5, 2), RoundUp, 3},
+		{big.NewRat(5, 2), RoundDown, 2},
+		{big.NewRat(-5, 2), RoundHalfUp, -3},
+		{big.NewRat(-5, 2), RoundHalfEven, -2},
+		{big.NewRat(-7, 3), RoundUp, -3},
+		{big.NewRat(-7, 3), RoundDown, -2},
+		{big.NewRat(-7, 3), RoundHalfUp, -2},
+		{big.NewRat(4, 1), RoundUp, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode.String()+" "+tt.value.RatString(), func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.mode.round(tt.value))
+		})
+	}
+
+	t.Run("should parse rounding modes by name", func(t *testing.T) {
+		mode, err := ParseRoundingMode("half_even")
+		assert.NoError(t, err)
+		assert.Equal(t, RoundHalfEven, mode)
+
+		_, err = ParseRoundingMode("sideways")
+		assert.True(t, goerrors.Is(err, errors.ErrInvalidRoundingMode))
+	})
+}
@@ -0,0 +1,79 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+)
+
+// RoundingMode decides how an amount that falls between two minor units is
+// rounded
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds to the nearest minor unit, halves away from zero
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds to the nearest minor unit, halves to the even
+	// one, also known as banker's rounding
+	RoundHalfEven
+	// RoundUp rounds away from zero
+	RoundUp
+	// RoundDown rounds towards zero
+	RoundDown
+)
+
+var roundingModeNames = map[RoundingMode]string{
+	RoundHalfUp:   "half_up",
+	RoundHalfEven: "half_even",
+	RoundUp:       "up",
+	RoundDown:     "down",
+}
+
+// ParseRoundingMode returns the rounding mode with the given name, e.g.
+// half_even
+func ParseRoundingMode(name string) (RoundingMode, error) {
+	for mode, n := range roundingModeNames {
+		if n == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", errors.ErrInvalidRoundingMode, name)
+}
+
+func (m RoundingMode) String() string {
+	if name, exists := roundingModeNames[m]; exists {
+		return name
+	}
+	return fmt.Sprintf("RoundingMode(%d)", int(m))
+}
+
+// round rounds r to an integer
+func (m RoundingMode) round(r *big.Rat) int64 {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() != 0 {
+		// compare twice the remainder with the denominator to find out
+		// whether r is below, at or above the half
+		half := new(big.Int).Abs(rem)
+		half.Lsh(half, 1)
+		cmp := half.Cmp(r.Denom())
+
+		awayFromZero := false
+		switch m {
+		case RoundUp:
+			awayFromZero = true
+		case RoundHalfUp:
+			awayFromZero = cmp >= 0
+		case RoundHalfEven:
+			awayFromZero = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+		}
+		if awayFromZero {
+			q.Add(q, big.NewInt(int64(r.Sign())))
+		}
+	}
+
+	if !q.IsInt64() {
+		panic(fmt.Errorf("%w: %s overflows", errors.ErrInvalidAmount, r.FloatString(0)))
+	}
+	return q.Int64()
+}
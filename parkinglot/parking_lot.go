@@ -1,56 +1,443 @@
 package parkinglot
 
 import (
-	"fmt"
+	"reflect"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/natanaelrusli/parking-lot/clock"
 	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/event"
 	"github.com/natanaelrusli/parking-lot/fee"
+	"github.com/natanaelrusli/parking-lot/logging"
 	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/money"
+	"github.com/natanaelrusli/parking-lot/permit"
+	"github.com/natanaelrusli/parking-lot/repository"
 	"github.com/natanaelrusli/parking-lot/ticket"
 )
 
+// ParkingLot is safe for concurrent use. All access to the embedded
+// models.ParkingLot state must go through its methods.
+//
+// ParkedCars, UsedTickets and Reservations, and the tickets issued to the
+// parked cars, are a cache of the lot's state in its repository: every change is saved to the repository before it is applied
+// in memory, so a lot reopened from the same repository picks up where it
+// left off.
 type ParkingLot struct {
 	*models.ParkingLot
+	mu    sync.RWMutex
+	clock clock.Clock
+	repo  repository.ParkingLotRepository
+	// tickets issued to the parked cars, keyed by ticket number
+	tickets map[string]models.Ticket
+	// feeStrategy bills the cars leaving the lot, change it with
+	// ChangeFeeStrategy
+	feeStrategy fee.ParkingFeeStrategy
+	// lostTicketFeeStrategy bills the cars leaving without their ticket,
+	// nil bills them with feeStrategy for at least DefaultLostTicketStay
+	lostTicketFeeStrategy fee.ParkingFeeStrategy
+	// slot layout requested with WithSlotSizes, nil to keep the stored one
+	slotSizes []models.SlotSize
+	// permits set with WithPermits, nil when the lot takes none
+	permits permit.RegistryItf
+	// spaces kept for permit holders requested with WithPermitCapacity, nil
+	// to keep the stored number
+	permitCapacity *int
+	// signer set with WithTicketSigner, nil when tickets aren't signed
+	signer *ticket.Signer
+	// generator set with WithTicketGenerator, issues the numbers of
+	// unsigned tickets
+	generator ticket.Generator
+	// serial of the last ticket issued
+	serial uint64
+	// bus the lot publishes its events on, set with WithEventBus
+	bus *event.Bus
+	// whether the lot was full when it last published an event, to tell
+	// when it fills up or frees a space
+	full bool
+	// calls ExpireReservations when the next reservation starts or ends
+	reservationTimer clock.Timer
+	// subscriptions of the observers added with AddObserver
+	observers map[models.ParkingLotObserver]*event.Subscription
+	// logger set with WithLogger
+	logger logging.Logger
+}
+
+// DefaultHourlyRate is what lots charge per hour until their fee strategy
+// is changed
+var DefaultHourlyRate = money.FromMajor(10, money.USD)
+
+// DefaultLostTicketStay is the shortest stay cars leaving without their
+// ticket are billed for, unless the lot has a lost ticket fee strategy
+var DefaultLostTicketStay = 24 * time.Hour
+
+// Option configures a ParkingLot created with New
+type Option func(*ParkingLot)
+
+// WithSlotSizes sets the size of each slot of a lot created with New or
+// Open, sizes[i] being the size of slot number i+1. The lot capacity becomes
+// the number of slots.
+func WithSlotSizes(sizes ...models.SlotSize) Option {
+	return func(p *ParkingLot) {
+		p.slotSizes = sizes
+	}
+}
+
+// WithFeeStrategy sets the strategy cars leaving the lot are billed with,
+// e.g. one loaded with fee.LoadConfig
+func WithFeeStrategy(strategy fee.ParkingFeeStrategy) Option {
+	return func(p *ParkingLot) {
+		p.feeStrategy = strategy
+	}
+}
+
+// WithLostTicketFeeStrategy sets the strategy cars leaving without their
+// ticket are billed with
+func WithLostTicketFeeStrategy(strategy fee.ParkingFeeStrategy) Option {
+	return func(p *ParkingLot) {
+		p.lostTicketFeeStrategy = strategy
+	}
+}
+
+// WithTicketSigner makes the lot issue signed ticket numbers and only
+// accept tickets it signed, billed from their signed entry time. Cars
+// parked with unsigned tickets before can leave with UnparkLostTicket.
+func WithTicketSigner(signer *ticket.Signer) Option {
+	return func(p *ParkingLot) {
+		p.signer = signer
+	}
+}
+
+// WithTicketGenerator sets how the lot numbers unsigned tickets, random
+// 8 hex characters by default. Numbers already taken by a parked car or a
+// used ticket are skipped whatever the generator.
+func WithTicketGenerator(generator ticket.Generator) Option {
+	return func(p *ParkingLot) {
+		p.generator = generator
+	}
+}
+
+// WithPermits lets the holders of permits in the registry that cover the
+// lot park for free
+func WithPermits(permits permit.RegistryItf) Option {
+	return func(p *ParkingLot) {
+		p.permits = permits
+	}
+}
+
+// WithPermitCapacity keeps capacity spaces of a lot created with Open for
+// permit holders. Walk-ins can't park in them, permit holders can also park
+// in the other spaces once they are taken.
+func WithPermitCapacity(capacity int) Option {
+	return func(p *ParkingLot) {
+		p.permitCapacity = &capacity
+	}
+}
+
+// WithEventBus makes the lot publish its events on the bus, e.g. one
+// shared with other lots and attendants. Every lot has a bus of its own
+// otherwise.
+func WithEventBus(bus *event.Bus) Option {
+	return func(p *ParkingLot) {
+		p.bus = bus
+	}
+}
+
+// WithLogger sets the logger the lot logs the cars parking and leaving,
+// the fees charged and the lot filling up to, as well as the panics of the
+// handlers of the bus the lot creates when not given one. Lots log nothing
+// otherwise.
+func WithLogger(logger logging.Logger) Option {
+	return func(p *ParkingLot) {
+		p.logger = logger
+	}
+}
+
+// WithClock sets the clock used to stamp entry and exit times, and whose
+// timers tell the lot when reservations start and end
+func WithClock(c clock.Clock) Option {
+	return func(p *ParkingLot) {
+		p.clock = c
+	}
 }
 
 type ParkingLotItf interface {
 	Park(car *models.Car) (*models.Ticket, error)
-	Unpark(ticket *models.Ticket) (*models.Car, error)
+	Unpark(ticket *models.Ticket) (*models.Receipt, error)
+	UnparkLostTicket(licensePlate string) (*models.Receipt, error)
 	GetCapacity() int
 	GetParkedCars(ticket *models.Ticket) *models.Car
 	GetParkedCarCount() int
+	GetSlotForPlate(licensePlate string) (int, error)
+	GetPlatesInSlots() map[int]string
+	IsCarParked(car *models.Car) bool
 	IsFull() bool
-	AddObserver(observer models.ParkingLotObserver)
-	CalculateFee(duration time.Duration) float64
+	GetStatus() models.ParkingLotStatus
+	AddObserver(observer models.ParkingLotObserver, filters ...event.Filter) *event.Subscription
+	RemoveObserver(observer models.ParkingLotObserver)
+	Events() *event.Bus
+	CalculateFee(vehicleType models.VehicleType, duration time.Duration) money.Money
 	GetId() string
 	ChangeFeeStrategy(strategy fee.ParkingFeeStrategy)
+	Reserve(car *models.Car, start time.Time, end time.Time) (*models.Reservation, error)
+	CancelReservation(code string) error
+	ExpireReservations() []models.Reservation
+	GetReservations() []models.Reservation
+	ParkWithReservation(car *models.Car, code string) (*models.Ticket, error)
+}
+
+// New creates an empty lot with a random ID whose state is kept in memory
+func New(capacity int, opts ...Option) ParkingLotItf {
+	// saving to a fresh in-memory repository can't fail
+	p, _ := Open(repository.NewInMemoryRepository(), uuid.New().String()[:8], capacity, opts...)
+	return p
 }
 
-func New(capacity int) ParkingLotItf {
-	hourlystrategy := fee.NewHourlyFeeStrategy(10.0)
+// Open opens the lot with the given ID from repo, restoring its parked cars
+// and used tickets, or creates it if the repository doesn't know it yet.
+// A new lot gets capacity medium slots unless WithSlotSizes is given, and
+// keeps no spaces for permit holders unless WithPermitCapacity is given. A
+// stored lot keeps its slot layout and permit capacity, capacity is
+// ignored, unless the options are given.
+func Open(repo repository.ParkingLotRepository, id string, capacity int, opts ...Option) (ParkingLotItf, error) {
+	p := newParkingLot(repo, opts...)
 
-	return &ParkingLot{
-		ParkingLot: &models.ParkingLot{
-			ID:          uuid.New().String()[:8],
-			ParkedCars:  make(map[string]string),
-			UsedTickets: make(map[string]bool),
-			Capacity:    capacity,
-			Subscribers: []models.ParkingLotObserver{},
-			FeeStrategy: hourlystrategy,
-		},
+	record, err := repo.FindLot(id)
+	if err != nil && err != errors.ErrLotNotFound {
+		return nil, err
+	}
+	exists := err == nil
+
+	slotSizes := p.slotSizes
+	if slotSizes == nil && !exists {
+		slotSizes = make([]models.SlotSize, capacity)
+		for i := range slotSizes {
+			slotSizes[i] = models.SlotSizeMedium
+		}
+	}
+
+	if p.permitCapacity != nil {
+		slots := len(slotSizes)
+		if slotSizes == nil {
+			slots = record.Capacity
+		}
+		if *p.permitCapacity < 0 || *p.permitCapacity > slots {
+			return nil, errors.ErrInvalidPermitCapacity
+		}
+	}
+
+	if slotSizes != nil {
+		if err := repo.SaveLot(id, slotSizes); err != nil {
+			return nil, err
+		}
 	}
+
+	if p.permitCapacity != nil {
+		if err := repo.SavePermitCapacity(id, *p.permitCapacity); err != nil {
+			return nil, err
+		}
+	}
+
+	if slotSizes != nil || p.permitCapacity != nil {
+		if record, err = repo.FindLot(id); err != nil {
+			return nil, err
+		}
+	}
+
+	p.restore(record)
+	return p, nil
+}
+
+// OpenAll restores every lot stored in repo with the slot layout and permit
+// capacity it was saved with
+func OpenAll(repo repository.ParkingLotRepository, opts ...Option) ([]ParkingLotItf, error) {
+	records, err := repo.FindAllLots()
+	if err != nil {
+		return nil, err
+	}
+
+	lots := make([]ParkingLotItf, 0, len(records))
+	for _, record := range records {
+		p := newParkingLot(repo, opts...)
+		p.restore(record)
+		lots = append(lots, p)
+	}
+	return lots, nil
+}
+
+func newParkingLot(repo repository.ParkingLotRepository, opts ...Option) *ParkingLot {
+	hourlystrategy := fee.NewHourlyFeeStrategy(DefaultHourlyRate, money.RoundHalfUp)
+
+	p := &ParkingLot{
+		ParkingLot:  &models.ParkingLot{},
+		clock:       clock.NewRealClock(),
+		repo:        repo,
+		feeStrategy: hourlystrategy,
+		generator:   ticket.NewRandomGenerator(),
+		observers:   make(map[models.ParkingLotObserver]*event.Subscription),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.bus == nil {
+		var busOpts []event.BusOption
+		if p.logger != nil {
+			busOpts = append(busOpts, event.WithErrorHandler(event.LogErrors(p.logger)))
+		}
+		p.bus = event.NewBus(busOpts...)
+	}
+	if p.logger == nil {
+		p.logger = logging.Nop()
+	}
+
+	return p
+}
+
+// restore loads the lot state from its repository record
+func (p *ParkingLot) restore(record *repository.LotRecord) {
+	parkedCars := make(map[string]string, len(record.Sessions))
+	tickets := make(map[string]models.Ticket, len(record.Sessions))
+	permitTickets := make(map[string]string)
+	for ticketNumber, session := range record.Sessions {
+		parkedCars[ticketNumber] = session.Car.LicensePlate
+		tickets[ticketNumber] = session.Ticket
+		if session.Ticket.PermitID != "" {
+			permitTickets[ticketNumber] = session.Ticket.PermitID
+		}
+	}
+
+	p.ID = record.ID
+	p.ParkedCars = parkedCars
+	p.tickets = tickets
+	p.UsedTickets = record.UsedTickets
+	p.Capacity = record.Capacity
+	p.Slots = restoreSlots(record)
+	p.Reservations = restoreReservations(record)
+	p.PermitCapacity = record.PermitCapacity
+	p.PermitTickets = permitTickets
+	// every ticket issued is either parked or used
+	p.serial = uint64(len(record.Sessions) + len(record.UsedTickets))
+	p.full = p.status().IsFull
+	p.scheduleReservations(p.clock.Now())
+
+	if seeder, ok := p.generator.(ticket.Seeder); ok {
+		issued := make([]string, 0, len(record.Sessions)+len(record.UsedTickets))
+		for ticketNumber := range record.Sessions {
+			issued = append(issued, ticketNumber)
+		}
+		for ticketNumber := range record.UsedTickets {
+			issued = append(issued, ticketNumber)
+		}
+		seeder.Seed(record.ID, issued)
+	}
+}
+
+// restoreSlots puts every stored session back in its slot. Sessions whose
+// slot is unknown, taken or too small, e.g. after the layout changed, get
+// the nearest free slot that fits, with extra slots added if there is none.
+func restoreSlots(record *repository.LotRecord) []models.Slot {
+	slots := make([]models.Slot, len(record.SlotSizes))
+	for i, size := range record.SlotSizes {
+		slots[i] = models.Slot{Number: i + 1, Size: size}
+	}
+
+	ticketNumbers := make([]string, 0, len(record.Sessions))
+	for ticketNumber := range record.Sessions {
+		ticketNumbers = append(ticketNumbers, ticketNumber)
+	}
+	sort.Strings(ticketNumbers)
+
+	var misplaced []models.ParkingSession
+	for _, ticketNumber := range ticketNumbers {
+		session := record.Sessions[ticketNumber]
+		i := session.Ticket.SlotNumber - 1
+		if i < 0 || i >= len(slots) || !slots[i].IsFree() || !slots[i].Size.Fits(session.Car.GetVehicleType()) {
+			misplaced = append(misplaced, session)
+			continue
+		}
+		occupySlot(&slots[i], session.Ticket.TicketNumber, session.Car)
+	}
+
+	for _, session := range misplaced {
+		vehicleType := session.Car.GetVehicleType()
+		i := findSlot(slots, vehicleType)
+		if i < 0 {
+			slots = append(slots, models.Slot{
+				Number: len(slots) + 1,
+				Size:   vehicleType.RequiredSlotSize(),
+			})
+			i = len(slots) - 1
+		}
+		occupySlot(&slots[i], session.Ticket.TicketNumber, session.Car)
+	}
+
+	return slots
+}
+
+func occupySlot(slot *models.Slot, ticketNumber string, car models.Car) {
+	slot.TicketNumber = ticketNumber
+	slot.LicensePlate = car.LicensePlate
+	slot.VehicleType = car.GetVehicleType()
+}
+
+// findSlot returns the index of the free slot to park a vehicle of the given
+// type in, or -1 if none fits. The smallest fitting size is preferred, so
+// motorcycles don't take up bus bays, and among those the nearest, lowest
+// numbered slot.
+func findSlot(slots []models.Slot, vehicleType models.VehicleType) int {
+	best := -1
+	for i, slot := range slots {
+		if !slot.IsFree() || !slot.Size.Fits(vehicleType) {
+			continue
+		}
+		if best < 0 || slot.Size.Smaller(slots[best].Size) {
+			best = i
+		}
+	}
+	return best
 }
 
 func (p *ParkingLot) ChangeFeeStrategy(strategy fee.ParkingFeeStrategy) {
-	p.FeeStrategy = strategy
+	p.mu.Lock()
+	changed := &event.StrategyChanged{
+		Metadata: p.metadata(p.clock.Now()),
+		Kind:     event.StrategyKindFee,
+		Previous: fee.Name(p.feeStrategy),
+		Current:  fee.Name(strategy),
+	}
+	p.feeStrategy = strategy
+	events := p.enqueue(changed)
+	p.mu.Unlock()
+
+	p.publish(events...)
 }
 
 func (p *ParkingLot) GetParkedCarCount() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	return len(p.ParkedCars)
 }
 
+// IsCarParked reports whether a car with the same license plate is
+// currently parked in this lot.
+func (p *ParkingLot) IsCarParked(car *models.Car) bool {
+	if car == nil {
+		return false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.checkCarExist(car)
+}
+
+// checkCarExist must be called with p.mu held.
 func (p *ParkingLot) checkCarExist(car *models.Car) bool {
 	for _, plateNumber := range p.ParkedCars {
 		if plateNumber == car.LicensePlate {
@@ -60,70 +447,394 @@ func (p *ParkingLot) checkCarExist(car *models.Car) bool {
 	return false
 }
 
+// GetSlotForPlate returns the number of the slot the car is parked in
+func (p *ParkingLot) GetSlotForPlate(licensePlate string) (int, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, slot := range p.Slots {
+		if !slot.IsFree() && slot.LicensePlate == licensePlate {
+			return slot.Number, nil
+		}
+	}
+	return 0, errors.ErrCarNotFound
+}
+
+// GetPlatesInSlots returns the license plate parked in each occupied slot,
+// keyed by slot number
+func (p *ParkingLot) GetPlatesInSlots() map[int]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	plates := make(map[int]string)
+	for _, slot := range p.Slots {
+		if !slot.IsFree() {
+			plates[slot.Number] = slot.LicensePlate
+		}
+	}
+	return plates
+}
+
 func (p *ParkingLot) GetId() string {
 	return p.ID
 }
 
 func (p *ParkingLot) IsFull() bool {
-	return len(p.ParkedCars) >= p.Capacity
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.occupied(p.clock.Now(), "") >= p.Capacity
 }
 
-// Adding new observers
-// why we can use interface as the observer?
-func (p *ParkingLot) AddObserver(observer models.ParkingLotObserver) {
-	p.Subscribers = append(p.Subscribers, observer)
+func (p *ParkingLot) GetStatus() models.ParkingLotStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.status()
 }
 
-// Notifying all observers
-func (p *ParkingLot) notifyObservers() {
-	status := models.ParkingLotStatus{
-		IsFull:    len(p.ParkedCars) >= p.Capacity,
-		LotID:     p.ID,
-		Capacity:  p.Capacity,
-		Available: p.Capacity - len(p.ParkedCars),
+// status must be called with p.mu held.
+func (p *ParkingLot) status() models.ParkingLotStatus {
+	now := p.clock.Now()
+	held := p.held(now, "")
+	heldTypes := p.heldTypes(now, "")
+	available := p.Capacity - len(p.ParkedCars) - held - p.permitSpacesFree()
+	if available < 0 {
+		available = 0
 	}
 
+	capacityByType := make(map[models.VehicleType]int, len(models.VehicleTypes))
+	availableByType := make(map[models.VehicleType]int, len(models.VehicleTypes))
+	for _, vehicleType := range models.VehicleTypes {
+		capacityByType[vehicleType] = 0
+		availableByType[vehicleType] = 0
+		for _, slot := range p.Slots {
+			if !slot.Size.Fits(vehicleType) {
+				continue
+			}
+			capacityByType[vehicleType]++
+			if slot.IsFree() {
+				availableByType[vehicleType]++
+			}
+		}
+		// spaces held for reservations or permit holders can't be taken
+		// by walk-ins, nor can the slots the reserved vehicles need
+		if availableByType[vehicleType] > available {
+			availableByType[vehicleType] = available
+		}
+		vehicles := append([]models.VehicleType(nil), heldTypes...)
+		fitting := 0
+		for fitting < availableByType[vehicleType] && p.fitsFreeSlots(append(vehicles, vehicleType)) {
+			vehicles = append(vehicles, vehicleType)
+			fitting++
+		}
+		availableByType[vehicleType] = fitting
+	}
+
+	return models.ParkingLotStatus{
+		IsFull:          available == 0,
+		LotID:           p.ID,
+		Capacity:        p.Capacity,
+		Available:       available,
+		Reserved:        held,
+		PermitCapacity:  p.PermitCapacity,
+		PermitParked:    len(p.PermitTickets),
+		CapacityByType:  capacityByType,
+		AvailableByType: availableByType,
+	}
+}
+
+// AddObserver makes the observer receive the lot status whenever a car
+// parks or leaves, or a reservation changes the spaces available, limited
+// to the events that pass every filter. It subscribes the observer to the
+// lot's events through an event.StatusObserver and returns the
+// subscription, cancel it to stop the observer being notified.
+//
+// Adding an observer already added returns its subscription unchanged, so
+// it isn't notified twice.
+func (p *ParkingLot) AddObserver(observer models.ParkingLotObserver, filters ...event.Filter) *event.Subscription {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	comparable := isComparable(observer)
+	if comparable {
+		if subscription, exists := p.observers[observer]; exists && subscription.Active() {
+			return subscription
+		}
+	}
+
+	subscription := p.bus.Subscribe(event.NewStatusObserver(p.ID, observer), filters...)
+	if comparable {
+		p.observers[observer] = subscription
+	}
+	return subscription
+}
+
+// RemoveObserver stops the observer being notified, it does nothing if the
+// observer wasn't added
+func (p *ParkingLot) RemoveObserver(observer models.ParkingLotObserver) {
+	if !isComparable(observer) {
+		return
+	}
+
+	p.mu.Lock()
+	subscription, exists := p.observers[observer]
+	delete(p.observers, observer)
+	p.mu.Unlock()
+
+	if exists {
+		subscription.Cancel()
+	}
+}
+
+// isComparable reports whether the observer can be told apart from the
+// others, observers whose type can't be a map key, e.g. funcs, can't
+func isComparable(observer models.ParkingLotObserver) bool {
+	return observer != nil && reflect.TypeOf(observer).Comparable()
+}
+
+// Events returns the bus the lot publishes its events on.
+// Events are numbered under the lot lock, so they are delivered in the
+// order the lot changed even when cars park and leave concurrently, and
+// delivered once the lock is released, so handlers are free to call back
+// into the lot (or into an attendant that is itself parking a car).
+func (p *ParkingLot) Events() *event.Bus {
+	return p.bus
+}
+
+// metadata must be called with p.mu held.
+func (p *ParkingLot) metadata(now time.Time) event.Metadata {
+	return event.Metadata{Time: now, LotID: p.ID}
+}
+
+// withAvailability appends LotFull or LotAvailable to the events when the
+// lot filled up or freed a space since the last events.
+// It must be called with p.mu held.
+func (p *ParkingLot) withAvailability(events []event.Event, status models.ParkingLotStatus, now time.Time) []event.Event {
+	if status.IsFull == p.full {
+		return events
+	}
+	p.full = status.IsFull
+
 	if status.IsFull {
-		fmt.Printf("ALERT: Parking lot %s is now FULL (Capacity: %d)\n",
-			status.LotID, status.Capacity)
-	} else {
-		fmt.Printf("Parking lot %s has %d available spaces (Capacity: %d)\n",
-			status.LotID, status.Available, status.Capacity)
+		return append(events, &event.LotFull{Metadata: p.metadata(now), Status: status})
+	}
+	return append(events, &event.LotAvailable{Metadata: p.metadata(now), Status: status})
+}
+
+// publishStatus publishes the lot status after a change that moved no
+// car, e.g. a reservation
+func (p *ParkingLot) publishStatus() {
+	p.mu.Lock()
+	now := p.clock.Now()
+	status := p.status()
+	events := []event.Event{&event.StatusChanged{Metadata: p.metadata(now), Status: status}}
+	events = p.enqueue(p.withAvailability(events, status, now)...)
+	p.mu.Unlock()
+
+	p.publish(events...)
+}
+
+// enqueue numbers the events of a change on the bus and returns them, to
+// be published once the lock is released.
+// It must be called with p.mu held.
+func (p *ParkingLot) enqueue(events ...event.Event) []event.Event {
+	p.bus.Enqueue(events...)
+	return events
+}
+
+// publish delivers the events enqueued by a change once the lot lock is
+// released, and logs them
+func (p *ParkingLot) publish(events ...event.Event) {
+	p.bus.Deliver()
+
+	for _, e := range events {
+		p.logEvent(e)
 	}
+}
 
-	// Notify each observer
-	for _, observer := range p.Subscribers {
-		observer.OnParkingLotStatusChanged(status)
+func (p *ParkingLot) logEvent(e event.Event) {
+	switch e := e.(type) {
+	case *event.CarParked:
+		p.logger.Info("car parked",
+			logging.KeyLot, e.LotID,
+			logging.KeyTicket, e.Ticket.TicketNumber,
+			logging.KeyPlate, e.Car.LicensePlate,
+			logging.KeySlot, e.Ticket.SlotNumber,
+			"available", e.Status.Available)
+	case *event.CarUnparked:
+		msg := "car left"
+		if e.LostTicket {
+			msg = "car left without its ticket"
+		}
+		p.logger.Info(msg,
+			logging.KeyLot, e.LotID,
+			logging.KeyTicket, e.Ticket.TicketNumber,
+			logging.KeyPlate, e.Car.LicensePlate,
+			logging.KeySlot, e.SlotNumber,
+			"available", e.Status.Available)
+	case *event.FeeCharged:
+		p.logger.Info("fee charged",
+			logging.KeyLot, e.LotID,
+			logging.KeyTicket, e.Receipt.Ticket.TicketNumber,
+			logging.KeyPlate, e.Receipt.Car.LicensePlate,
+			"fee", e.Receipt.Fee,
+			"strategy", e.Receipt.FeeStrategy)
+	case *event.LotFull:
+		p.logger.Warn("parking lot is full",
+			logging.KeyLot, e.LotID,
+			"capacity", e.Status.Capacity)
+	case *event.LotAvailable:
+		p.logger.Info("parking lot has space again",
+			logging.KeyLot, e.LotID,
+			"available", e.Status.Available,
+			"capacity", e.Status.Capacity)
+	case *event.StatusChanged:
+		p.logger.Debug("parking lot status changed",
+			logging.KeyLot, e.LotID,
+			"available", e.Status.Available,
+			"reserved", e.Status.Reserved)
+	case *event.StrategyChanged:
+		p.logger.Info("fee strategy changed",
+			logging.KeyLot, e.LotID,
+			"previous", e.Previous,
+			"current", e.Current)
 	}
 }
 
+// Park parks the car in the nearest free slot that fits it. Spaces held
+// for reservations are only given to their holder: a car with an active
+// reservation for its license plate redeems it and parks in its space.
+// Likewise, cars with a valid permit for the lot may park in the spaces
+// kept for permit holders, and leave without paying while the permit lasts.
 func (p *ParkingLot) Park(car *models.Car) (*models.Ticket, error) {
+	ticket, events, err := p.park(car, "")
+	if err != nil {
+		return nil, err
+	}
+
+	p.publish(events...)
+
+	return ticket, nil
+}
+
+// park checks capacity and records the car under a single lock, so two
+// concurrent callers can never both take the last free space. The car
+// redeems the reservation with the given code, or when code is empty its
+// license plate's reservation active now if it has one. It returns the
+// events to publish once the lock is released.
+func (p *ParkingLot) park(car *models.Car, code string) (*models.Ticket, []event.Event, error) {
 	if car == nil {
-		return nil, errors.ErrNilCar
+		return nil, nil, errors.ErrNilCar
 	}
 
 	if car.LicensePlate == "" {
-		return nil, errors.ErrEmptyLicensePlate
+		return nil, nil, errors.ErrEmptyLicensePlate
+	}
+
+	vehicleType := car.GetVehicleType()
+	if !vehicleType.IsValid() {
+		return nil, nil, errors.ErrInvalidVehicleType
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	if err := p.expireReservations(now); err != nil {
+		return nil, nil, err
 	}
 
-	if len(p.ParkedCars) >= p.Capacity {
-		return nil, errors.ErrNoAvailablePosition
+	reservation, err := p.findReservation(car, code, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	carPermit, err := p.findPermit(car, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the space held by the car's own reservation is the one it parks in
+	var reservationCode string
+	if reservation != nil {
+		reservationCode = reservation.Code
+	}
+	occupied := p.occupied(now, reservationCode)
+	if carPermit != nil {
+		occupied -= p.permitSpacesFree()
+	}
+	if occupied >= p.Capacity {
+		return nil, nil, errors.ErrNoAvailablePosition
 	}
 
 	if p.checkCarExist(car) {
-		return nil, errors.ErrCarAlreadyParked
+		return nil, nil, errors.ErrCarAlreadyParked
+	}
+
+	// the slots left must still fit the vehicles of the other reservations
+	// active now, the smallest one the car fits in then always does
+	if !p.fitsFreeSlots(append(p.heldTypes(now, reservationCode), vehicleType)) {
+		return nil, nil, errors.ErrNoCompatibleSlot
+	}
+	slot := findSlot(p.Slots, vehicleType)
+
+	ticketNumber, err := p.newTicketNumber(now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t := &models.Ticket{
+		TicketNumber:    ticketNumber,
+		EntryTime:       now,
+		SlotNumber:      p.Slots[slot].Number,
+		ReservationCode: reservationCode,
+	}
+	if carPermit != nil {
+		t.PermitID = carPermit.ID
+	}
+
+	err = p.repo.SaveSession(p.ID, models.ParkingSession{Ticket: *t, Car: *car})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.ParkedCars[t.TicketNumber] = car.LicensePlate
+	p.tickets[t.TicketNumber] = *t
+	occupySlot(&p.Slots[slot], t.TicketNumber, *car)
+	if t.PermitID != "" {
+		p.PermitTickets[t.TicketNumber] = t.PermitID
+	}
+
+	if reservation != nil {
+		p.redeemReservation(reservation.Code)
 	}
 
-	ticketNumber := ticket.GenerateTicketNumber()
-	p.ParkedCars[ticketNumber] = car.LicensePlate
+	status := p.status()
+	events := []event.Event{&event.CarParked{Metadata: p.metadata(now), Car: *car, Ticket: *t, Status: status}}
+	return t, p.enqueue(p.withAvailability(events, status, now)...), nil
+}
+
+// newTicketNumber returns a ticket number that no parked car holds and
+// that was never used in the lot, so a new session can't overwrite a live
+// one or be mistaken for a closed one.
+// It must be called with p.mu held.
+func (p *ParkingLot) newTicketNumber(now time.Time) (string, error) {
+	generator := p.generator
+	if p.signer != nil {
+		generator = ticket.GeneratorFunc(func(lotID string) string {
+			p.serial++
+			return p.signer.Sign(ticket.Claims{LotID: lotID, EntryTime: now, Serial: p.serial})
+		})
+	}
 
-	// Notify observers after successful parking
-	p.notifyObservers()
+	return ticket.NewUniqueGenerator(generator, p.isTicketTaken).Generate(p.ID)
+}
 
-	return &models.Ticket{
-		TicketNumber: ticketNumber,
-		EntryTime:    time.Now(),
-	}, nil
+// isTicketTaken must be called with p.mu held.
+func (p *ParkingLot) isTicketTaken(ticketNumber string) bool {
+	_, parked := p.ParkedCars[ticketNumber]
+	return parked || p.UsedTickets[ticketNumber]
 }
 
 func (p *ParkingLot) GetCapacity() int {
@@ -131,43 +842,223 @@ func (p *ParkingLot) GetCapacity() int {
 }
 
 func (p *ParkingLot) GetParkedCars(ticket *models.Ticket) *models.Car {
+	if ticket == nil {
+		return nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.getParkedCar(ticket)
+}
+
+// getParkedCar must be called with p.mu held.
+func (p *ParkingLot) getParkedCar(ticket *models.Ticket) *models.Car {
 	licensePlate, exists := p.ParkedCars[ticket.TicketNumber]
 	if !exists {
 		return nil
 	}
 
-	return &models.Car{
+	car := &models.Car{
 		LicensePlate: licensePlate,
 	}
+	for _, slot := range p.Slots {
+		if slot.TicketNumber == ticket.TicketNumber {
+			car.Type = slot.VehicleType
+			break
+		}
+	}
+	return car
 }
 
-func (p *ParkingLot) Unpark(ticket *models.Ticket) (*models.Car, error) {
+// Unpark releases the car held by the ticket and bills the stay, from the
+// entry time the lot issued the ticket with until now, with the lot's
+// current fee strategy. The entry time on the ticket presented is not
+// trusted. Cars that parked with a permit only pay for the part of the stay
+// their permit no longer covers at exit.
+func (p *ParkingLot) Unpark(ticket *models.Ticket) (*models.Receipt, error) {
+	receipt, events, err := p.unpark(ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	p.publish(events...)
+
+	return receipt, nil
+}
+
+func (p *ParkingLot) unpark(ticket *models.Ticket) (*models.Receipt, []event.Event, error) {
 	if ticket == nil {
-		return nil, errors.ErrNilTicket
+		return nil, nil, errors.ErrNilTicket
 	}
 
 	if ticket.TicketNumber == "" {
-		return nil, errors.ErrEmptyTicketNumber
+		return nil, nil, errors.ErrEmptyTicketNumber
+	}
+
+	if p.signer != nil {
+		if _, err := p.signer.VerifyTicket(ticket, p.ID); err != nil {
+			return nil, nil, err
+		}
 	}
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if p.UsedTickets[ticket.TicketNumber] {
-		return nil, errors.ErrUnrecognizedTicket
+		return nil, nil, errors.ErrUnrecognizedTicket
 	}
 
-	car := p.GetParkedCars(ticket)
+	car := p.getParkedCar(ticket)
 	if car == nil {
-		return nil, errors.ErrUnrecognizedTicket
+		return nil, nil, errors.ErrUnrecognizedTicket
+	}
+
+	// the stay is billed from the ticket the lot issued, whatever entry
+	// time is printed on the one the driver presents
+	issued, err := p.issuedTicket(ticket.TicketNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p.release(issued, car, p.feeStrategy, false)
+}
+
+// UnparkLostTicket releases the car with the license plate when its driver
+// lost the ticket. The session is looked up in the repository and the stay
+// is billed with the lost ticket fee strategy, unless it is covered by a
+// permit. The receipt records that the car left without its ticket.
+func (p *ParkingLot) UnparkLostTicket(licensePlate string) (*models.Receipt, error) {
+	receipt, events, err := p.unparkLostTicket(licensePlate)
+	if err != nil {
+		return nil, err
+	}
+
+	p.publish(events...)
+
+	return receipt, nil
+}
+
+func (p *ParkingLot) unparkLostTicket(licensePlate string) (*models.Receipt, []event.Event, error) {
+	if licensePlate == "" {
+		return nil, nil, errors.ErrEmptyLicensePlate
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ticketNumber string
+	for number, plate := range p.ParkedCars {
+		if plate == licensePlate {
+			ticketNumber = number
+			break
+		}
+	}
+	if ticketNumber == "" {
+		return nil, nil, errors.ErrCarNotFound
+	}
+
+	ticket, err := p.issuedTicket(ticketNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	strategy := p.lostTicketFeeStrategy
+	if strategy == nil {
+		strategy = fee.NewLostTicketStrategy(p.feeStrategy, DefaultLostTicketStay)
+	}
+
+	return p.release(ticket, p.getParkedCar(ticket), strategy, true)
+}
+
+// issuedTicket returns the ticket the lot issued to the parked car with
+// the number, as saved with its session.
+// It must be called with p.mu held.
+func (p *ParkingLot) issuedTicket(ticketNumber string) (*models.Ticket, error) {
+	ticket, exists := p.tickets[ticketNumber]
+	if !exists {
+		return nil, errors.ErrSessionNotFound
+	}
+	return &ticket, nil
+}
+
+// release closes the session of the parked car and bills its stay with the
+// strategy, recording on the receipt whether the car left without its
+// ticket. It returns the events to publish once the lock is released.
+// It must be called with p.mu held.
+func (p *ParkingLot) release(ticket *models.Ticket, car *models.Car, strategy fee.ParkingFeeStrategy, lostTicket bool) (*models.Receipt, []event.Event, error) {
+	stay := fee.NewStay(car, ticket, p.clock.Now())
+	permitID := p.PermitTickets[ticket.TicketNumber]
+
+	parkingFee := strategy.CalculateFee(stay)
+	if permitID != "" {
+		uncovered, err := p.permitFee(permitID, stay, strategy)
+		if err != nil {
+			return nil, nil, err
+		}
+		parkingFee = uncovered
+	}
+
+	if err := p.repo.CloseSession(p.ID, ticket.TicketNumber); err != nil {
+		return nil, nil, err
 	}
 
 	delete(p.ParkedCars, ticket.TicketNumber)
+	delete(p.tickets, ticket.TicketNumber)
+	delete(p.PermitTickets, ticket.TicketNumber)
 	p.UsedTickets[ticket.TicketNumber] = true
+	slotNumber := p.freeSlot(ticket.TicketNumber)
 
-	// Notify observers after successful unparking
-	p.notifyObservers()
+	receipt := &models.Receipt{
+		Car:         car,
+		Ticket:      ticket,
+		LotID:       p.ID,
+		SlotNumber:  slotNumber,
+		EntryTime:   stay.EntryTime,
+		ExitTime:    stay.ExitTime,
+		Duration:    stay.Duration,
+		Fee:         parkingFee,
+		FeeStrategy: fee.Name(strategy),
+		PermitID:    permitID,
+		LostTicket:  lostTicket,
+	}
 
-	return car, nil
+	status := p.status()
+	events := []event.Event{
+		&event.CarUnparked{
+			Metadata:   p.metadata(stay.ExitTime),
+			Car:        *car,
+			Ticket:     *ticket,
+			SlotNumber: slotNumber,
+			LostTicket: lostTicket,
+			Status:     status,
+		},
+		&event.FeeCharged{Metadata: p.metadata(stay.ExitTime), Receipt: *receipt},
+	}
+	return receipt, p.enqueue(p.withAvailability(events, status, stay.ExitTime)...), nil
 }
 
-func (p *ParkingLot) CalculateFee(duration time.Duration) float64 {
-	return p.FeeStrategy.CalculateFee(duration)
+// freeSlot frees the slot held by the ticket and returns its number.
+// It must be called with p.mu held.
+func (p *ParkingLot) freeSlot(ticketNumber string) int {
+	for i := range p.Slots {
+		if p.Slots[i].TicketNumber == ticketNumber {
+			p.Slots[i] = models.Slot{Number: p.Slots[i].Number, Size: p.Slots[i].Size}
+			return p.Slots[i].Number
+		}
+	}
+	return 0
+}
+
+// CalculateFee quotes the fee for a vehicle of the given type parking from
+// now for duration
+func (p *ParkingLot) CalculateFee(vehicleType models.VehicleType, duration time.Duration) money.Money {
+	p.mu.RLock()
+	strategy := p.feeStrategy
+	p.mu.RUnlock()
+
+	entryTime := p.clock.Now()
+	car := &models.Car{Type: vehicleType}
+	ticket := &models.Ticket{EntryTime: entryTime}
+	return strategy.CalculateFee(fee.NewStay(car, ticket, entryTime.Add(duration)))
 }
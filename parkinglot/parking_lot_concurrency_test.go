@@ -0,0 +1,187 @@
+package parkinglot
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/natanaelrusli/parking-lot/car"
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/event"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// run with `go test -race` to catch unsynchronized access to the lot maps
+func TestParkingLotConcurrency(t *testing.T) {
+	t.Run("should never park more cars than capacity", func(t *testing.T) {
+		// arrange
+		capacity := 10
+		pl := New(capacity)
+		var parked int32
+		var wg sync.WaitGroup
+
+		// act
+		for i := 0; i < 200; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, err := pl.Park(car.NewCar(fmt.Sprintf("CAR%d", i)))
+				if err == nil {
+					atomic.AddInt32(&parked, 1)
+					return
+				}
+				assert.Equal(t, errors.ErrNoAvailablePosition, err)
+			}(i)
+		}
+		wg.Wait()
+
+		// assert
+		assert.Equal(t, int32(capacity), parked)
+		assert.Equal(t, capacity, pl.GetParkedCarCount())
+		assert.True(t, pl.IsFull())
+	})
+
+	t.Run("should park the same car only once", func(t *testing.T) {
+		// arrange
+		pl := New(10)
+		var parked int32
+		var wg sync.WaitGroup
+
+		// act
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := pl.Park(car.NewCar("SAME1")); err == nil {
+					atomic.AddInt32(&parked, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		assert.Equal(t, int32(1), parked)
+		assert.Equal(t, 1, pl.GetParkedCarCount())
+	})
+
+	t.Run("should handle concurrent park, unpark and reads", func(t *testing.T) {
+		// arrange
+		pl := New(5)
+		pl.AddObserver(NewMockObserverSafe())
+		var wg sync.WaitGroup
+
+		// act
+		for i := 0; i < 100; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				c := car.NewCar(fmt.Sprintf("CAR%d", i))
+				for {
+					ticket, err := pl.Park(c)
+					if err == errors.ErrNoAvailablePosition {
+						runtime.Gosched()
+						continue
+					}
+					assert.NoError(t, err)
+					assert.NotNil(t, pl.GetParkedCars(ticket))
+
+					_, err = pl.Unpark(ticket)
+					assert.NoError(t, err)
+					return
+				}
+			}(i)
+			go func() {
+				defer wg.Done()
+				pl.IsFull()
+				pl.GetParkedCarCount()
+				pl.CalculateFee(models.VehicleTypeCar, 0)
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		assert.Equal(t, 0, pl.GetParkedCarCount())
+	})
+
+	t.Run("should accept only one unpark per ticket", func(t *testing.T) {
+		// arrange
+		pl := New(1)
+		ticket, err := pl.Park(car.NewCar("ABC123"))
+		assert.NoError(t, err)
+		var unparked int32
+		var wg sync.WaitGroup
+
+		// act
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := pl.Unpark(ticket); err == nil {
+					atomic.AddInt32(&unparked, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		assert.Equal(t, int32(1), unparked)
+	})
+
+	t.Run("should deliver the lot filling up and freeing a space in the order it happened", func(t *testing.T) {
+		// arrange
+		pl := New(1)
+		var full []bool
+		pl.Events().Subscribe(event.HandlerFunc(func(e event.Event) {
+			switch e.(type) {
+			case *event.LotFull:
+				full = append(full, true)
+			case *event.LotAvailable:
+				full = append(full, false)
+			}
+		}))
+		var wg sync.WaitGroup
+
+		// act
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					ticket, err := pl.Park(car.NewCar(fmt.Sprintf("CAR%d-%d", i, j)))
+					if err == nil {
+						_, _ = pl.Unpark(ticket)
+					}
+					runtime.Gosched()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		// assert
+		assert.NotEmpty(t, full)
+		for i, isFull := range full {
+			assert.Equal(t, i%2 == 0, isFull, "event %d", i)
+		}
+		assert.Equal(t, pl.IsFull(), full[len(full)-1])
+	})
+}
+
+// MockObserverSafe is a MockObserver that can be notified from many goroutines
+type MockObserverSafe struct {
+	mu            sync.Mutex
+	notifications []models.ParkingLotStatus
+}
+
+func NewMockObserverSafe() *MockObserverSafe {
+	return &MockObserverSafe{}
+}
+
+func (m *MockObserverSafe) OnParkingLotStatusChanged(status models.ParkingLotStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.notifications = append(m.notifications, status)
+}
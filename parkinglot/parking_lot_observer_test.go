@@ -1,10 +1,18 @@
 package parkinglot
 
 import (
+	"bytes"
+	"fmt"
 	"log"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/natanaelrusli/parking-lot/car"
+	"github.com/natanaelrusli/parking-lot/clock"
+	"github.com/natanaelrusli/parking-lot/event"
+	"github.com/natanaelrusli/parking-lot/fee"
+	"github.com/natanaelrusli/parking-lot/logging"
 	"github.com/natanaelrusli/parking-lot/mocks"
 	"github.com/natanaelrusli/parking-lot/models"
 	"github.com/stretchr/testify/assert"
@@ -312,3 +320,251 @@ func TestParkingLotObserverWithMockery(t *testing.T) {
 		assert.NoError(t, err2)
 	})
 }
+
+// eventRecorder records the events published on a bus
+type eventRecorder struct {
+	events []event.Event
+}
+
+func (r *eventRecorder) HandleEvent(e event.Event) {
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) types() []event.Type {
+	types := make([]event.Type, len(r.events))
+	for i, e := range r.events {
+		types[i] = e.Type()
+	}
+	return types
+}
+
+func TestParkingLotEvents(t *testing.T) {
+	entry := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+
+	t.Run("should publish the cars parking and leaving", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		parkingLot := New(2, WithClock(c))
+		recorder := &eventRecorder{}
+		parkingLot.Events().Subscribe(recorder)
+
+		ticket, _ := parkingLot.Park(car.NewCar("ABC123"))
+		c.Advance(time.Hour)
+		receipt, _ := parkingLot.Unpark(ticket)
+
+		assert.Equal(t, []event.Type{event.TypeCarParked, event.TypeCarUnparked, event.TypeFeeCharged}, recorder.types())
+
+		parked := recorder.events[0].(*event.CarParked)
+		assert.Equal(t, uint64(1), parked.Sequence)
+		assert.Equal(t, entry, parked.Time)
+		assert.Equal(t, parkingLot.GetId(), parked.LotID)
+		assert.Equal(t, "ABC123", parked.Car.LicensePlate)
+		assert.Equal(t, *ticket, parked.Ticket)
+		assert.Equal(t, 1, parked.Status.Available)
+
+		unparked := recorder.events[1].(*event.CarUnparked)
+		assert.Equal(t, entry.Add(time.Hour), unparked.Time)
+		assert.Equal(t, ticket.TicketNumber, unparked.Ticket.TicketNumber)
+		assert.Equal(t, ticket.SlotNumber, unparked.SlotNumber)
+		assert.False(t, unparked.LostTicket)
+		assert.Equal(t, 2, unparked.Status.Available)
+
+		charged := recorder.events[2].(*event.FeeCharged)
+		assert.Equal(t, *receipt, charged.Receipt)
+	})
+
+	t.Run("should publish when the lot fills up and frees a space", func(t *testing.T) {
+		parkingLot := New(1)
+		recorder := &eventRecorder{}
+		parkingLot.Events().Subscribe(recorder)
+
+		_, _ = parkingLot.Park(car.NewCar("ABC123"))
+		_, _ = parkingLot.UnparkLostTicket("ABC123")
+
+		assert.Equal(t, []event.Type{
+			event.TypeCarParked, event.TypeLotFull,
+			event.TypeCarUnparked, event.TypeFeeCharged, event.TypeLotAvailable,
+		}, recorder.types())
+		assert.True(t, recorder.events[2].(*event.CarUnparked).LostTicket)
+		assert.True(t, recorder.events[3].(*event.FeeCharged).Receipt.LostTicket)
+	})
+
+	t.Run("should publish reservations changing the spaces available", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		parkingLot := New(1, WithClock(c))
+		recorder := &eventRecorder{}
+		observer := NewMockObserver("TestObserver")
+		parkingLot.Events().Subscribe(recorder)
+		parkingLot.AddObserver(observer)
+
+		reservation, _ := parkingLot.Reserve(car.NewCar("ABC123"), entry, entry.Add(time.Hour))
+		_ = parkingLot.CancelReservation(reservation.Code)
+
+		assert.Equal(t, []event.Type{
+			event.TypeStatusChanged, event.TypeLotFull,
+			event.TypeStatusChanged, event.TypeLotAvailable,
+		}, recorder.types())
+		assert.Len(t, observer.notifications, 2)
+		assert.True(t, observer.notifications[0].IsFull)
+	})
+
+	t.Run("should publish fee strategy changes", func(t *testing.T) {
+		parkingLot := New(1)
+		recorder := &eventRecorder{}
+		parkingLot.Events().Subscribe(recorder)
+
+		parkingLot.ChangeFeeStrategy(fee.NewFlatFeeStrategy(usd(5)))
+
+		changed := recorder.events[0].(*event.StrategyChanged)
+		assert.Equal(t, event.StrategyKindFee, changed.Kind)
+		assert.Equal(t, "HourlyFeeStrategy", changed.Previous)
+		assert.Equal(t, "FlatFeeStrategy", changed.Current)
+	})
+
+	t.Run("should number the events of lots sharing a bus", func(t *testing.T) {
+		bus := event.NewBus()
+		lot1 := New(1, WithEventBus(bus))
+		lot2 := New(1, WithEventBus(bus))
+		recorder := &eventRecorder{}
+		observer := NewMockObserver("TestObserver")
+		bus.Subscribe(recorder)
+		lot1.AddObserver(observer)
+
+		_, _ = lot1.Park(car.NewCar("ABC123"))
+		_, _ = lot2.Park(car.NewCar("XYZ789"))
+
+		assert.Len(t, recorder.events, 4)
+		assert.Equal(t, lot2.GetId(), recorder.events[3].Meta().LotID)
+		assert.Equal(t, uint64(4), recorder.events[3].Meta().Sequence)
+		// status observers only hear about the lot they observe
+		assert.Len(t, observer.notifications, 1)
+	})
+}
+
+// blockingObserver blocks every notification until release is closed
+type blockingObserver struct {
+	release chan struct{}
+}
+
+func (o *blockingObserver) OnParkingLotStatusChanged(status models.ParkingLotStatus) {
+	<-o.release
+}
+
+// panickingObserver panics on every notification
+type panickingObserver struct{}
+
+func (o *panickingObserver) OnParkingLotStatusChanged(status models.ParkingLotStatus) {
+	panic("display board offline")
+}
+
+func TestParkingLotAsyncObservers(t *testing.T) {
+	t.Run("should not let slow or panicking observers hold up parking", func(t *testing.T) {
+		dispatcher := event.NewDispatcher(event.WithDispatchErrorHandler(func(err error) {}))
+		parkingLot := New(2, WithEventBus(event.NewBus(event.WithDispatcher(dispatcher))))
+		slow := &blockingObserver{release: make(chan struct{})}
+		observer := NewMockObserver("TestObserver")
+		parkingLot.AddObserver(slow)
+		parkingLot.AddObserver(&panickingObserver{})
+		parkingLot.AddObserver(observer)
+
+		ticket, err := parkingLot.Park(car.NewCar("ABC123"))
+		assert.NoError(t, err)
+		_, err = parkingLot.Unpark(ticket)
+		assert.NoError(t, err)
+
+		close(slow.release)
+		dispatcher.Flush()
+		assert.Len(t, observer.notifications, 2)
+		dispatcher.Close()
+	})
+}
+
+func TestParkingLotObserverSubscriptions(t *testing.T) {
+	t.Run("should stop notifying a cancelled observer", func(t *testing.T) {
+		parkingLot := New(2)
+		observer := NewMockObserver("DisplayBoard")
+		subscription := parkingLot.AddObserver(observer)
+
+		_, _ = parkingLot.Park(car.NewCar("ABC123"))
+		subscription.Cancel()
+		_, _ = parkingLot.Park(car.NewCar("XYZ789"))
+
+		assert.Len(t, observer.notifications, 1)
+	})
+
+	t.Run("should stop notifying a removed observer", func(t *testing.T) {
+		parkingLot := New(2)
+		observer := NewMockObserver("DisplayBoard")
+		other := NewMockObserver("Other")
+		parkingLot.AddObserver(observer)
+		parkingLot.AddObserver(other)
+
+		parkingLot.RemoveObserver(observer)
+		parkingLot.RemoveObserver(observer)
+		_, _ = parkingLot.Park(car.NewCar("ABC123"))
+
+		assert.Len(t, observer.notifications, 0)
+		assert.Len(t, other.notifications, 1)
+	})
+
+	t.Run("should notify an observer added twice once", func(t *testing.T) {
+		parkingLot := New(2)
+		observer := NewMockObserver("DisplayBoard")
+
+		first := parkingLot.AddObserver(observer)
+		second := parkingLot.AddObserver(observer)
+		_, _ = parkingLot.Park(car.NewCar("ABC123"))
+
+		assert.Same(t, first, second)
+		assert.Len(t, observer.notifications, 1)
+	})
+
+	t.Run("should notify an observer added again after it was cancelled", func(t *testing.T) {
+		parkingLot := New(2)
+		observer := NewMockObserver("DisplayBoard")
+
+		parkingLot.AddObserver(observer).Cancel()
+		parkingLot.AddObserver(observer)
+		_, _ = parkingLot.Park(car.NewCar("ABC123"))
+
+		assert.Len(t, observer.notifications, 1)
+	})
+
+	t.Run("should only notify the observer of the events passing its filters", func(t *testing.T) {
+		parkingLot := New(2)
+		observer := NewMockObserver("ExitDisplay")
+		parkingLot.AddObserver(observer, event.OfType(event.TypeCarUnparked))
+
+		ticket, _ := parkingLot.Park(car.NewCar("ABC123"))
+		_, _ = parkingLot.Unpark(ticket)
+
+		assert.Len(t, observer.notifications, 1)
+		assert.Equal(t, 2, observer.notifications[0].Available)
+	})
+}
+
+func TestParkingLotLogging(t *testing.T) {
+	t.Run("should log cars parking and leaving with their lot, ticket and plate", func(t *testing.T) {
+		var buf bytes.Buffer
+		parkingLot := New(1, WithLogger(logging.NewTextLogger(&buf, logging.LevelInfo)))
+
+		ticket, _ := parkingLot.Park(car.NewCar("ABC123"))
+		_, _ = parkingLot.Unpark(ticket)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 5)
+		fields := fmt.Sprintf("lot=%s ticket=%s plate=ABC123", parkingLot.GetId(), ticket.TicketNumber)
+		assert.Contains(t, lines[0], `level=INFO msg="car parked" `+fields+" slot=1 available=0")
+		assert.Contains(t, lines[1], `level=WARN msg="parking lot is full" lot=`+parkingLot.GetId())
+		assert.Contains(t, lines[2], `msg="car left" `+fields)
+		assert.Contains(t, lines[3], `msg="fee charged" `+fields)
+		assert.Contains(t, lines[4], `msg="parking lot has space again"`)
+	})
+
+	t.Run("should log nothing by default", func(t *testing.T) {
+		parkingLot := New(1)
+
+		assert.NotPanics(t, func() {
+			_, _ = parkingLot.Park(car.NewCar("ABC123"))
+		})
+	})
+}
@@ -1,16 +1,31 @@
 package parkinglot
 
 import (
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/natanaelrusli/parking-lot/car"
+	"github.com/natanaelrusli/parking-lot/clock"
 	"github.com/natanaelrusli/parking-lot/errors"
 	"github.com/natanaelrusli/parking-lot/fee"
 	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/money"
+	"github.com/natanaelrusli/parking-lot/repository"
+	"github.com/natanaelrusli/parking-lot/ticket"
 	"github.com/stretchr/testify/assert"
 )
 
+func usd(amount int64) money.Money {
+	return money.FromMajor(amount, money.USD)
+}
+
+func newSigner(t *testing.T, key string) *ticket.Signer {
+	signer, err := ticket.NewSigner([]byte(key))
+	assert.NoError(t, err)
+	return signer
+}
+
 func TestParkingLotOperations(t *testing.T) {
 	parkingLot := New(10)
 
@@ -31,20 +46,20 @@ func TestParkingLotOperations(t *testing.T) {
 		car := car.NewCar("XYZ789")
 		ticket, _ := parkingLot.Park(car)
 
-		unparkedCar, err := parkingLot.Unpark(ticket)
+		receipt, err := parkingLot.Unpark(ticket)
 
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 			return
 		}
 
-		if unparkedCar == nil {
+		if receipt == nil || receipt.Car == nil {
 			t.Error("Expected car, got nil")
 			return
 		}
 
-		if unparkedCar.LicensePlate != "XYZ789" {
-			t.Errorf("Expected license plate XYZ789, got %s", unparkedCar.LicensePlate)
+		if receipt.Car.LicensePlate != "XYZ789" {
+			t.Errorf("Expected license plate XYZ789, got %s", receipt.Car.LicensePlate)
 			return
 		}
 	})
@@ -92,13 +107,13 @@ func TestParkingLotOperations(t *testing.T) {
 		}
 
 		car1Retrieved, _ := parkingLot.Unpark(ticket1)
-		if car1Retrieved.LicensePlate != "AAA111" {
-			t.Errorf("Expected AAA111, got %s", car1Retrieved.LicensePlate)
+		if car1Retrieved.Car.LicensePlate != "AAA111" {
+			t.Errorf("Expected AAA111, got %s", car1Retrieved.Car.LicensePlate)
 		}
 
 		car2Retrieved, _ := parkingLot.Unpark(ticket2)
-		if car2Retrieved.LicensePlate != "BBB222" {
-			t.Errorf("Expected BBB222, got %s", car2Retrieved.LicensePlate)
+		if car2Retrieved.Car.LicensePlate != "BBB222" {
+			t.Errorf("Expected BBB222, got %s", car2Retrieved.Car.LicensePlate)
 		}
 	})
 
@@ -137,24 +152,45 @@ func TestParkingLotFeeStrategy(t *testing.T) {
 	t.Run("should have default hourly fee strategy", func(t *testing.T) {
 		parkingLot := New(10)
 
-		fee := parkingLot.CalculateFee(time.Hour * 2)
-		if fee != 20.0 {
-			t.Errorf("Expected fee to be 20.0, got %f", fee)
+		fee := parkingLot.CalculateFee(models.VehicleTypeCar, time.Hour*2)
+		if fee != usd(20) {
+			t.Errorf("Expected fee to be 20.00 USD, got %s", fee)
 		}
 	})
 
 	t.Run("should be able to change fee strategy to flat fee strategy", func(t *testing.T) {
 		pl := New(10)
 
-		f := pl.CalculateFee(time.Hour * 2)
-		if f != 20.0 {
-			t.Errorf("Expected fee to be 20.0, got %f", f)
+		f := pl.CalculateFee(models.VehicleTypeCar, time.Hour*2)
+		if f != usd(20) {
+			t.Errorf("Expected fee to be 20.00 USD, got %s", f)
 		}
 
-		pl.ChangeFeeStrategy(fee.NewFlatFeeStrategy(200))
-		f = pl.CalculateFee(1000)
+		pl.ChangeFeeStrategy(fee.NewFlatFeeStrategy(usd(200)))
+		f = pl.CalculateFee(models.VehicleTypeCar, 1000)
+
+		assert.Equal(t, f, usd(200))
+	})
+
+	t.Run("should bill each vehicle type with its own strategy", func(t *testing.T) {
+		c0 := clock.NewFakeClock(time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC))
+		pl := New(0, WithClock(c0), WithSlotSizes(models.SlotSizeSmall, models.SlotSizeExtraLarge))
+		strategy, err := fee.NewVehicleTypeFeeStrategy(fee.NewHourlyFeeStrategy(usd(10), money.RoundHalfUp), map[models.VehicleType]fee.ParkingFeeStrategy{
+			models.VehicleTypeMotorcycle: fee.NewFlatFeeStrategy(usd(5)),
+			models.VehicleTypeBus:        fee.NewHourlyFeeStrategy(usd(40), money.RoundHalfUp),
+		})
+		assert.NoError(t, err)
+		pl.ChangeFeeStrategy(strategy)
+		moto, _ := pl.Park(car.NewVehicle("MOTO111", models.VehicleTypeMotorcycle))
+		bus, _ := pl.Park(car.NewVehicle("BUS111", models.VehicleTypeBus))
+
+		c0.Advance(2 * time.Hour)
+		motoReceipt, _ := pl.Unpark(moto)
+		busReceipt, _ := pl.Unpark(bus)
 
-		assert.Equal(t, f, float64(200))
+		assert.Equal(t, usd(5), motoReceipt.Fee)
+		assert.Equal(t, usd(80), busReceipt.Fee)
+		assert.Equal(t, usd(20), pl.CalculateFee(models.VehicleTypeVan, 2*time.Hour))
 	})
 }
 
@@ -188,8 +224,620 @@ func TestTicketDuration(t *testing.T) {
 		duration := time.Since(ticket.EntryTime)
 
 		// Assume parking fee is $10 per hour
-		pfee := pl.CalculateFee(duration)
-		assert.Equal(t, int(pfee), 2*10)
+		pfee := pl.CalculateFee(models.VehicleTypeCar, duration)
+		assert.Equal(t, pfee, usd(2*10))
+	})
+
+}
+
+func TestUnparkReceipt(t *testing.T) {
+	t.Run("should bill the stay from the ticket entry time", func(t *testing.T) {
+		c := clock.NewFakeClock(time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC))
+		pl := New(1, WithClock(c))
+		c1 := car.NewCar("B6788PPP")
+		ticket, err := pl.Park(c1)
+		assert.NoError(t, err)
+
+		c.Advance(3 * time.Hour)
+		receipt, err := pl.Unpark(ticket)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "B6788PPP", receipt.Car.LicensePlate)
+		assert.Equal(t, ticket, receipt.Ticket)
+		assert.Equal(t, pl.GetId(), receipt.LotID)
+		assert.Equal(t, ticket.EntryTime, receipt.EntryTime)
+		assert.Equal(t, receipt.ExitTime.Sub(receipt.EntryTime), receipt.Duration)
+		assert.Equal(t, 3, int(receipt.Duration.Hours()))
+		assert.Equal(t, usd(30), receipt.Fee)
+	})
+
+	t.Run("should bill the stay from the entry time the lot issued the ticket with", func(t *testing.T) {
+		entry := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+		c := clock.NewFakeClock(entry)
+		pl := New(1, WithClock(c))
+		ticket, _ := pl.Park(car.NewCar("B6788PPP"))
+		c.Advance(2 * time.Hour)
+
+		for _, entryTime := range []time.Time{{}, entry.Add(-10 * time.Hour), entry.Add(100 * time.Hour)} {
+			presented := *ticket
+			presented.EntryTime = entryTime
+			receipt, err := pl.Unpark(&presented)
+
+			assert.NoError(t, err)
+			assert.Equal(t, entry, receipt.EntryTime)
+			assert.Equal(t, 2*time.Hour, receipt.Duration)
+			assert.Equal(t, usd(20), receipt.Fee)
+
+			ticket, _ = pl.Park(car.NewCar("B6788PPP"))
+			entry = c.Now()
+			c.Advance(2 * time.Hour)
+		}
+	})
+
+	t.Run("should use the lot's current fee strategy", func(t *testing.T) {
+		pl := New(1)
+		flat := fee.NewFlatFeeStrategy(usd(50))
+		pl.ChangeFeeStrategy(flat)
+		ticket, _ := pl.Park(car.NewCar("B6788PPP"))
+
+		receipt, err := pl.Unpark(ticket)
+
+		assert.NoError(t, err)
+		assert.Equal(t, usd(50), receipt.Fee)
+		assert.Equal(t, "FlatFeeStrategy", receipt.FeeStrategy)
+	})
+
+	t.Run("should not return a receipt for an unrecognized ticket", func(t *testing.T) {
+		pl := New(1)
+
+		receipt, err := pl.Unpark(&models.Ticket{TicketNumber: "INVALID"})
+
+		assert.Nil(t, receipt)
+		assert.Equal(t, errors.ErrUnrecognizedTicket, err)
+	})
+}
+
+func TestUnparkLostTicket(t *testing.T) {
+	entry := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+
+	t.Run("should bill a lost ticket for at least a day by default", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		pl := New(1, WithClock(c))
+		strategy, err := fee.NewDailyMaximumStrategy(fee.NewHourlyFeeStrategy(usd(10), money.RoundHalfUp), usd(50))
+		assert.NoError(t, err)
+		pl.ChangeFeeStrategy(strategy)
+		ticket, _ := pl.Park(car.NewCar("AAA111"))
+
+		c.Advance(2 * time.Hour)
+		receipt, err := pl.UnparkLostTicket("AAA111")
+
+		assert.NoError(t, err)
+		assert.True(t, receipt.LostTicket)
+		assert.Equal(t, ticket.TicketNumber, receipt.Ticket.TicketNumber)
+		assert.Equal(t, entry, receipt.EntryTime)
+		assert.Equal(t, 2*time.Hour, receipt.Duration)
+		assert.Equal(t, usd(50), receipt.Fee)
+		assert.Equal(t, "LostTicketStrategy", receipt.FeeStrategy)
+		assert.Equal(t, 0, pl.GetParkedCarCount())
+	})
+
+	t.Run("should bill a lost ticket with the lost ticket fee strategy", func(t *testing.T) {
+		pl := New(1, WithLostTicketFeeStrategy(fee.NewFlatFeeStrategy(usd(30))))
+		_, _ = pl.Park(car.NewCar("AAA111"))
+
+		receipt, err := pl.UnparkLostTicket("AAA111")
+
+		assert.NoError(t, err)
+		assert.Equal(t, usd(30), receipt.Fee)
+	})
+
+	t.Run("should not accept the lost ticket once the car left", func(t *testing.T) {
+		pl := New(1)
+		ticket, _ := pl.Park(car.NewCar("AAA111"))
+		_, _ = pl.UnparkLostTicket("AAA111")
+
+		_, err := pl.Unpark(ticket)
+
+		assert.Equal(t, errors.ErrUnrecognizedTicket, err)
+	})
+
+	t.Run("should return error for unknown or empty license plates", func(t *testing.T) {
+		pl := New(1)
+
+		_, unknown := pl.UnparkLostTicket("AAA111")
+		_, empty := pl.UnparkLostTicket("")
+
+		assert.Equal(t, errors.ErrCarNotFound, unknown)
+		assert.Equal(t, errors.ErrEmptyLicensePlate, empty)
+	})
+}
+
+func TestParkingLotSignedTickets(t *testing.T) {
+	entry := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+	signer := newSigner(t, "secret-key-of-32-bytes-for-tests")
+
+	t.Run("should issue tickets signed with the lot and entry time", func(t *testing.T) {
+		pl := New(2, WithClock(clock.NewFakeClock(entry)), WithTicketSigner(signer))
+
+		first, _ := pl.Park(car.NewCar("AAA111"))
+		second, _ := pl.Park(car.NewCar("BBB222"))
+		claims, err := signer.Verify(first.TicketNumber)
+
+		assert.NoError(t, err)
+		assert.Equal(t, pl.GetId(), claims.LotID)
+		assert.True(t, entry.Equal(claims.EntryTime))
+		assert.NotEqual(t, first.TicketNumber, second.TicketNumber)
+	})
+
+	t.Run("should bill from the signed entry time", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		pl := New(1, WithClock(c), WithTicketSigner(signer))
+		issued, _ := pl.Park(car.NewCar("AAA111"))
+
+		c.Advance(3 * time.Hour)
+		receipt, err := pl.Unpark(&models.Ticket{TicketNumber: issued.TicketNumber})
+
+		assert.NoError(t, err)
+		assert.Equal(t, entry, receipt.EntryTime)
+		assert.Equal(t, usd(30), receipt.Fee)
+	})
+
+	t.Run("should reject forged or altered tickets", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		pl := New(1, WithClock(c), WithTicketSigner(signer))
+		issued, _ := pl.Park(car.NewCar("AAA111"))
+
+		forged := newSigner(t, "guessed-key-of-32-bytes-in-tests").Sign(ticket.Claims{LotID: pl.GetId(), EntryTime: entry, Serial: 1})
+		_, forgedErr := pl.Unpark(&models.Ticket{TicketNumber: forged})
+		_, alteredErr := pl.Unpark(&models.Ticket{TicketNumber: issued.TicketNumber, EntryTime: entry.Add(2 * time.Hour)})
+		_, malformedErr := pl.Unpark(&models.Ticket{TicketNumber: "abcd1234"})
+
+		assert.Equal(t, errors.ErrForgedTicket, forgedErr)
+		assert.Equal(t, errors.ErrForgedTicket, alteredErr)
+		assert.Equal(t, errors.ErrMalformedTicket, malformedErr)
+		assert.Equal(t, 1, pl.GetParkedCarCount())
+	})
+
+	t.Run("should not accept tickets signed for another lot", func(t *testing.T) {
+		lot1 := New(1, WithTicketSigner(signer))
+		lot2 := New(1, WithTicketSigner(signer))
+		issued, _ := lot1.Park(car.NewCar("AAA111"))
+
+		_, err := lot2.Unpark(issued)
+
+		assert.Equal(t, errors.ErrUnrecognizedTicket, err)
+	})
+
+	t.Run("should keep issuing new serials when reopened", func(t *testing.T) {
+		repo := repository.NewInMemoryRepository()
+		c := clock.NewFakeClock(entry)
+		pl, _ := Open(repo, "lot1", 2, WithClock(c), WithTicketSigner(signer))
+		first, _ := pl.Park(car.NewCar("AAA111"))
+
+		reopened, _ := Open(repo, "lot1", 2, WithClock(c), WithTicketSigner(signer))
+		second, err := reopened.Park(car.NewCar("BBB222"))
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, first.TicketNumber, second.TicketNumber)
+	})
+}
+
+func TestParkingLotTicketNumbers(t *testing.T) {
+	t.Run("should skip ticket numbers held by parked cars", func(t *testing.T) {
+		numbers := []string{"dup", "dup", "other"}
+		generator := ticket.GeneratorFunc(func(lotID string) string {
+			number := numbers[0]
+			numbers = numbers[1:]
+			return number
+		})
+		pl := New(2, WithTicketGenerator(generator))
+
+		first, _ := pl.Park(car.NewCar("AAA111"))
+		second, err := pl.Park(car.NewCar("BBB222"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "dup", first.TicketNumber)
+		assert.Equal(t, "other", second.TicketNumber)
+		assert.Equal(t, "AAA111", pl.GetParkedCars(first).LicensePlate)
+	})
+
+	t.Run("should not reissue used ticket numbers", func(t *testing.T) {
+		pl := New(1, WithTicketGenerator(ticket.GeneratorFunc(func(lotID string) string {
+			return "same"
+		})))
+		issued, _ := pl.Park(car.NewCar("AAA111"))
+		_, _ = pl.Unpark(issued)
+
+		_, err := pl.Park(car.NewCar("BBB222"))
+
+		assert.Equal(t, errors.ErrTicketNumbersExhausted, err)
+		assert.Equal(t, 0, pl.GetParkedCarCount())
+	})
+
+	t.Run("should continue sequential numbers after reopening", func(t *testing.T) {
+		repo := repository.NewInMemoryRepository()
+		pl, _ := Open(repo, "lot1", 2, WithTicketGenerator(ticket.NewSequentialGenerator()))
+		first, _ := pl.Park(car.NewCar("AAA111"))
+		// more used tickets than UniqueGenerator would skip
+		for i := 0; i < ticket.MaxGenerateAttempts+1; i++ {
+			issued, err := pl.Park(car.NewCar("BBB222"))
+			assert.NoError(t, err)
+			_, err = pl.Unpark(issued)
+			assert.NoError(t, err)
+		}
+
+		reopened, _ := Open(repo, "lot1", 2, WithTicketGenerator(ticket.NewSequentialGenerator()))
+		second, err := reopened.Park(car.NewCar("CCC333"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "lot1-000001", first.TicketNumber)
+		assert.Equal(t, "lot1-000103", second.TicketNumber)
+	})
+}
+
+func TestParkingLotPrintedTickets(t *testing.T) {
+	entry := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+
+	t.Run("should accept a scanned ticket", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		pl := New(1, WithClock(c))
+		issued, _ := pl.Park(car.NewCar("AAA111"))
+		code, _ := ticket.QRCode(issued)
+
+		c.Advance(2 * time.Hour)
+		scanned, err := ticket.ParsePayload(code.Content())
+		assert.NoError(t, err)
+		receipt, err := pl.Unpark(scanned)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "AAA111", receipt.Car.LicensePlate)
+		assert.Equal(t, usd(20), receipt.Fee)
+	})
+
+	t.Run("should bill a signed ticket scanned without its entry time", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		pl := New(1, WithClock(c), WithTicketSigner(newSigner(t, "secret-key-of-32-bytes-for-tests")))
+		issued, _ := pl.Park(car.NewCar("AAA111"))
+		code, err := ticket.Code128(&models.Ticket{TicketNumber: issued.TicketNumber})
+		assert.NoError(t, err)
+
+		c.Advance(2 * time.Hour)
+		scanned, _ := ticket.ParsePayload(code.Content())
+		receipt, err := pl.Unpark(scanned)
+
+		assert.NoError(t, err)
+		assert.Equal(t, entry, receipt.EntryTime)
+		assert.Equal(t, usd(20), receipt.Fee)
 	})
 
+	t.Run("should bill an unsigned ticket from the stored session whatever the scan says", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		pl := New(2, WithClock(c))
+		issued, _ := pl.Park(car.NewCar("AAA111"))
+		altered := *issued
+		altered.EntryTime = entry.Add(90 * time.Minute)
+		alteredPayload, _ := ticket.Payload(&altered)
+		incomplete, _ := pl.Park(car.NewCar("BBB222"))
+		incompletePayload, _ := ticket.Payload(&models.Ticket{TicketNumber: incomplete.TicketNumber})
+
+		c.Advance(2 * time.Hour)
+		for _, payload := range []string{alteredPayload, incompletePayload} {
+			scanned, err := ticket.ParsePayload(payload)
+			assert.NoError(t, err)
+			receipt, err := pl.Unpark(scanned)
+
+			assert.NoError(t, err)
+			assert.Equal(t, entry, receipt.EntryTime)
+			assert.Equal(t, 2*time.Hour, receipt.Duration)
+			assert.Equal(t, usd(20), receipt.Fee)
+		}
+	})
+}
+
+func TestParkingLotClock(t *testing.T) {
+	entry := time.Date(2024, time.March, 1, 22, 30, 0, 0, time.UTC)
+
+	t.Run("should stamp the ticket with the lot clock", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		pl := New(1, WithClock(c))
+
+		ticket, err := pl.Park(car.NewCar("B6788PPP"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, entry, ticket.EntryTime)
+	})
+
+	t.Run("should bill an overnight stay", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		pl := New(1, WithClock(c))
+		ticket, _ := pl.Park(car.NewCar("B6788PPP"))
+
+		c.Advance(10 * time.Hour)
+		receipt, err := pl.Unpark(ticket)
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, time.March, 2, 8, 30, 0, 0, time.UTC), receipt.ExitTime)
+		assert.Equal(t, 10*time.Hour, receipt.Duration)
+		assert.Equal(t, usd(100), receipt.Fee)
+	})
+
+	t.Run("should bill a multi-day stay", func(t *testing.T) {
+		c := clock.NewFakeClock(entry)
+		pl := New(1, WithClock(c))
+		ticket, _ := pl.Park(car.NewCar("B6788PPP"))
+
+		c.Advance(3*24*time.Hour + 30*time.Minute)
+		receipt, err := pl.Unpark(ticket)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 72*time.Hour+30*time.Minute, receipt.Duration)
+		assert.Equal(t, usd(725), receipt.Fee)
+	})
+}
+
+func TestParkingLotRepository(t *testing.T) {
+	t.Run("should restore parked cars and used tickets when reopened", func(t *testing.T) {
+		repo := repository.NewInMemoryRepository()
+		pl, err := Open(repo, "lot1", 2)
+		assert.NoError(t, err)
+
+		ticket1, _ := pl.Park(car.NewCar("AAA111"))
+		ticket2, _ := pl.Park(car.NewCar("BBB222"))
+		_, _ = pl.Unpark(ticket1)
+
+		reopened, err := Open(repo, "lot1", 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "lot1", reopened.GetId())
+		assert.Equal(t, 1, reopened.GetParkedCarCount())
+		assert.Equal(t, "BBB222", reopened.GetParkedCars(ticket2).LicensePlate)
+		_, err = reopened.Unpark(ticket1)
+		assert.Equal(t, errors.ErrUnrecognizedTicket, err)
+	})
+
+	t.Run("should restore all lots from a file repository", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "parking.log")
+		repo, _ := repository.NewFileRepository(path)
+		lot1, _ := Open(repo, "lot1", 1)
+		_, _ = Open(repo, "lot2", 3)
+		ticket, _ := lot1.Park(car.NewCar("AAA111"))
+		_ = repo.Close()
+
+		repo, _ = repository.NewFileRepository(path)
+		defer repo.Close()
+		lots, err := OpenAll(repo)
+
+		assert.NoError(t, err)
+		assert.Len(t, lots, 2)
+		assert.True(t, lots[0].IsFull())
+		assert.Equal(t, "AAA111", lots[0].GetParkedCars(ticket).LicensePlate)
+		assert.Equal(t, 3, lots[1].GetCapacity())
+
+		receipt, err := lots[0].Unpark(ticket)
+		assert.NoError(t, err)
+		assert.Equal(t, "AAA111", receipt.Car.LicensePlate)
+	})
+
+	t.Run("should bill the cars of a reopened lot without reading the repository", func(t *testing.T) {
+		c := clock.NewFakeClock(time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC))
+		repo := &countingRepository{InMemoryRepository: repository.NewInMemoryRepository()}
+		pl, _ := Open(repo, "lot1", 2, WithClock(c))
+		ticket, _ := pl.Park(car.NewCar("AAA111"))
+		c.Advance(2 * time.Hour)
+
+		reopened, _ := Open(repo, "lot1", 2, WithClock(c))
+		found := repo.found
+		receipt, err := reopened.Unpark(ticket)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ticket.EntryTime, receipt.EntryTime)
+		assert.Equal(t, usd(20), receipt.Fee)
+		assert.Equal(t, found, repo.found)
+	})
+
+	t.Run("should not park a car the repository failed to save", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "parking.log")
+		repo, _ := repository.NewFileRepository(path)
+		pl, _ := Open(repo, "lot1", 1)
+		_ = repo.Close()
+
+		ticket, err := pl.Park(car.NewCar("AAA111"))
+
+		assert.Nil(t, ticket)
+		assert.Error(t, err)
+		assert.Equal(t, 0, pl.GetParkedCarCount())
+	})
+}
+
+func TestParkingLotSlots(t *testing.T) {
+	t.Run("should allocate the nearest free slot and record it on the ticket", func(t *testing.T) {
+		pl := New(3)
+
+		ticket1, _ := pl.Park(car.NewCar("AAA111"))
+		ticket2, _ := pl.Park(car.NewCar("BBB222"))
+
+		assert.Equal(t, 1, ticket1.SlotNumber)
+		assert.Equal(t, 2, ticket2.SlotNumber)
+	})
+
+	t.Run("should reuse a slot once it is freed", func(t *testing.T) {
+		pl := New(3)
+		ticket1, _ := pl.Park(car.NewCar("AAA111"))
+		_, _ = pl.Park(car.NewCar("BBB222"))
+
+		receipt, err := pl.Unpark(ticket1)
+		ticket3, _ := pl.Park(car.NewCar("CCC333"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, receipt.SlotNumber)
+		assert.Equal(t, 1, ticket3.SlotNumber)
+	})
+
+	t.Run("should find the slot for a plate", func(t *testing.T) {
+		pl := New(3)
+		_, _ = pl.Park(car.NewCar("AAA111"))
+		_, _ = pl.Park(car.NewCar("BBB222"))
+
+		slot, err := pl.GetSlotForPlate("BBB222")
+		_, notFoundErr := pl.GetSlotForPlate("CCC333")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, slot)
+		assert.Equal(t, errors.ErrCarNotFound, notFoundErr)
+	})
+
+	t.Run("should list the plates in occupied slots", func(t *testing.T) {
+		pl := New(3)
+		ticket1, _ := pl.Park(car.NewCar("AAA111"))
+		_, _ = pl.Park(car.NewCar("BBB222"))
+		_, _ = pl.Park(car.NewCar("CCC333"))
+		_, _ = pl.Unpark(ticket1)
+
+		plates := pl.GetPlatesInSlots()
+
+		assert.Equal(t, map[int]string{2: "BBB222", 3: "CCC333"}, plates)
+	})
+
+	t.Run("should restore slots when reopened", func(t *testing.T) {
+		repo := repository.NewInMemoryRepository()
+		pl, _ := Open(repo, "lot1", 3)
+		ticket1, _ := pl.Park(car.NewCar("AAA111"))
+		_, _ = pl.Park(car.NewCar("BBB222"))
+		_, _ = pl.Unpark(ticket1)
+
+		reopened, _ := Open(repo, "lot1", 3)
+		ticket3, _ := reopened.Park(car.NewCar("CCC333"))
+
+		assert.Equal(t, map[int]string{1: "CCC333", 2: "BBB222"}, reopened.GetPlatesInSlots())
+		assert.Equal(t, 1, ticket3.SlotNumber)
+	})
+
+	t.Run("should keep every car in a slot when reopened with a smaller capacity", func(t *testing.T) {
+		repo := repository.NewInMemoryRepository()
+		pl, _ := Open(repo, "lot1", 3)
+		_, _ = pl.Park(car.NewCar("AAA111"))
+		_, _ = pl.Park(car.NewCar("BBB222"))
+		_, _ = pl.Park(car.NewCar("CCC333"))
+
+		reopened, _ := Open(repo, "lot1", 2, WithSlotSizes(models.SlotSizeMedium, models.SlotSizeMedium))
+
+		assert.Len(t, reopened.GetPlatesInSlots(), 3)
+		assert.True(t, reopened.IsFull())
+	})
+
+	t.Run("should keep the stored slot layout and permit capacity when reopened without them", func(t *testing.T) {
+		repo := &countingRepository{InMemoryRepository: repository.NewInMemoryRepository()}
+		_, _ = Open(repo, "lot1", 0, WithSlotSizes(models.SlotSizeSmall, models.SlotSizeLarge), WithPermitCapacity(1))
+		repo.saved = 0
+
+		reopened, err := Open(repo, "lot1", 5)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, repo.saved)
+		assert.Equal(t, 2, reopened.GetCapacity())
+		assert.Equal(t, 1, reopened.GetStatus().PermitCapacity)
+		assert.Equal(t, 1, reopened.GetStatus().AvailableByType[models.VehicleTypeVan])
+	})
+
+	t.Run("should replace the stored slot layout and permit capacity when reopened with them", func(t *testing.T) {
+		repo := repository.NewInMemoryRepository()
+		_, _ = Open(repo, "lot1", 3, WithPermitCapacity(1))
+
+		reopened, err := Open(repo, "lot1", 3, WithSlotSizes(models.SlotSizeLarge), WithPermitCapacity(0))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, reopened.GetCapacity())
+		assert.Equal(t, 0, reopened.GetStatus().PermitCapacity)
+	})
+}
+
+func TestParkingLotVehicleTypes(t *testing.T) {
+	t.Run("should park smaller vehicles in bigger slots but not the other way around", func(t *testing.T) {
+		pl := New(0, WithSlotSizes(models.SlotSizeMedium))
+
+		_, err := pl.Park(car.NewVehicle("BUS111", models.VehicleTypeBus))
+		assert.Equal(t, errors.ErrNoCompatibleSlot, err)
+
+		ticket, err := pl.Park(car.NewVehicle("MOTO111", models.VehicleTypeMotorcycle))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, ticket.SlotNumber)
+	})
+
+	t.Run("should prefer the smallest slot that fits", func(t *testing.T) {
+		pl := New(0, WithSlotSizes(models.SlotSizeLarge, models.SlotSizeMedium, models.SlotSizeSmall, models.SlotSizeSmall))
+
+		moto, _ := pl.Park(car.NewVehicle("MOTO111", models.VehicleTypeMotorcycle))
+		regular, _ := pl.Park(car.NewCar("CAR111"))
+		van, _ := pl.Park(car.NewVehicle("VAN111", models.VehicleTypeVan))
+
+		assert.Equal(t, 3, moto.SlotNumber)
+		assert.Equal(t, 2, regular.SlotNumber)
+		assert.Equal(t, 1, van.SlotNumber)
+	})
+
+	t.Run("should reject unknown vehicle types", func(t *testing.T) {
+		pl := New(1)
+
+		_, err := pl.Park(car.NewVehicle("TANK111", "tank"))
+
+		assert.Equal(t, errors.ErrInvalidVehicleType, err)
+	})
+
+	t.Run("should report capacity per vehicle type", func(t *testing.T) {
+		pl := New(0, WithSlotSizes(models.SlotSizeSmall, models.SlotSizeMedium, models.SlotSizeLarge))
+		_, _ = pl.Park(car.NewCar("CAR111"))
+
+		status := pl.GetStatus()
+
+		assert.Equal(t, map[models.VehicleType]int{
+			models.VehicleTypeMotorcycle: 3,
+			models.VehicleTypeCar:        2,
+			models.VehicleTypeVan:        1,
+			models.VehicleTypeBus:        0,
+		}, status.CapacityByType)
+		assert.Equal(t, map[models.VehicleType]int{
+			models.VehicleTypeMotorcycle: 2,
+			models.VehicleTypeCar:        1,
+			models.VehicleTypeVan:        1,
+			models.VehicleTypeBus:        0,
+		}, status.AvailableByType)
+	})
+
+	t.Run("should restore vehicle types and slot sizes when reopened", func(t *testing.T) {
+		repo := repository.NewInMemoryRepository()
+		pl, _ := Open(repo, "lot1", 0, WithSlotSizes(models.SlotSizeSmall, models.SlotSizeLarge))
+		ticket, _ := pl.Park(car.NewVehicle("VAN111", models.VehicleTypeVan))
+
+		lots, _ := OpenAll(repo)
+		reopened := lots[0]
+		receipt, err := reopened.Unpark(ticket)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.VehicleTypeVan, receipt.Car.Type)
+		assert.Equal(t, 2, reopened.GetCapacity())
+		assert.Equal(t, 1, reopened.GetStatus().AvailableByType[models.VehicleTypeVan])
+	})
+}
+
+// countingRepository counts the lots read from and saved to an in-memory
+// repository
+type countingRepository struct {
+	*repository.InMemoryRepository
+	found int
+	saved int
+}
+
+func (r *countingRepository) FindLot(id string) (*repository.LotRecord, error) {
+	r.found++
+	return r.InMemoryRepository.FindLot(id)
+}
+
+func (r *countingRepository) SaveLot(id string, slotSizes []models.SlotSize) error {
+	r.saved++
+	return r.InMemoryRepository.SaveLot(id, slotSizes)
+}
+
+func (r *countingRepository) SavePermitCapacity(lotID string, capacity int) error {
+	r.saved++
+	return r.InMemoryRepository.SavePermitCapacity(lotID, capacity)
 }
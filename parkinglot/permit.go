@@ -0,0 +1,68 @@
+package parkinglot
+
+import (
+	goerrors "errors"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/fee"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/money"
+)
+
+// findPermit returns the permit the car parks with, or nil if the lot
+// takes no permits or the car has no valid one.
+// It must be called with p.mu held.
+func (p *ParkingLot) findPermit(car *models.Car, now time.Time) (*models.Permit, error) {
+	if p.permits == nil {
+		return nil, nil
+	}
+
+	carPermit, err := p.permits.Find(car.LicensePlate, p.ID, now)
+	if goerrors.Is(err, errors.ErrPermitNotFound) {
+		return nil, nil
+	}
+	return carPermit, err
+}
+
+// permitFee bills the part of the stay the permit the car parked with
+// doesn't cover. The permit is checked again at exit: a permit that is
+// still valid covers the whole stay, one that expired during the stay
+// covers it until it ended and one that was revoked covers none of it.
+// It must be called with p.mu held.
+func (p *ParkingLot) permitFee(permitID string, stay fee.Stay, strategy fee.ParkingFeeStrategy) (money.Money, error) {
+	if p.permits == nil {
+		// the lot was opened without its registry, the permit can't be
+		// checked again
+		return money.Zero(strategy.CalculateFee(stay).Currency()), nil
+	}
+
+	carPermit, err := p.permits.Get(permitID)
+	if goerrors.Is(err, errors.ErrPermitNotFound) {
+		return strategy.CalculateFee(stay), nil
+	}
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	if stay.ExitTime.Before(carPermit.ValidUntil) {
+		return money.Zero(strategy.CalculateFee(stay).Currency()), nil
+	}
+
+	uncovered := *stay.Ticket
+	if carPermit.ValidUntil.After(uncovered.EntryTime) {
+		uncovered.EntryTime = carPermit.ValidUntil
+	}
+	return strategy.CalculateFee(fee.NewStay(stay.Car, &uncovered, stay.ExitTime)), nil
+}
+
+// permitSpacesFree returns the number of spaces kept for permit holders
+// that no permit holder is parked in.
+// It must be called with p.mu held.
+func (p *ParkingLot) permitSpacesFree() int {
+	free := p.PermitCapacity - len(p.PermitTickets)
+	if free < 0 {
+		return 0
+	}
+	return free
+}
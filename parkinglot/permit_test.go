@@ -0,0 +1,166 @@
+package parkinglot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/car"
+	"github.com/natanaelrusli/parking-lot/clock"
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/permit"
+	"github.com/natanaelrusli/parking-lot/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParkingLotPermits(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+
+	newPermitLot := func(capacity int, permitCapacity int) (ParkingLotItf, permit.RegistryItf, *clock.FakeClock) {
+		repo := repository.NewInMemoryRepository()
+		registry, _ := permit.NewRegistry(repo)
+		c := clock.NewFakeClock(now)
+		pl, err := Open(repo, "lot1", capacity, WithClock(c), WithPermits(registry), WithPermitCapacity(permitCapacity))
+		assert.NoError(t, err)
+		return pl, registry, c
+	}
+
+	t.Run("should leave with a zero fee receipt for a valid permit", func(t *testing.T) {
+		pl, registry, c := newPermitLot(2, 0)
+		holder, _ := registry.Register("AAA111", []string{"lot1"}, now.AddDate(0, 0, -1), now.AddDate(0, 1, 0))
+
+		ticket, err := pl.Park(car.NewCar("AAA111"))
+		assert.NoError(t, err)
+		assert.Equal(t, holder.ID, ticket.PermitID)
+		assert.Equal(t, 1, pl.GetStatus().Available)
+
+		c.Advance(3 * time.Hour)
+		receipt, err := pl.Unpark(ticket)
+
+		assert.NoError(t, err)
+		assert.True(t, receipt.Fee.IsZero())
+		assert.Equal(t, holder.ID, receipt.PermitID)
+		assert.Equal(t, 3*time.Hour, receipt.Duration)
+	})
+
+	t.Run("should not bill permit holders who lost their ticket", func(t *testing.T) {
+		pl, registry, _ := newPermitLot(1, 0)
+		_, _ = registry.Register("AAA111", nil, now, now.AddDate(0, 1, 0))
+		_, _ = pl.Park(car.NewCar("AAA111"))
+
+		receipt, err := pl.UnparkLostTicket("AAA111")
+
+		assert.NoError(t, err)
+		assert.True(t, receipt.LostTicket)
+		assert.True(t, receipt.Fee.IsZero())
+	})
+
+	t.Run("should bill cars whose permit is expired or for another lot", func(t *testing.T) {
+		pl, registry, c := newPermitLot(2, 0)
+		_, _ = registry.Register("AAA111", nil, now.AddDate(0, -1, 0), now)
+		_, _ = registry.Register("BBB222", []string{"lot2"}, now, now.AddDate(0, 1, 0))
+
+		expired, _ := pl.Park(car.NewCar("AAA111"))
+		otherLot, _ := pl.Park(car.NewCar("BBB222"))
+		c.Advance(time.Hour)
+		expiredReceipt, _ := pl.Unpark(expired)
+		otherLotReceipt, _ := pl.Unpark(otherLot)
+
+		assert.Empty(t, expired.PermitID)
+		assert.Equal(t, usd(10), expiredReceipt.Fee)
+		assert.Equal(t, usd(10), otherLotReceipt.Fee)
+	})
+
+	t.Run("should bill the part of the stay after the permit expired", func(t *testing.T) {
+		pl, registry, c := newPermitLot(1, 0)
+		_, _ = registry.Register("AAA111", nil, now.AddDate(0, 0, -1), now.Add(time.Hour))
+		ticket, _ := pl.Park(car.NewCar("AAA111"))
+		assert.NotEmpty(t, ticket.PermitID)
+
+		c.Advance(3 * time.Hour)
+		receipt, err := pl.Unpark(ticket)
+
+		assert.NoError(t, err)
+		assert.Equal(t, usd(20), receipt.Fee)
+		assert.Equal(t, 3*time.Hour, receipt.Duration)
+		assert.Equal(t, ticket.PermitID, receipt.PermitID)
+	})
+
+	t.Run("should bill the whole stay once the permit is revoked", func(t *testing.T) {
+		pl, registry, c := newPermitLot(1, 0)
+		holder, _ := registry.Register("AAA111", nil, now, now.AddDate(0, 1, 0))
+		ticket, _ := pl.Park(car.NewCar("AAA111"))
+
+		c.Advance(2 * time.Hour)
+		assert.NoError(t, registry.Revoke(holder.ID))
+		receipt, err := pl.Unpark(ticket)
+
+		assert.NoError(t, err)
+		assert.Equal(t, usd(20), receipt.Fee)
+	})
+
+	t.Run("should keep the permit capacity for permit holders", func(t *testing.T) {
+		pl, registry, _ := newPermitLot(3, 2)
+		_, _ = registry.Register("AAA111", nil, now, now.AddDate(0, 1, 0))
+		_, _ = registry.Register("BBB222", nil, now, now.AddDate(0, 1, 0))
+		_, _ = registry.Register("CCC333", nil, now, now.AddDate(0, 1, 0))
+
+		_, walkIn := pl.Park(car.NewCar("WALK01"))
+		_, fullErr := pl.Park(car.NewCar("WALK02"))
+		status := pl.GetStatus()
+
+		assert.NoError(t, walkIn)
+		assert.Equal(t, errors.ErrNoAvailablePosition, fullErr)
+		assert.True(t, status.IsFull)
+		assert.Equal(t, 2, status.PermitCapacity)
+		assert.Equal(t, 0, status.PermitParked)
+
+		_, first := pl.Park(car.NewCar("AAA111"))
+		_, second := pl.Park(car.NewCar("BBB222"))
+		_, third := pl.Park(car.NewCar("CCC333"))
+
+		assert.NoError(t, first)
+		assert.NoError(t, second)
+		assert.Equal(t, errors.ErrNoAvailablePosition, third)
+		assert.Equal(t, 2, pl.GetStatus().PermitParked)
+		assert.Equal(t, 3, pl.GetParkedCarCount())
+	})
+
+	t.Run("should let permit holders park in general spaces once the permit capacity is taken", func(t *testing.T) {
+		pl, registry, _ := newPermitLot(3, 1)
+		_, _ = registry.Register("AAA111", nil, now, now.AddDate(0, 1, 0))
+		_, _ = registry.Register("BBB222", nil, now, now.AddDate(0, 1, 0))
+
+		_, first := pl.Park(car.NewCar("AAA111"))
+		_, second := pl.Park(car.NewCar("BBB222"))
+		status := pl.GetStatus()
+
+		assert.NoError(t, first)
+		assert.NoError(t, second)
+		assert.Equal(t, 1, status.Available)
+		assert.Equal(t, 2, status.PermitParked)
+	})
+
+	t.Run("should reject a permit capacity bigger than the lot", func(t *testing.T) {
+		pl, err := Open(repository.NewInMemoryRepository(), "lot1", 2, WithPermitCapacity(3))
+
+		assert.Nil(t, pl)
+		assert.Equal(t, errors.ErrInvalidPermitCapacity, err)
+	})
+
+	t.Run("should restore permit holders and capacity when reopened", func(t *testing.T) {
+		repo := repository.NewInMemoryRepository()
+		registry, _ := permit.NewRegistry(repo)
+		c := clock.NewFakeClock(now)
+		_, _ = registry.Register("AAA111", nil, now, now.AddDate(0, 1, 0))
+		pl, _ := Open(repo, "lot1", 2, WithClock(c), WithPermits(registry), WithPermitCapacity(1))
+		ticket, _ := pl.Park(car.NewCar("AAA111"))
+
+		lots, err := OpenAll(repo, WithClock(c), WithPermits(registry))
+		assert.NoError(t, err)
+		receipt, unparkErr := lots[0].Unpark(ticket)
+
+		assert.NoError(t, unparkErr)
+		assert.True(t, receipt.Fee.IsZero())
+		assert.Equal(t, 1, lots[0].GetStatus().PermitCapacity)
+	})
+}
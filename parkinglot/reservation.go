@@ -0,0 +1,398 @@
+package parkinglot
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/repository"
+)
+
+// Reserve books a space for the car from start until end. While the
+// reservation is active the space is held: it counts as taken in IsFull
+// and GetStatus and only the car redeeming the reservation can park in it.
+// The car's license plate may be left empty, the reservation is then
+// redeemed with its code using ParkWithReservation.
+//
+// A reservation is only accepted if the lot has a space left for the whole
+// window, counting the other reservations, the cars parked now and the
+// spaces kept for permit holders, and a slot the car fits in while the
+// vehicles of the other reservations get theirs.
+// Reservations that are not redeemed before they end expire.
+func (p *ParkingLot) Reserve(car *models.Car, start time.Time, end time.Time) (*models.Reservation, error) {
+	reservation, err := p.reserve(car, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	p.publishStatus()
+
+	return reservation, nil
+}
+
+func (p *ParkingLot) reserve(car *models.Car, start time.Time, end time.Time) (*models.Reservation, error) {
+	if car == nil {
+		return nil, errors.ErrNilCar
+	}
+
+	vehicleType := car.GetVehicleType()
+	if !vehicleType.IsValid() {
+		return nil, errors.ErrInvalidVehicleType
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	if !end.After(start) || !end.After(now) {
+		return nil, errors.ErrInvalidReservationWindow
+	}
+
+	if err := p.expireReservations(now); err != nil {
+		return nil, err
+	}
+
+	if !p.fitsAnySlot(vehicleType) {
+		return nil, errors.ErrNoCompatibleSlot
+	}
+
+	if p.booked(start, end, now) >= p.Capacity || !p.fitsWindow(vehicleType, start, end, now) {
+		return nil, errors.ErrFullyBooked
+	}
+
+	reservation := models.Reservation{
+		Code:         uuid.New().String()[:8],
+		LotID:        p.ID,
+		LicensePlate: car.LicensePlate,
+		VehicleType:  vehicleType,
+		Start:        start,
+		End:          end,
+	}
+
+	if err := p.repo.SaveReservation(p.ID, reservation); err != nil {
+		return nil, err
+	}
+
+	p.Reservations[reservation.Code] = reservation
+	p.scheduleReservations(now)
+
+	return &reservation, nil
+}
+
+// CancelReservation cancels the reservation and releases the space it holds
+func (p *ParkingLot) CancelReservation(code string) error {
+	if err := p.cancelReservation(code); err != nil {
+		return err
+	}
+
+	p.publishStatus()
+
+	return nil
+}
+
+func (p *ParkingLot) cancelReservation(code string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exists := p.Reservations[code]; !exists {
+		return errors.ErrReservationNotFound
+	}
+
+	if err := p.repo.DeleteReservation(p.ID, code); err != nil {
+		return err
+	}
+
+	delete(p.Reservations, code)
+	return nil
+}
+
+// ExpireReservations removes the reservations that ended without being
+// redeemed and returns them. Expired reservations are also removed
+// whenever a car parks or a space is reserved.
+//
+// The lot publishes its status when reservations expired, or when it
+// filled up or freed a space since its last events because reservations
+// started or ended. The lot calls it on its own, from its clock's timers,
+// whenever a reservation starts or ends.
+func (p *ParkingLot) ExpireReservations() []models.Reservation {
+	p.mu.Lock()
+	now := p.clock.Now()
+	expired := p.expiredReservations(now)
+	for _, reservation := range expired {
+		// a reservation the repository failed to delete is retried next time
+		if err := p.repo.DeleteReservation(p.ID, reservation.Code); err == nil {
+			delete(p.Reservations, reservation.Code)
+		}
+	}
+	changed := p.status().IsFull != p.full
+	p.scheduleReservations(now)
+	p.mu.Unlock()
+
+	if len(expired) > 0 || changed {
+		p.publishStatus()
+	}
+
+	return expired
+}
+
+// GetReservations returns the lot's reservations ordered by start time
+func (p *ParkingLot) GetReservations() []models.Reservation {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	reservations := make([]models.Reservation, 0, len(p.Reservations))
+	for _, reservation := range p.Reservations {
+		reservations = append(reservations, reservation)
+	}
+	sortReservations(reservations)
+	return reservations
+}
+
+// ParkWithReservation parks the car in the space held by the reservation
+// with the given code. The reservation can be redeemed before it starts
+// if the lot has a free space.
+func (p *ParkingLot) ParkWithReservation(car *models.Car, code string) (*models.Ticket, error) {
+	if code == "" {
+		return nil, errors.ErrReservationNotFound
+	}
+
+	ticket, events, err := p.park(car, code)
+	if err != nil {
+		return nil, err
+	}
+
+	p.publish(events...)
+
+	return ticket, nil
+}
+
+// scheduleReservations sets the lot's timer to call ExpireReservations
+// the next time a reservation starts or ends, so the lot publishes its
+// status when that fills it up or frees a space.
+// It must be called with p.mu held.
+func (p *ParkingLot) scheduleReservations(now time.Time) {
+	var next time.Time
+	for _, reservation := range p.Reservations {
+		at := reservation.Start
+		if !at.After(now) {
+			at = reservation.End
+		}
+		if at.After(now) && (next.IsZero() || at.Before(next)) {
+			next = at
+		}
+	}
+
+	if p.reservationTimer != nil {
+		p.reservationTimer.Stop()
+		p.reservationTimer = nil
+	}
+	if next.IsZero() {
+		return
+	}
+	p.reservationTimer = p.clock.AfterFunc(next.Sub(now), func() {
+		p.ExpireReservations()
+	})
+}
+
+// findReservation returns the reservation the car redeems when parking: the
+// one with the given code, which may not have started yet, or when code is
+// empty the earliest reservation for its license plate active now, if any.
+// It must be called with p.mu held.
+func (p *ParkingLot) findReservation(car *models.Car, code string, now time.Time) (*models.Reservation, error) {
+	if code != "" {
+		reservation, exists := p.Reservations[code]
+		if !exists || !now.Before(reservation.End) {
+			return nil, errors.ErrReservationNotFound
+		}
+		return &reservation, nil
+	}
+
+	var found *models.Reservation
+	for _, reservation := range p.Reservations {
+		if reservation.LicensePlate != car.LicensePlate || !reservation.IsActive(now) {
+			continue
+		}
+		if found == nil || reservation.Start.Before(found.Start) {
+			r := reservation
+			found = &r
+		}
+	}
+	return found, nil
+}
+
+// redeemReservation removes a reservation a car has parked with. The
+// session is already saved with the reservation code, so if deleting the
+// reservation fails it is dropped when the lot is restored instead.
+// It must be called with p.mu held.
+func (p *ParkingLot) redeemReservation(code string) {
+	_ = p.repo.DeleteReservation(p.ID, code)
+	delete(p.Reservations, code)
+}
+
+// expireReservations removes the reservations that ended by now.
+// It must be called with p.mu held.
+func (p *ParkingLot) expireReservations(now time.Time) error {
+	for _, reservation := range p.expiredReservations(now) {
+		if err := p.repo.DeleteReservation(p.ID, reservation.Code); err != nil {
+			return err
+		}
+		delete(p.Reservations, reservation.Code)
+	}
+	return nil
+}
+
+// expiredReservations must be called with p.mu held.
+func (p *ParkingLot) expiredReservations(now time.Time) []models.Reservation {
+	var expired []models.Reservation
+	for _, reservation := range p.Reservations {
+		if !now.Before(reservation.End) {
+			expired = append(expired, reservation)
+		}
+	}
+	sortReservations(expired)
+	return expired
+}
+
+// held returns the number of spaces held by active reservations at t, not
+// counting the reservation with the except code.
+// It must be called with p.mu held.
+func (p *ParkingLot) held(t time.Time, except string) int {
+	count := 0
+	for code, reservation := range p.Reservations {
+		if code != except && reservation.IsActive(t) {
+			count++
+		}
+	}
+	return count
+}
+
+// occupied returns the number of spaces taken by parked cars, held for
+// reservations now or kept for permit holders.
+// It must be called with p.mu held.
+func (p *ParkingLot) occupied(now time.Time, except string) int {
+	return len(p.ParkedCars) + p.held(now, except) + p.permitSpacesFree()
+}
+
+// booked returns the most spaces taken at any time between start and end.
+// Cars parked now are counted from now on, as they may stay for the whole
+// window.
+// It must be called with p.mu held.
+func (p *ParkingLot) booked(start time.Time, end time.Time, now time.Time) int {
+	most := 0
+	for _, t := range p.instants(start, end, now) {
+		if count := p.held(t, ""); count > most {
+			most = count
+		}
+	}
+	return len(p.ParkedCars) + p.permitSpacesFree() + most
+}
+
+// fitsWindow reports whether a vehicle of the given type can be given a
+// free slot at any time between start and end, alongside the vehicles of
+// the reservations active then. Cars parked now keep their slots for the
+// whole window.
+// It must be called with p.mu held.
+func (p *ParkingLot) fitsWindow(vehicleType models.VehicleType, start time.Time, end time.Time, now time.Time) bool {
+	for _, t := range p.instants(start, end, now) {
+		if !p.fitsFreeSlots(append(p.heldTypes(t, ""), vehicleType)) {
+			return false
+		}
+	}
+	return true
+}
+
+// instants returns the times between start and end at which the spaces
+// held are the most. They only go up when a reservation starts, so they
+// are the start of the window, from now on, and every reservation start
+// within it.
+// It must be called with p.mu held.
+func (p *ParkingLot) instants(start time.Time, end time.Time, now time.Time) []time.Time {
+	if start.Before(now) {
+		start = now
+	}
+
+	instants := []time.Time{start}
+	for _, reservation := range p.Reservations {
+		if reservation.Start.After(start) && reservation.Start.Before(end) {
+			instants = append(instants, reservation.Start)
+		}
+	}
+	return instants
+}
+
+// heldTypes returns the vehicle types of the reservations active at t, not
+// counting the reservation with the except code.
+// It must be called with p.mu held.
+func (p *ParkingLot) heldTypes(t time.Time, except string) []models.VehicleType {
+	var vehicleTypes []models.VehicleType
+	for code, reservation := range p.Reservations {
+		if code != except && reservation.IsActive(t) {
+			vehicleTypes = append(vehicleTypes, reservation.VehicleType)
+		}
+	}
+	return vehicleTypes
+}
+
+// fitsFreeSlots reports whether every vehicle can be given a free slot at
+// once. A vehicle fits every slot at least as big as the one it needs, so
+// that is when, for every size, there are as many free slots of that size
+// or bigger as vehicles needing one.
+// It must be called with p.mu held.
+func (p *ParkingLot) fitsFreeSlots(vehicleTypes []models.VehicleType) bool {
+	for _, threshold := range models.VehicleTypes {
+		size := threshold.RequiredSlotSize()
+
+		needed := 0
+		for _, vehicleType := range vehicleTypes {
+			if !vehicleType.RequiredSlotSize().Smaller(size) {
+				needed++
+			}
+		}
+
+		free := 0
+		for _, slot := range p.Slots {
+			if slot.IsFree() && !slot.Size.Smaller(size) {
+				free++
+			}
+		}
+
+		if needed > free {
+			return false
+		}
+	}
+	return true
+}
+
+// fitsAnySlot must be called with p.mu held.
+func (p *ParkingLot) fitsAnySlot(vehicleType models.VehicleType) bool {
+	for _, slot := range p.Slots {
+		if slot.Size.Fits(vehicleType) {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreReservations loads the stored reservations, dropping those a
+// parked car has already redeemed
+func restoreReservations(record *repository.LotRecord) map[string]models.Reservation {
+	reservations := make(map[string]models.Reservation, len(record.Reservations))
+	for code, reservation := range record.Reservations {
+		reservations[code] = reservation
+	}
+	for _, session := range record.Sessions {
+		delete(reservations, session.Ticket.ReservationCode)
+	}
+	return reservations
+}
+
+func sortReservations(reservations []models.Reservation) {
+	sort.Slice(reservations, func(i, j int) bool {
+		if !reservations[i].Start.Equal(reservations[j].Start) {
+			return reservations[i].Start.Before(reservations[j].Start)
+		}
+		return reservations[i].Code < reservations[j].Code
+	})
+}
@@ -0,0 +1,259 @@
+package parkinglot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/car"
+	"github.com/natanaelrusli/parking-lot/clock"
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParkingLotReservations(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+
+	t.Run("should hold a space while the reservation is active", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(2, WithClock(c))
+
+		reservation, err := pl.Reserve(car.NewCar("AAA111"), now.Add(time.Hour), now.Add(3*time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, pl.GetStatus().Available)
+
+		c.Advance(time.Hour)
+		status := pl.GetStatus()
+
+		assert.Equal(t, "AAA111", reservation.LicensePlate)
+		assert.Equal(t, 1, status.Available)
+		assert.Equal(t, 1, status.Reserved)
+		assert.Equal(t, 1, status.AvailableByType[models.VehicleTypeCar])
+		assert.False(t, status.IsFull)
+	})
+
+	t.Run("should not let walk-ins take a held space", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(2, WithClock(c))
+		_, _ = pl.Reserve(car.NewCar("AAA111"), now, now.Add(time.Hour))
+
+		_, err := pl.Park(car.NewCar("BBB222"))
+		assert.NoError(t, err)
+		ticket, err := pl.Park(car.NewCar("CCC333"))
+
+		assert.Nil(t, ticket)
+		assert.Equal(t, errors.ErrNoAvailablePosition, err)
+		assert.True(t, pl.IsFull())
+	})
+
+	t.Run("should redeem the reservation by license plate", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(1, WithClock(c))
+		reservation, _ := pl.Reserve(car.NewCar("AAA111"), now, now.Add(time.Hour))
+
+		ticket, err := pl.Park(car.NewCar("AAA111"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, reservation.Code, ticket.ReservationCode)
+		assert.Empty(t, pl.GetReservations())
+		assert.Equal(t, 0, pl.GetStatus().Reserved)
+		assert.True(t, pl.IsFull())
+	})
+
+	t.Run("should not redeem a reservation that hasn't started by license plate", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(1, WithClock(c))
+		reservation, _ := pl.Reserve(car.NewCar("AAA111"), now.Add(7*24*time.Hour), now.Add(7*24*time.Hour+time.Hour))
+
+		ticket, err := pl.Park(car.NewCar("AAA111"))
+
+		assert.NoError(t, err)
+		assert.Empty(t, ticket.ReservationCode)
+		assert.Equal(t, []models.Reservation{*reservation}, pl.GetReservations())
+	})
+
+	t.Run("should redeem a reservation that hasn't started by code", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(1, WithClock(c))
+		reservation, _ := pl.Reserve(car.NewCar("AAA111"), now.Add(time.Hour), now.Add(2*time.Hour))
+
+		ticket, err := pl.ParkWithReservation(car.NewCar("AAA111"), reservation.Code)
+
+		assert.NoError(t, err)
+		assert.Equal(t, reservation.Code, ticket.ReservationCode)
+		assert.Empty(t, pl.GetReservations())
+	})
+
+	t.Run("should redeem the reservation by code", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(1, WithClock(c))
+		reservation, _ := pl.Reserve(&models.Car{}, now, now.Add(time.Hour))
+
+		_, walkInErr := pl.Park(car.NewCar("BBB222"))
+		ticket, err := pl.ParkWithReservation(car.NewCar("AAA111"), reservation.Code)
+
+		assert.Equal(t, errors.ErrNoAvailablePosition, walkInErr)
+		assert.NoError(t, err)
+		assert.Equal(t, reservation.Code, ticket.ReservationCode)
+		assert.Equal(t, "AAA111", pl.GetParkedCars(ticket).LicensePlate)
+	})
+
+	t.Run("should return error for an unknown reservation code", func(t *testing.T) {
+		pl := New(1)
+
+		ticket, err := pl.ParkWithReservation(car.NewCar("AAA111"), "unknown")
+
+		assert.Nil(t, ticket)
+		assert.Equal(t, errors.ErrReservationNotFound, err)
+	})
+
+	t.Run("should release the space when the reservation is cancelled", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(1, WithClock(c))
+		reservation, _ := pl.Reserve(car.NewCar("AAA111"), now, now.Add(time.Hour))
+
+		err := pl.CancelReservation(reservation.Code)
+		_, parkErr := pl.Park(car.NewCar("BBB222"))
+
+		assert.NoError(t, err)
+		assert.NoError(t, parkErr)
+		assert.Equal(t, errors.ErrReservationNotFound, pl.CancelReservation(reservation.Code))
+	})
+
+	t.Run("should expire reservations that were not redeemed once they end", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(1, WithClock(c))
+		reservation, _ := pl.Reserve(car.NewCar("AAA111"), now, now.Add(time.Hour))
+		pending, _ := pl.Reserve(car.NewCar("BBB222"), now.Add(time.Hour), now.Add(2*time.Hour))
+
+		c.Advance(time.Hour)
+
+		assert.Equal(t, []models.Reservation{*pending}, pl.GetReservations())
+		assert.Empty(t, pl.ExpireReservations())
+		_, err := pl.ParkWithReservation(car.NewCar("AAA111"), reservation.Code)
+		assert.Equal(t, errors.ErrReservationNotFound, err)
+	})
+
+	t.Run("should expire the reservations that ended while the lot was closed", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		repo := repository.NewInMemoryRepository()
+		pl, _ := Open(repo, "lot1", 1, WithClock(c))
+		reservation, _ := pl.Reserve(car.NewCar("AAA111"), now, now.Add(time.Hour))
+
+		reopened, _ := Open(repo, "lot1", 1, WithClock(clock.NewFakeClock(now.Add(time.Hour))))
+		expired := reopened.ExpireReservations()
+
+		assert.Equal(t, []models.Reservation{*reservation}, expired)
+		assert.Empty(t, reopened.GetReservations())
+	})
+
+	t.Run("should publish the status when reservations starting and ending fill and free the lot", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(1, WithClock(c))
+		_, _ = pl.Reserve(car.NewCar("AAA111"), now.Add(time.Hour), now.Add(2*time.Hour))
+		observer := NewMockObserver("TestObserver")
+		pl.AddObserver(observer)
+
+		c.Advance(30 * time.Minute)
+		assert.Empty(t, observer.notifications)
+		c.Advance(30 * time.Minute)
+		assert.Len(t, observer.notifications, 1)
+		assert.True(t, observer.notifications[0].IsFull)
+		c.Advance(time.Hour)
+
+		assert.Len(t, observer.notifications, 2)
+		assert.False(t, observer.notifications[1].IsFull)
+		assert.Empty(t, pl.GetReservations())
+	})
+
+	t.Run("should not overbook a time window", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(2, WithClock(c))
+		_, _ = pl.Park(car.NewCar("AAA111"))
+		_, _ = pl.Reserve(car.NewCar("BBB222"), now.Add(2*time.Hour), now.Add(4*time.Hour))
+
+		_, overlapErr := pl.Reserve(car.NewCar("CCC333"), now.Add(time.Hour), now.Add(3*time.Hour))
+		_, beforeErr := pl.Reserve(car.NewCar("CCC333"), now.Add(time.Hour), now.Add(2*time.Hour))
+
+		assert.Equal(t, errors.ErrFullyBooked, overlapErr)
+		assert.NoError(t, beforeErr)
+	})
+
+	t.Run("should reject invalid reservation windows", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(1, WithClock(c))
+
+		_, backwards := pl.Reserve(car.NewCar("AAA111"), now.Add(2*time.Hour), now.Add(time.Hour))
+		_, past := pl.Reserve(car.NewCar("AAA111"), now.Add(-2*time.Hour), now.Add(-time.Hour))
+		_, nilCar := pl.Reserve(nil, now, now.Add(time.Hour))
+
+		assert.Equal(t, errors.ErrInvalidReservationWindow, backwards)
+		assert.Equal(t, errors.ErrInvalidReservationWindow, past)
+		assert.Equal(t, errors.ErrNilCar, nilCar)
+	})
+
+	t.Run("should reject vehicles no slot fits", func(t *testing.T) {
+		pl := New(0, WithSlotSizes(models.SlotSizeMedium))
+
+		_, err := pl.Reserve(car.NewVehicle("BUS001", models.VehicleTypeBus), time.Now(), time.Now().Add(time.Hour))
+
+		assert.Equal(t, errors.ErrNoCompatibleSlot, err)
+	})
+
+	t.Run("should keep a slot the reserved vehicle fits in", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(0, WithClock(c), WithSlotSizes(models.SlotSizeMedium, models.SlotSizeExtraLarge))
+		_, err := pl.Reserve(car.NewVehicle("BUS001", models.VehicleTypeBus), now, now.Add(time.Hour))
+		assert.NoError(t, err)
+
+		_, vanErr := pl.Park(car.NewVehicle("VAN001", models.VehicleTypeVan))
+		carTicket, carErr := pl.Park(car.NewCar("CAR001"))
+		busTicket, busErr := pl.Park(car.NewVehicle("BUS001", models.VehicleTypeBus))
+
+		assert.Equal(t, errors.ErrNoCompatibleSlot, vanErr)
+		assert.NoError(t, carErr)
+		assert.Equal(t, 1, carTicket.SlotNumber)
+		assert.NoError(t, busErr)
+		assert.Equal(t, 2, busTicket.SlotNumber)
+	})
+
+	t.Run("should not show the slot a reserved vehicle needs as available", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(0, WithClock(c), WithSlotSizes(models.SlotSizeMedium, models.SlotSizeExtraLarge))
+		_, _ = pl.Reserve(car.NewVehicle("BUS001", models.VehicleTypeBus), now, now.Add(time.Hour))
+
+		status := pl.GetStatus()
+
+		assert.Equal(t, 1, status.AvailableByType[models.VehicleTypeCar])
+		assert.Equal(t, 0, status.AvailableByType[models.VehicleTypeVan])
+	})
+
+	t.Run("should not reserve when the slots the vehicle fits in are taken", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		pl := New(0, WithClock(c), WithSlotSizes(models.SlotSizeMedium, models.SlotSizeExtraLarge))
+		_, _ = pl.Park(car.NewVehicle("VAN001", models.VehicleTypeVan))
+
+		_, busErr := pl.Reserve(car.NewVehicle("BUS001", models.VehicleTypeBus), now, now.Add(time.Hour))
+		_, carErr := pl.Reserve(car.NewCar("CAR001"), now, now.Add(time.Hour))
+
+		assert.Equal(t, errors.ErrFullyBooked, busErr)
+		assert.NoError(t, carErr)
+	})
+
+	t.Run("should restore reservations when reopened", func(t *testing.T) {
+		c := clock.NewFakeClock(now)
+		repo := repository.NewInMemoryRepository()
+		pl, _ := Open(repo, "lot1", 2, WithClock(c))
+		redeemed, _ := pl.Reserve(car.NewCar("AAA111"), now, now.Add(time.Hour))
+		pending, _ := pl.Reserve(car.NewCar("BBB222"), now, now.Add(time.Hour))
+		_, _ = pl.Park(car.NewCar("AAA111"))
+
+		reopened, err := Open(repo, "lot1", 2, WithClock(c))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Reservation{*pending}, reopened.GetReservations())
+		assert.NotEqual(t, redeemed.Code, pending.Code)
+		assert.True(t, reopened.IsFull())
+	})
+}
@@ -1,11 +1,29 @@
 package parking_styles
 
-import "github.com/natanaelrusli/parking-lot/parkinglot"
+import (
+	"reflect"
+
+	"github.com/natanaelrusli/parking-lot/parkinglot"
+)
 
 type ParkingStyleStrategy interface {
 	GetLot(parkingLots []*parkinglot.ParkingLot) *parkinglot.ParkingLot
 }
 
+// Name returns the type name of the strategy, e.g. "MostCapacityStrategy",
+// or an empty string when there is none
+func Name(strategy ParkingStyleStrategy) string {
+	if strategy == nil {
+		return ""
+	}
+
+	t := reflect.TypeOf(strategy)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
 type MostCapacityStrategy struct{}
 
 type MostFreeSpaceStrategy struct{}
@@ -23,8 +41,8 @@ func (s *MostCapacityStrategy) GetLot(parkingLots []*parkinglot.ParkingLot) *par
 	lotWithHighestCap := &parkinglot.ParkingLot{}
 
 	for _, v := range parkingLots {
-		if v.Capacity > highestCap {
-			highestCap = v.Capacity
+		if v.GetCapacity() > highestCap {
+			highestCap = v.GetCapacity()
 			lotWithHighestCap = v
 		}
 	}
@@ -36,7 +54,7 @@ func (s *MostFreeSpaceStrategy) GetLot(parkingLots []*parkinglot.ParkingLot) *pa
 	lotWithHighestFree := &parkinglot.ParkingLot{}
 
 	for _, v := range parkingLots {
-		freeSpace := v.Capacity - len(v.ParkedCars)
+		freeSpace := v.GetCapacity() - v.GetParkedCarCount()
 
 		if freeSpace > highestFree {
 			highestFree = freeSpace
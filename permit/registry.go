@@ -0,0 +1,139 @@
+package permit
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/natanaelrusli/parking-lot/repository"
+)
+
+// RegistryItf keeps track of the permits sold to subscribers, e.g. monthly
+// passes, so lots can let their holders park for free
+type RegistryItf interface {
+	// Register issues a permit for the license plate, valid from validFrom
+	// until validUntil in the given lots, or in every lot when none is given
+	Register(licensePlate string, lotIDs []string, validFrom time.Time, validUntil time.Time) (*models.Permit, error)
+	Revoke(id string) error
+	// Get returns the permit with the ID, or ErrPermitNotFound if there is
+	// none, e.g. it was revoked
+	Get(id string) (*models.Permit, error)
+	// Find returns the permit letting the car park in the lot at t, or
+	// ErrPermitNotFound if it has none
+	Find(licensePlate string, lotID string, t time.Time) (*models.Permit, error)
+	// GetPermits returns every permit ordered by ID
+	GetPermits() []models.Permit
+}
+
+// Registry is safe for concurrent use. Permits are saved to the repository
+// before they are applied in memory, like lot state.
+type Registry struct {
+	mu      sync.RWMutex
+	repo    repository.PermitRepository
+	permits map[string]models.Permit
+}
+
+// NewRegistry creates a registry holding the permits already stored in repo
+func NewRegistry(repo repository.PermitRepository) (RegistryItf, error) {
+	stored, err := repo.FindAllPermits()
+	if err != nil {
+		return nil, err
+	}
+
+	permits := make(map[string]models.Permit, len(stored))
+	for _, permit := range stored {
+		permits[permit.ID] = permit
+	}
+
+	return &Registry{repo: repo, permits: permits}, nil
+}
+
+func (r *Registry) Register(licensePlate string, lotIDs []string, validFrom time.Time, validUntil time.Time) (*models.Permit, error) {
+	if licensePlate == "" || !validUntil.After(validFrom) {
+		return nil, errors.ErrInvalidPermit
+	}
+
+	permit := models.Permit{
+		ID:           uuid.New().String()[:8],
+		LicensePlate: licensePlate,
+		LotIDs:       append([]string(nil), lotIDs...),
+		ValidFrom:    validFrom,
+		ValidUntil:   validUntil,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.repo.SavePermit(permit); err != nil {
+		return nil, err
+	}
+
+	r.permits[permit.ID] = permit
+	return &permit, nil
+}
+
+func (r *Registry) Revoke(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.permits[id]; !exists {
+		return errors.ErrPermitNotFound
+	}
+
+	if err := r.repo.DeletePermit(id); err != nil {
+		return err
+	}
+
+	delete(r.permits, id)
+	return nil
+}
+
+func (r *Registry) Get(id string) (*models.Permit, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	permit, exists := r.permits[id]
+	if !exists {
+		return nil, errors.ErrPermitNotFound
+	}
+	return &permit, nil
+}
+
+// Find prefers the permit valid the longest when the car has several
+func (r *Registry) Find(licensePlate string, lotID string, t time.Time) (*models.Permit, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var found *models.Permit
+	for _, permit := range r.permits {
+		if permit.LicensePlate != licensePlate || !permit.Covers(lotID) || !permit.IsValid(t) {
+			continue
+		}
+		if found == nil || permit.ValidUntil.After(found.ValidUntil) {
+			p := permit
+			found = &p
+		}
+	}
+
+	if found == nil {
+		return nil, errors.ErrPermitNotFound
+	}
+	return found, nil
+}
+
+func (r *Registry) GetPermits() []models.Permit {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	permits := make([]models.Permit, 0, len(r.permits))
+	for _, permit := range r.permits {
+		permits = append(permits, permit)
+	}
+	sort.Slice(permits, func(i, j int) bool {
+		return permits[i].ID < permits[j].ID
+	})
+	return permits
+}
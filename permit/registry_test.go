@@ -0,0 +1,100 @@
+package permit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	until := from.AddDate(0, 1, 0)
+
+	t.Run("should find a valid permit for the lots it covers", func(t *testing.T) {
+		registry, _ := NewRegistry(repository.NewInMemoryRepository())
+		permit, err := registry.Register("AAA111", []string{"lot1"}, from, until)
+		assert.NoError(t, err)
+
+		found, err := registry.Find("AAA111", "lot1", from.Add(time.Hour))
+
+		assert.NoError(t, err)
+		assert.Equal(t, permit, found)
+	})
+
+	t.Run("should not find permits for other lots, plates or times", func(t *testing.T) {
+		registry, _ := NewRegistry(repository.NewInMemoryRepository())
+		_, _ = registry.Register("AAA111", []string{"lot1"}, from, until)
+
+		_, otherLot := registry.Find("AAA111", "lot2", from)
+		_, otherPlate := registry.Find("BBB222", "lot1", from)
+		_, before := registry.Find("AAA111", "lot1", from.Add(-time.Second))
+		_, expired := registry.Find("AAA111", "lot1", until)
+
+		assert.Equal(t, errors.ErrPermitNotFound, otherLot)
+		assert.Equal(t, errors.ErrPermitNotFound, otherPlate)
+		assert.Equal(t, errors.ErrPermitNotFound, before)
+		assert.Equal(t, errors.ErrPermitNotFound, expired)
+	})
+
+	t.Run("should cover every lot when no lot is given", func(t *testing.T) {
+		registry, _ := NewRegistry(repository.NewInMemoryRepository())
+		_, _ = registry.Register("AAA111", nil, from, until)
+
+		_, err := registry.Find("AAA111", "any", from)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject invalid permits", func(t *testing.T) {
+		registry, _ := NewRegistry(repository.NewInMemoryRepository())
+
+		_, noPlate := registry.Register("", nil, from, until)
+		_, backwards := registry.Register("AAA111", nil, until, from)
+
+		assert.Equal(t, errors.ErrInvalidPermit, noPlate)
+		assert.Equal(t, errors.ErrInvalidPermit, backwards)
+		assert.Empty(t, registry.GetPermits())
+	})
+
+	t.Run("should revoke permits", func(t *testing.T) {
+		registry, _ := NewRegistry(repository.NewInMemoryRepository())
+		permit, _ := registry.Register("AAA111", nil, from, until)
+
+		found, getErr := registry.Get(permit.ID)
+		err := registry.Revoke(permit.ID)
+		_, findErr := registry.Find("AAA111", "lot1", from)
+		_, revokedErr := registry.Get(permit.ID)
+
+		assert.NoError(t, getErr)
+		assert.Equal(t, permit, found)
+		assert.NoError(t, err)
+		assert.Equal(t, errors.ErrPermitNotFound, findErr)
+		assert.Equal(t, errors.ErrPermitNotFound, revokedErr)
+		assert.Equal(t, errors.ErrPermitNotFound, registry.Revoke(permit.ID))
+	})
+
+	t.Run("should restore permits from the repository", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "parking.log")
+		repo, _ := repository.NewFileRepository(path)
+		registry, _ := NewRegistry(repo)
+		kept, _ := registry.Register("AAA111", []string{"lot1"}, from, until)
+		revoked, _ := registry.Register("BBB222", nil, from, until)
+		_ = registry.Revoke(revoked.ID)
+		_ = repo.Close()
+
+		repo, _ = repository.NewFileRepository(path)
+		defer repo.Close()
+		restored, err := NewRegistry(repo)
+
+		assert.NoError(t, err)
+		permits := restored.GetPermits()
+		assert.Len(t, permits, 1)
+		assert.Equal(t, kept.ID, permits[0].ID)
+		assert.Equal(t, []string{"lot1"}, permits[0].LotIDs)
+		assert.True(t, kept.ValidUntil.Equal(permits[0].ValidUntil))
+	})
+}
@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+)
+
+const (
+	opSaveLot            = "save_lot"
+	opSaveSession        = "save_session"
+	opCloseSession       = "close_session"
+	opSaveReservation    = "save_reservation"
+	opDeleteReservation  = "delete_reservation"
+	opSavePermitCapacity = "save_permit_capacity"
+	opSavePermit         = "save_permit"
+	opDeletePermit       = "delete_permit"
+)
+
+// logEntry is a single line of the append-only log
+type logEntry struct {
+	Op        string            `json:"op"`
+	LotID     string            `json:"lot_id"`
+	SlotSizes []models.SlotSize `json:"slot_sizes,omitempty"`
+	// Capacity is only set by logs written before slots had sizes, whose
+	// slots are all medium
+	Capacity        int                    `json:"capacity,omitempty"`
+	Session         *models.ParkingSession `json:"session,omitempty"`
+	TicketNumber    string                 `json:"ticket_number,omitempty"`
+	Reservation     *models.Reservation    `json:"reservation,omitempty"`
+	ReservationCode string                 `json:"reservation_code,omitempty"`
+	PermitCapacity  int                    `json:"permit_capacity,omitempty"`
+	Permit          *models.Permit         `json:"permit,omitempty"`
+	PermitID        string                 `json:"permit_id,omitempty"`
+}
+
+// FileRepository is a durable repository backed by an append-only log with
+// one JSON entry per line. The log is replayed into memory when the file is
+// opened, and every change is synced to disk before it is applied, so the
+// state survives process restarts.
+type FileRepository struct {
+	mu     sync.Mutex
+	file   *os.File
+	memory *InMemoryRepository
+}
+
+// NewFileRepository opens the log at path, creating it if needed, and
+// replays it. A trailing entry left half-written by a crash is discarded.
+func NewFileRepository(path string) (*FileRepository, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FileRepository{
+		file:   file,
+		memory: NewInMemoryRepository(),
+	}
+
+	if err := r.replay(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *FileRepository) replay() error {
+	reader := bufio.NewReader(r.file)
+	var offset int64
+
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(bytes.TrimSpace(data)) > 0 {
+				// torn write from a crash, drop it so the next append starts clean
+				return r.file.Truncate(offset)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var entry logEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return fmt.Errorf("%w: line %d: %v", errors.ErrCorruptLog, line, err)
+		}
+		if err := r.apply(entry); err != nil {
+			return fmt.Errorf("%w: line %d: %v", errors.ErrCorruptLog, line, err)
+		}
+
+		offset += int64(len(data))
+	}
+}
+
+func (r *FileRepository) apply(entry logEntry) error {
+	switch entry.Op {
+	case opSaveLot:
+		slotSizes := entry.SlotSizes
+		if slotSizes == nil {
+			slotSizes = make([]models.SlotSize, entry.Capacity)
+			for i := range slotSizes {
+				slotSizes[i] = models.SlotSizeMedium
+			}
+		}
+		return r.memory.SaveLot(entry.LotID, slotSizes)
+	case opSaveSession:
+		if entry.Session == nil {
+			return fmt.Errorf("missing session")
+		}
+		return r.memory.SaveSession(entry.LotID, *entry.Session)
+	case opCloseSession:
+		return r.memory.CloseSession(entry.LotID, entry.TicketNumber)
+	case opSaveReservation:
+		if entry.Reservation == nil {
+			return fmt.Errorf("missing reservation")
+		}
+		return r.memory.SaveReservation(entry.LotID, *entry.Reservation)
+	case opDeleteReservation:
+		return r.memory.DeleteReservation(entry.LotID, entry.ReservationCode)
+	case opSavePermitCapacity:
+		return r.memory.SavePermitCapacity(entry.LotID, entry.PermitCapacity)
+	case opSavePermit:
+		if entry.Permit == nil {
+			return fmt.Errorf("missing permit")
+		}
+		return r.memory.SavePermit(*entry.Permit)
+	case opDeletePermit:
+		return r.memory.DeletePermit(entry.PermitID)
+	default:
+		return fmt.Errorf("unknown op %q", entry.Op)
+	}
+}
+
+// append writes the entry to disk and then applies it in memory.
+// It must be called with r.mu held.
+func (r *FileRepository) append(entry logEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := r.file.Sync(); err != nil {
+		return err
+	}
+
+	return r.apply(entry)
+}
+
+func (r *FileRepository) SaveLot(id string, slotSizes []models.SlotSize) error {
+	for _, size := range slotSizes {
+		if !size.IsValid() {
+			return errors.ErrInvalidSlotSize
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.append(logEntry{
+		Op:        opSaveLot,
+		LotID:     id,
+		SlotSizes: slotSizes,
+	})
+}
+
+func (r *FileRepository) FindLot(id string) (*LotRecord, error) {
+	return r.memory.FindLot(id)
+}
+
+func (r *FileRepository) FindAllLots() ([]*LotRecord, error) {
+	return r.memory.FindAllLots()
+}
+
+func (r *FileRepository) SaveSession(lotID string, session models.ParkingSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// validate before writing so a rejected change never reaches the log
+	if _, err := r.memory.checkSession(lotID, session.Ticket.TicketNumber); err != nil {
+		return err
+	}
+
+	return r.append(logEntry{
+		Op:      opSaveSession,
+		LotID:   lotID,
+		Session: &session,
+	})
+}
+
+func (r *FileRepository) CloseSession(lotID string, ticketNumber string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exists, err := r.memory.checkSession(lotID, ticketNumber)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.ErrSessionNotFound
+	}
+
+	return r.append(logEntry{
+		Op:           opCloseSession,
+		LotID:        lotID,
+		TicketNumber: ticketNumber,
+	})
+}
+
+func (r *FileRepository) SaveReservation(lotID string, reservation models.Reservation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.memory.checkReservation(lotID, reservation.Code); err != nil {
+		return err
+	}
+
+	return r.append(logEntry{
+		Op:          opSaveReservation,
+		LotID:       lotID,
+		Reservation: &reservation,
+	})
+}
+
+func (r *FileRepository) DeleteReservation(lotID string, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exists, err := r.memory.checkReservation(lotID, code)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.ErrReservationNotFound
+	}
+
+	return r.append(logEntry{
+		Op:              opDeleteReservation,
+		LotID:           lotID,
+		ReservationCode: code,
+	})
+}
+
+func (r *FileRepository) SavePermitCapacity(lotID string, capacity int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.memory.checkPermitCapacity(lotID, capacity); err != nil {
+		return err
+	}
+
+	return r.append(logEntry{
+		Op:             opSavePermitCapacity,
+		LotID:          lotID,
+		PermitCapacity: capacity,
+	})
+}
+
+func (r *FileRepository) SavePermit(permit models.Permit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.append(logEntry{
+		Op:     opSavePermit,
+		Permit: &permit,
+	})
+}
+
+func (r *FileRepository) DeletePermit(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.memory.checkPermit(id) {
+		return errors.ErrPermitNotFound
+	}
+
+	return r.append(logEntry{
+		Op:       opDeletePermit,
+		PermitID: id,
+	})
+}
+
+func (r *FileRepository) FindAllPermits() ([]models.Permit, error) {
+	return r.memory.FindAllPermits()
+}
+
+// Close closes the underlying log file
+func (r *FileRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.file.Close()
+}
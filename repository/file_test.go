@@ -0,0 +1,156 @@
+package repository
+
+import (
+	goerrors "errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileRepository(t *testing.T) {
+	t.Run("should restore lots, sessions and used tickets after reopening", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "parking.log")
+		repo, err := NewFileRepository(path)
+		assert.NoError(t, err)
+
+		_ = repo.SaveLot("lot1", mediumSlots(10))
+		_ = repo.SaveLot("lot2", mediumSlots(5))
+		_ = repo.SaveSession("lot1", newSession("T1", "AAA111"))
+		_ = repo.SaveSession("lot1", newSession("T2", "BBB222"))
+		_ = repo.CloseSession("lot1", "T1")
+		assert.NoError(t, repo.Close())
+
+		reopened, err := NewFileRepository(path)
+		assert.NoError(t, err)
+		defer reopened.Close()
+
+		lots, _ := reopened.FindAllLots()
+		lot, _ := reopened.FindLot("lot1")
+
+		assert.Len(t, lots, 2)
+		assert.Equal(t, 10, lot.Capacity)
+		assert.Len(t, lot.Sessions, 1)
+		assert.Equal(t, "BBB222", lot.Sessions["T2"].Car.LicensePlate)
+		assert.Equal(t, newSession("T2", "BBB222").Ticket.EntryTime, lot.Sessions["T2"].Ticket.EntryTime.UTC())
+		assert.True(t, lot.UsedTickets["T1"])
+	})
+
+	t.Run("should not write rejected changes to the log", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "parking.log")
+		repo, _ := NewFileRepository(path)
+		_ = repo.SaveLot("lot1", mediumSlots(10))
+
+		assert.Equal(t, errors.ErrLotNotFound, repo.SaveSession("unknown", newSession("T1", "AAA111")))
+		assert.Equal(t, errors.ErrSessionNotFound, repo.CloseSession("lot1", "T1"))
+		assert.NoError(t, repo.Close())
+
+		_, err := NewFileRepository(path)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("should restore reservations after reopening", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "parking.log")
+		repo, _ := NewFileRepository(path)
+		_ = repo.SaveLot("lot1", mediumSlots(10))
+		_ = repo.SaveReservation("lot1", newReservation("R1", "AAA111"))
+		_ = repo.SaveReservation("lot1", newReservation("R2", ""))
+		_ = repo.DeleteReservation("lot1", "R1")
+		assert.Equal(t, errors.ErrReservationNotFound, repo.DeleteReservation("lot1", "R1"))
+		assert.NoError(t, repo.Close())
+
+		reopened, err := NewFileRepository(path)
+		assert.NoError(t, err)
+		defer reopened.Close()
+
+		lot, _ := reopened.FindLot("lot1")
+
+		assert.Len(t, lot.Reservations, 1)
+		assert.Equal(t, newReservation("R2", "").End, lot.Reservations["R2"].End.UTC())
+	})
+
+	t.Run("should restore permits and permit capacity after reopening", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "parking.log")
+		repo, _ := NewFileRepository(path)
+		from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+		_ = repo.SaveLot("lot1", mediumSlots(10))
+		_ = repo.SavePermitCapacity("lot1", 3)
+		_ = repo.SavePermit(models.Permit{ID: "P1", LicensePlate: "AAA111", LotIDs: []string{"lot1"}, ValidFrom: from, ValidUntil: from.AddDate(0, 1, 0)})
+		_ = repo.SavePermit(models.Permit{ID: "P2", LicensePlate: "BBB222", ValidFrom: from, ValidUntil: from.AddDate(0, 1, 0)})
+		_ = repo.DeletePermit("P2")
+		assert.Equal(t, errors.ErrInvalidPermitCapacity, repo.SavePermitCapacity("lot1", 11))
+		assert.NoError(t, repo.Close())
+
+		reopened, err := NewFileRepository(path)
+		assert.NoError(t, err)
+		defer reopened.Close()
+
+		lot, _ := reopened.FindLot("lot1")
+		permits, _ := reopened.FindAllPermits()
+
+		assert.Equal(t, 3, lot.PermitCapacity)
+		assert.Len(t, permits, 1)
+		assert.Equal(t, []string{"lot1"}, permits[0].LotIDs)
+	})
+
+	t.Run("should discard a half-written last entry", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "parking.log")
+		repo, _ := NewFileRepository(path)
+		_ = repo.SaveLot("lot1", mediumSlots(10))
+		_ = repo.Close()
+
+		f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+		_, _ = f.WriteString(`{"op":"save_session","lot_id":"lot1","sess`)
+		_ = f.Close()
+
+		reopened, err := NewFileRepository(path)
+		assert.NoError(t, err)
+
+		assert.NoError(t, reopened.SaveSession("lot1", newSession("T1", "AAA111")))
+		_ = reopened.Close()
+
+		reopened, err = NewFileRepository(path)
+		assert.NoError(t, err)
+		lot, _ := reopened.FindLot("lot1")
+		assert.Len(t, lot.Sessions, 1)
+	})
+
+	t.Run("should restore slot sizes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "parking.log")
+		repo, _ := NewFileRepository(path)
+		sizes := []models.SlotSize{models.SlotSizeSmall, models.SlotSizeExtraLarge}
+		_ = repo.SaveLot("lot1", sizes)
+		_ = repo.Close()
+
+		reopened, _ := NewFileRepository(path)
+		lot, _ := reopened.FindLot("lot1")
+
+		assert.Equal(t, sizes, lot.SlotSizes)
+		assert.Equal(t, 2, lot.Capacity)
+	})
+
+	t.Run("should read lots saved with a capacity as medium slots", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "parking.log")
+		_ = os.WriteFile(path, []byte(`{"op":"save_lot","lot_id":"lot1","capacity":3}`+"\n"), 0o644)
+
+		repo, err := NewFileRepository(path)
+		assert.NoError(t, err)
+		lot, _ := repo.FindLot("lot1")
+
+		assert.Equal(t, mediumSlots(3), lot.SlotSizes)
+	})
+
+	t.Run("should return error for a corrupt log", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "parking.log")
+		_ = os.WriteFile(path, []byte("not json\n"), 0o644)
+
+		_, err := NewFileRepository(path)
+
+		assert.True(t, goerrors.Is(err, errors.ErrCorruptLog))
+	})
+}
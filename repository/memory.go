@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+)
+
+// InMemoryRepository keeps parking lot state for the lifetime of the process
+type InMemoryRepository struct {
+	mu      sync.RWMutex
+	lots    map[string]*LotRecord
+	permits map[string]models.Permit
+}
+
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		lots:    make(map[string]*LotRecord),
+		permits: make(map[string]models.Permit),
+	}
+}
+
+func (r *InMemoryRepository) SaveLot(id string, slotSizes []models.SlotSize) error {
+	for _, size := range slotSizes {
+		if !size.IsValid() {
+			return errors.ErrInvalidSlotSize
+		}
+	}
+
+	sizes := make([]models.SlotSize, len(slotSizes))
+	copy(sizes, slotSizes)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lot, exists := r.lots[id]; exists {
+		lot.Capacity = len(sizes)
+		lot.SlotSizes = sizes
+		if lot.PermitCapacity > lot.Capacity {
+			lot.PermitCapacity = lot.Capacity
+		}
+		return nil
+	}
+
+	r.lots[id] = &LotRecord{
+		ID:           id,
+		Capacity:     len(sizes),
+		SlotSizes:    sizes,
+		Sessions:     make(map[string]models.ParkingSession),
+		UsedTickets:  make(map[string]bool),
+		Reservations: make(map[string]models.Reservation),
+	}
+	return nil
+}
+
+func (r *InMemoryRepository) FindLot(id string) (*LotRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lot, exists := r.lots[id]
+	if !exists {
+		return nil, errors.ErrLotNotFound
+	}
+
+	return copyLotRecord(lot), nil
+}
+
+// FindAllLots returns all lots ordered by ID
+func (r *InMemoryRepository) FindAllLots() ([]*LotRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lots := make([]*LotRecord, 0, len(r.lots))
+	for _, lot := range r.lots {
+		lots = append(lots, copyLotRecord(lot))
+	}
+
+	sort.Slice(lots, func(i, j int) bool {
+		return lots[i].ID < lots[j].ID
+	})
+	return lots, nil
+}
+
+func (r *InMemoryRepository) SaveSession(lotID string, session models.ParkingSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lot, exists := r.lots[lotID]
+	if !exists {
+		return errors.ErrLotNotFound
+	}
+
+	lot.Sessions[session.Ticket.TicketNumber] = session
+	return nil
+}
+
+func (r *InMemoryRepository) CloseSession(lotID string, ticketNumber string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lot, exists := r.lots[lotID]
+	if !exists {
+		return errors.ErrLotNotFound
+	}
+
+	if _, exists := lot.Sessions[ticketNumber]; !exists {
+		return errors.ErrSessionNotFound
+	}
+
+	delete(lot.Sessions, ticketNumber)
+	lot.UsedTickets[ticketNumber] = true
+	return nil
+}
+
+func (r *InMemoryRepository) SaveReservation(lotID string, reservation models.Reservation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lot, exists := r.lots[lotID]
+	if !exists {
+		return errors.ErrLotNotFound
+	}
+
+	lot.Reservations[reservation.Code] = reservation
+	return nil
+}
+
+func (r *InMemoryRepository) DeleteReservation(lotID string, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lot, exists := r.lots[lotID]
+	if !exists {
+		return errors.ErrLotNotFound
+	}
+
+	if _, exists := lot.Reservations[code]; !exists {
+		return errors.ErrReservationNotFound
+	}
+
+	delete(lot.Reservations, code)
+	return nil
+}
+
+func (r *InMemoryRepository) SavePermitCapacity(lotID string, capacity int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.validatePermitCapacity(lotID, capacity); err != nil {
+		return err
+	}
+
+	r.lots[lotID].PermitCapacity = capacity
+	return nil
+}
+
+func (r *InMemoryRepository) SavePermit(permit models.Permit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	permit.LotIDs = append([]string(nil), permit.LotIDs...)
+	r.permits[permit.ID] = permit
+	return nil
+}
+
+func (r *InMemoryRepository) DeletePermit(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.permits[id]; !exists {
+		return errors.ErrPermitNotFound
+	}
+
+	delete(r.permits, id)
+	return nil
+}
+
+func (r *InMemoryRepository) FindAllPermits() ([]models.Permit, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	permits := make([]models.Permit, 0, len(r.permits))
+	for _, permit := range r.permits {
+		permit.LotIDs = append([]string(nil), permit.LotIDs...)
+		permits = append(permits, permit)
+	}
+	sort.Slice(permits, func(i, j int) bool {
+		return permits[i].ID < permits[j].ID
+	})
+	return permits, nil
+}
+
+// checkPermitCapacity returns the error SavePermitCapacity would return
+func (r *InMemoryRepository) checkPermitCapacity(lotID string, capacity int) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.validatePermitCapacity(lotID, capacity)
+}
+
+// validatePermitCapacity must be called with r.mu held.
+func (r *InMemoryRepository) validatePermitCapacity(lotID string, capacity int) error {
+	lot, exists := r.lots[lotID]
+	if !exists {
+		return errors.ErrLotNotFound
+	}
+	if capacity < 0 || capacity > lot.Capacity {
+		return errors.ErrInvalidPermitCapacity
+	}
+	return nil
+}
+
+// checkPermit returns whether the permit exists
+func (r *InMemoryRepository) checkPermit(id string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, exists := r.permits[id]
+	return exists
+}
+
+// checkReservation returns whether the reservation exists, or
+// ErrLotNotFound when the lot itself is unknown
+func (r *InMemoryRepository) checkReservation(lotID string, code string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lot, exists := r.lots[lotID]
+	if !exists {
+		return false, errors.ErrLotNotFound
+	}
+
+	_, exists = lot.Reservations[code]
+	return exists, nil
+}
+
+// checkSession returns whether the session exists, or ErrLotNotFound when
+// the lot itself is unknown
+func (r *InMemoryRepository) checkSession(lotID string, ticketNumber string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lot, exists := r.lots[lotID]
+	if !exists {
+		return false, errors.ErrLotNotFound
+	}
+
+	_, exists = lot.Sessions[ticketNumber]
+	return exists, nil
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSession(ticketNumber string, plate string) models.ParkingSession {
+	return models.ParkingSession{
+		Ticket: models.Ticket{
+			TicketNumber: ticketNumber,
+			EntryTime:    time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC),
+		},
+		Car: models.Car{LicensePlate: plate},
+	}
+}
+
+func mediumSlots(n int) []models.SlotSize {
+	sizes := make([]models.SlotSize, n)
+	for i := range sizes {
+		sizes[i] = models.SlotSizeMedium
+	}
+	return sizes
+}
+
+func newReservation(code string, plate string) models.Reservation {
+	start := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+	return models.Reservation{
+		Code:         code,
+		LotID:        "lot1",
+		LicensePlate: plate,
+		VehicleType:  models.VehicleTypeCar,
+		Start:        start,
+		End:          start.Add(2 * time.Hour),
+	}
+}
+
+func TestInMemoryRepository(t *testing.T) {
+	t.Run("should save and find a lot", func(t *testing.T) {
+		repo := NewInMemoryRepository()
+
+		err := repo.SaveLot("lot1", mediumSlots(10))
+		lot, findErr := repo.FindLot("lot1")
+
+		assert.NoError(t, err)
+		assert.NoError(t, findErr)
+		assert.Equal(t, "lot1", lot.ID)
+		assert.Equal(t, 10, lot.Capacity)
+		assert.Equal(t, mediumSlots(10), lot.SlotSizes)
+		assert.Empty(t, lot.Sessions)
+		assert.Empty(t, lot.UsedTickets)
+	})
+
+	t.Run("should reject invalid slot sizes", func(t *testing.T) {
+		repo := NewInMemoryRepository()
+
+		err := repo.SaveLot("lot1", []models.SlotSize{models.SlotSizeSmall, "huge"})
+
+		assert.Equal(t, errors.ErrInvalidSlotSize, err)
+	})
+
+	t.Run("should return error for unknown lot", func(t *testing.T) {
+		repo := NewInMemoryRepository()
+
+		_, err := repo.FindLot("unknown")
+
+		assert.Equal(t, errors.ErrLotNotFound, err)
+		assert.Equal(t, errors.ErrLotNotFound, repo.SaveSession("unknown", newSession("T1", "AAA111")))
+		assert.Equal(t, errors.ErrLotNotFound, repo.CloseSession("unknown", "T1"))
+	})
+
+	t.Run("should keep sessions when the lot capacity changes", func(t *testing.T) {
+		repo := NewInMemoryRepository()
+		_ = repo.SaveLot("lot1", mediumSlots(10))
+		_ = repo.SaveSession("lot1", newSession("T1", "AAA111"))
+
+		_ = repo.SaveLot("lot1", mediumSlots(20))
+		lot, _ := repo.FindLot("lot1")
+
+		assert.Equal(t, 20, lot.Capacity)
+		assert.Equal(t, "AAA111", lot.Sessions["T1"].Car.LicensePlate)
+	})
+
+	t.Run("should close a session and mark its ticket as used", func(t *testing.T) {
+		repo := NewInMemoryRepository()
+		_ = repo.SaveLot("lot1", mediumSlots(10))
+		_ = repo.SaveSession("lot1", newSession("T1", "AAA111"))
+
+		err := repo.CloseSession("lot1", "T1")
+		lot, _ := repo.FindLot("lot1")
+
+		assert.NoError(t, err)
+		assert.Empty(t, lot.Sessions)
+		assert.True(t, lot.UsedTickets["T1"])
+		assert.Equal(t, errors.ErrSessionNotFound, repo.CloseSession("lot1", "T1"))
+	})
+
+	t.Run("should return copies that can't change the stored state", func(t *testing.T) {
+		repo := NewInMemoryRepository()
+		_ = repo.SaveLot("lot1", mediumSlots(10))
+
+		lot, _ := repo.FindLot("lot1")
+		lot.Sessions["T1"] = newSession("T1", "AAA111")
+		lot.UsedTickets["T2"] = true
+		stored, _ := repo.FindLot("lot1")
+
+		assert.Empty(t, stored.Sessions)
+		assert.Empty(t, stored.UsedTickets)
+	})
+
+	t.Run("should save and delete reservations", func(t *testing.T) {
+		repo := NewInMemoryRepository()
+		_ = repo.SaveLot("lot1", mediumSlots(10))
+
+		saveErr := repo.SaveReservation("lot1", newReservation("R1", "AAA111"))
+		_ = repo.SaveReservation("lot1", newReservation("R2", "BBB222"))
+		deleteErr := repo.DeleteReservation("lot1", "R1")
+		lot, _ := repo.FindLot("lot1")
+
+		assert.NoError(t, saveErr)
+		assert.NoError(t, deleteErr)
+		assert.Len(t, lot.Reservations, 1)
+		assert.Equal(t, "BBB222", lot.Reservations["R2"].LicensePlate)
+	})
+
+	t.Run("should return error for reservations of unknown lots or codes", func(t *testing.T) {
+		repo := NewInMemoryRepository()
+		_ = repo.SaveLot("lot1", mediumSlots(10))
+
+		assert.Equal(t, errors.ErrLotNotFound, repo.SaveReservation("unknown", newReservation("R1", "AAA111")))
+		assert.Equal(t, errors.ErrLotNotFound, repo.DeleteReservation("unknown", "R1"))
+		assert.Equal(t, errors.ErrReservationNotFound, repo.DeleteReservation("lot1", "R1"))
+	})
+
+	t.Run("should save the permit capacity within the lot capacity", func(t *testing.T) {
+		repo := NewInMemoryRepository()
+		_ = repo.SaveLot("lot1", mediumSlots(10))
+
+		err := repo.SavePermitCapacity("lot1", 4)
+		lot, _ := repo.FindLot("lot1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, lot.PermitCapacity)
+		assert.Equal(t, errors.ErrInvalidPermitCapacity, repo.SavePermitCapacity("lot1", 11))
+		assert.Equal(t, errors.ErrInvalidPermitCapacity, repo.SavePermitCapacity("lot1", -1))
+		assert.Equal(t, errors.ErrLotNotFound, repo.SavePermitCapacity("unknown", 1))
+	})
+
+	t.Run("should save, find and delete permits", func(t *testing.T) {
+		repo := NewInMemoryRepository()
+		from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+		_ = repo.SavePermit(models.Permit{ID: "P2", LicensePlate: "BBB222", ValidFrom: from, ValidUntil: from.AddDate(0, 1, 0)})
+		_ = repo.SavePermit(models.Permit{ID: "P1", LicensePlate: "AAA111", ValidFrom: from, ValidUntil: from.AddDate(0, 1, 0)})
+
+		err := repo.DeletePermit("P2")
+		permits, _ := repo.FindAllPermits()
+
+		assert.NoError(t, err)
+		assert.Len(t, permits, 1)
+		assert.Equal(t, "AAA111", permits[0].LicensePlate)
+		assert.Equal(t, errors.ErrPermitNotFound, repo.DeletePermit("P2"))
+	})
+
+	t.Run("should find all lots ordered by ID", func(t *testing.T) {
+		repo := NewInMemoryRepository()
+		_ = repo.SaveLot("lot2", mediumSlots(5))
+		_ = repo.SaveLot("lot1", mediumSlots(10))
+
+		lots, err := repo.FindAllLots()
+
+		assert.NoError(t, err)
+		assert.Len(t, lots, 2)
+		assert.Equal(t, "lot1", lots[0].ID)
+		assert.Equal(t, "lot2", lots[1].ID)
+	})
+}
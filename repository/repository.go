@@ -0,0 +1,88 @@
+package repository
+
+import "github.com/natanaelrusli/parking-lot/models"
+
+// LotRecord is the stored state of a parking lot
+type LotRecord struct {
+	ID       string
+	Capacity int
+	// Size of each slot, SlotSizes[i] is the size of slot number i+1
+	SlotSizes []models.SlotSize
+	// Cars currently parked, keyed by ticket number
+	Sessions map[string]models.ParkingSession
+	// Tickets that have already been used to leave the lot
+	UsedTickets map[string]bool
+	// Reservations not redeemed, cancelled or expired yet, keyed by code
+	Reservations map[string]models.Reservation
+	// Spaces kept for permit holders
+	PermitCapacity int
+}
+
+// ParkingLotRepository stores parking lot state.
+// Implementations must be safe for concurrent use, since several lots
+// may share a single repository.
+type ParkingLotRepository interface {
+	// SaveLot creates the lot or updates its slot layout, keeping its
+	// sessions and used tickets. The lot capacity is the number of slots.
+	SaveLot(id string, slotSizes []models.SlotSize) error
+	FindLot(id string) (*LotRecord, error)
+	FindAllLots() ([]*LotRecord, error)
+	// SaveSession records a car entering the lot
+	SaveSession(lotID string, session models.ParkingSession) error
+	// CloseSession removes the session and marks its ticket as used
+	CloseSession(lotID string, ticketNumber string) error
+	// SaveReservation records a reservation made in the lot
+	SaveReservation(lotID string, reservation models.Reservation) error
+	// DeleteReservation removes a reservation once it is redeemed,
+	// cancelled or expired
+	DeleteReservation(lotID string, code string) error
+	// SavePermitCapacity sets the number of spaces kept for permit holders
+	SavePermitCapacity(lotID string, capacity int) error
+}
+
+// PermitRepository stores parking permits, which are not tied to a single
+// lot. Implementations must be safe for concurrent use.
+type PermitRepository interface {
+	SavePermit(permit models.Permit) error
+	// DeletePermit removes the permit, returning ErrPermitNotFound if it
+	// doesn't exist
+	DeletePermit(id string) error
+	// FindAllPermits returns all permits ordered by ID
+	FindAllPermits() ([]models.Permit, error)
+}
+
+// Repository stores both lots and permits
+type Repository interface {
+	ParkingLotRepository
+	PermitRepository
+}
+
+func copyLotRecord(lot *LotRecord) *LotRecord {
+	sessions := make(map[string]models.ParkingSession, len(lot.Sessions))
+	for ticketNumber, session := range lot.Sessions {
+		sessions[ticketNumber] = session
+	}
+
+	usedTickets := make(map[string]bool, len(lot.UsedTickets))
+	for ticketNumber, used := range lot.UsedTickets {
+		usedTickets[ticketNumber] = used
+	}
+
+	reservations := make(map[string]models.Reservation, len(lot.Reservations))
+	for code, reservation := range lot.Reservations {
+		reservations[code] = reservation
+	}
+
+	slotSizes := make([]models.SlotSize, len(lot.SlotSizes))
+	copy(slotSizes, lot.SlotSizes)
+
+	return &LotRecord{
+		ID:             lot.ID,
+		Capacity:       lot.Capacity,
+		SlotSizes:      slotSizes,
+		Sessions:       sessions,
+		UsedTickets:    usedTickets,
+		Reservations:   reservations,
+		PermitCapacity: lot.PermitCapacity,
+	}
+}
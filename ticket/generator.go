@@ -0,0 +1,178 @@
+package ticket
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+)
+
+// MaxGenerateAttempts is how many ticket numbers UniqueGenerator draws
+// before giving up on finding a free one
+const MaxGenerateAttempts = 100
+
+// Generator issues ticket numbers for a lot. Generators don't need to
+// guarantee uniqueness, wrap them in a UniqueGenerator to skip numbers
+// already taken.
+type Generator interface {
+	Generate(lotID string) string
+}
+
+// GeneratorFunc lets an ordinary function be used as a Generator
+type GeneratorFunc func(lotID string) string
+
+func (f GeneratorFunc) Generate(lotID string) string {
+	return f(lotID)
+}
+
+// RandomGenerator issues the first 8 hex characters of a random UUID
+type RandomGenerator struct{}
+
+func NewRandomGenerator() *RandomGenerator {
+	return &RandomGenerator{}
+}
+
+func (g *RandomGenerator) Generate(lotID string) string {
+	return GenerateTicketNumber()
+}
+
+// Seeder is implemented by generators that continue from the ticket
+// numbers a lot issued before, e.g. before a restart. Lots seed their
+// generator with the numbers of the parked cars and used tickets when they
+// are restored.
+type Seeder interface {
+	Seed(lotID string, issued []string)
+}
+
+// SequentialGenerator numbers the tickets of each lot from 1, prefixed with
+// the lot ID, e.g. "lot1-000042". It is safe for concurrent use.
+type SequentialGenerator struct {
+	mu   sync.Mutex
+	next map[string]uint64
+}
+
+func NewSequentialGenerator() *SequentialGenerator {
+	return &SequentialGenerator{next: make(map[string]uint64)}
+}
+
+func (g *SequentialGenerator) Generate(lotID string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.next[lotID]++
+	return fmt.Sprintf("%s-%06d", lotID, g.next[lotID])
+}
+
+// Seed makes the lot's numbering continue after the highest sequential
+// number issued. Numbers issued by other generators are ignored.
+func (g *SequentialGenerator) Seed(lotID string, issued []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	prefix := lotID + "-"
+	for _, ticketNumber := range issued {
+		if !strings.HasPrefix(ticketNumber, prefix) {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimPrefix(ticketNumber, prefix), 10, 64)
+		if err == nil && n > g.next[lotID] {
+			g.next[lotID] = n
+		}
+	}
+}
+
+// friendlyDigits is the number of random digits in a friendly ticket
+// number, the check digit makes it 8
+const friendlyDigits = 7
+
+// FriendlyGenerator issues ticket numbers that are easy to read out and
+// type at a pay station: 8 digits, split in two groups, the last one being
+// a Luhn check digit so most typos are caught by ValidateFriendly, e.g.
+// "4821-3907".
+type FriendlyGenerator struct{}
+
+func NewFriendlyGenerator() *FriendlyGenerator {
+	return &FriendlyGenerator{}
+}
+
+func (g *FriendlyGenerator) Generate(lotID string) string {
+	max := big.NewInt(1)
+	for i := 0; i < friendlyDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		// the system's random source is broken, nothing sensible to do
+		panic(err)
+	}
+
+	digits := fmt.Sprintf("%0*d", friendlyDigits, n)
+	digits += string('0' + luhnCheckDigit(digits))
+	return digits[:4] + "-" + digits[4:]
+}
+
+// ValidateFriendly checks the check digit of a ticket number issued by
+// FriendlyGenerator. The group separator is optional.
+func ValidateFriendly(ticketNumber string) error {
+	digits := strings.ReplaceAll(ticketNumber, "-", "")
+	if len(digits) != friendlyDigits+1 {
+		return errors.ErrMalformedTicket
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return errors.ErrMalformedTicket
+		}
+	}
+
+	if luhnCheckDigit(digits[:friendlyDigits]) != digits[friendlyDigits]-'0' {
+		return errors.ErrInvalidCheckDigit
+	}
+	return nil
+}
+
+// luhnCheckDigit returns the digit that makes digits followed by it pass
+// the Luhn check
+func luhnCheckDigit(digits string) byte {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return byte((10 - sum%10) % 10)
+}
+
+// UniqueGenerator draws ticket numbers from a generator until it finds one
+// that isn't taken, e.g. by a car still parked or a ticket already used
+type UniqueGenerator struct {
+	generator Generator
+	taken     func(ticketNumber string) bool
+}
+
+func NewUniqueGenerator(generator Generator, taken func(ticketNumber string) bool) *UniqueGenerator {
+	return &UniqueGenerator{generator: generator, taken: taken}
+}
+
+// Generate returns a ticket number that isn't taken, or
+// ErrTicketNumbersExhausted after MaxGenerateAttempts
+func (g *UniqueGenerator) Generate(lotID string) (string, error) {
+	for i := 0; i < MaxGenerateAttempts; i++ {
+		ticketNumber := g.generator.Generate(lotID)
+		if ticketNumber != "" && !g.taken(ticketNumber) {
+			return ticketNumber, nil
+		}
+	}
+	return "", errors.ErrTicketNumbersExhausted
+}
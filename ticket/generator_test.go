@@ -0,0 +1,79 @@
+package ticket
+
+import (
+	"testing"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequentialGenerator(t *testing.T) {
+	generator := NewSequentialGenerator()
+
+	assert.Equal(t, "lot1-000001", generator.Generate("lot1"))
+	assert.Equal(t, "lot1-000002", generator.Generate("lot1"))
+	assert.Equal(t, "lot2-000001", generator.Generate("lot2"))
+}
+
+func TestSequentialGeneratorSeed(t *testing.T) {
+	generator := NewSequentialGenerator()
+
+	generator.Seed("lot1", []string{"lot1-000007", "lot1-000042", "lot2-000100", "ab12cd34", "lot1-x-000900"})
+
+	assert.Equal(t, "lot1-000043", generator.Generate("lot1"))
+	assert.Equal(t, "lot2-000001", generator.Generate("lot2"))
+
+	generator.Seed("lot1", []string{"lot1-000003"})
+	assert.Equal(t, "lot1-000044", generator.Generate("lot1"), "seeding never goes back")
+}
+
+func TestFriendlyGenerator(t *testing.T) {
+	generator := NewFriendlyGenerator()
+
+	t.Run("should issue numbers that pass their check digit", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			ticketNumber := generator.Generate("lot1")
+
+			assert.Len(t, ticketNumber, 9)
+			assert.Equal(t, byte('-'), ticketNumber[4])
+			assert.NoError(t, ValidateFriendly(ticketNumber))
+		}
+	})
+
+	t.Run("should catch mistyped digits", func(t *testing.T) {
+		assert.NoError(t, ValidateFriendly("7992-7398"))
+		assert.NoError(t, ValidateFriendly("79927398"))
+		assert.Equal(t, errors.ErrInvalidCheckDigit, ValidateFriendly("7992-7399"))
+		assert.Equal(t, errors.ErrInvalidCheckDigit, ValidateFriendly("7992-3798"))
+	})
+
+	t.Run("should reject malformed numbers", func(t *testing.T) {
+		for _, ticketNumber := range []string{"", "1234", "abcd-efgh", "1234-56789"} {
+			assert.Equal(t, errors.ErrMalformedTicket, ValidateFriendly(ticketNumber), ticketNumber)
+		}
+	})
+}
+
+func TestUniqueGenerator(t *testing.T) {
+	t.Run("should skip taken ticket numbers", func(t *testing.T) {
+		taken := map[string]bool{"lot1-000001": true, "lot1-000002": true}
+		generator := NewUniqueGenerator(NewSequentialGenerator(), func(ticketNumber string) bool {
+			return taken[ticketNumber]
+		})
+
+		ticketNumber, err := generator.Generate("lot1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "lot1-000003", ticketNumber)
+	})
+
+	t.Run("should give up when every number drawn is taken", func(t *testing.T) {
+		generator := NewUniqueGenerator(NewRandomGenerator(), func(ticketNumber string) bool {
+			return true
+		})
+
+		_, err := generator.Generate("lot1")
+
+		assert.Equal(t, errors.ErrTicketNumbersExhausted, err)
+	})
+}
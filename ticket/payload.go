@@ -0,0 +1,87 @@
+package ticket
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+)
+
+// payloadPrefix marks, and versions, the payloads printed on tickets
+const payloadPrefix = "PT1"
+
+// payloadSeparator can't appear in the ticket fields of a payload
+const payloadSeparator = "|"
+
+// Payload returns the text a printed ticket's QR code or barcode holds.
+// It keeps everything Unpark needs: the ticket number, the entry time to
+// the nanosecond, the slot and the reservation and permit codes, with
+// empty fields left out to keep the code small, e.g.
+// "PT1|ab12cd34|lt5ybb2og0w0|3".
+func Payload(t *models.Ticket) (string, error) {
+	if t == nil {
+		return "", errors.ErrNilTicket
+	}
+	if t.TicketNumber == "" {
+		return "", errors.ErrEmptyTicketNumber
+	}
+
+	var entryTime, slotNumber string
+	if !t.EntryTime.IsZero() {
+		entryTime = strconv.FormatInt(t.EntryTime.UnixNano(), 36)
+	}
+	if t.SlotNumber != 0 {
+		slotNumber = strconv.Itoa(t.SlotNumber)
+	}
+
+	fields := []string{payloadPrefix, t.TicketNumber, entryTime, slotNumber, t.ReservationCode, t.PermitID}
+	for _, field := range fields {
+		if strings.Contains(field, payloadSeparator) {
+			return "", errors.ErrInvalidTicketPayload
+		}
+	}
+
+	// trailing empty fields are left out
+	for fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+	return strings.Join(fields, payloadSeparator), nil
+}
+
+// ParsePayload returns the ticket a scanned payload was made from by
+// Payload. Nothing in an unsigned payload can be trusted, lots only use its
+// ticket number to find the ticket they issued and bill from that.
+func ParsePayload(payload string) (*models.Ticket, error) {
+	fields := strings.Split(strings.TrimSpace(payload), payloadSeparator)
+	if len(fields) < 2 || len(fields) > 6 || fields[0] != payloadPrefix || fields[1] == "" {
+		return nil, errors.ErrInvalidTicketPayload
+	}
+	// pad the trailing empty fields left out
+	fields = append(fields, make([]string, 6-len(fields))...)
+
+	t := &models.Ticket{
+		TicketNumber:    fields[1],
+		ReservationCode: fields[4],
+		PermitID:        fields[5],
+	}
+
+	if fields[2] != "" {
+		entryTime, err := strconv.ParseInt(fields[2], 36, 64)
+		if err != nil {
+			return nil, errors.ErrInvalidTicketPayload
+		}
+		t.EntryTime = time.Unix(0, entryTime).UTC()
+	}
+
+	if fields[3] != "" {
+		slotNumber, err := strconv.Atoi(fields[3])
+		if err != nil || slotNumber < 0 {
+			return nil, errors.ErrInvalidTicketPayload
+		}
+		t.SlotNumber = slotNumber
+	}
+
+	return t, nil
+}
@@ -0,0 +1,148 @@
+package ticket
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+)
+
+const (
+	// qrQuietZone is the blank border, in modules, scanners need around a
+	// QR code
+	qrQuietZone = 4
+	// code128QuietZone is the blank margin, in modules, on each side of a
+	// Code 128 barcode
+	code128QuietZone = 10
+	// code128Height is the height of Code 128 bars, in modules
+	code128Height = 50
+	// code128MaxLength is the most characters a Code 128 barcode holds
+	code128MaxLength = 80
+)
+
+// QRCode encodes the ticket's payload as a QR code with medium error
+// correction, so a creased or smudged ticket still scans
+func QRCode(t *models.Ticket) (barcode.Barcode, error) {
+	payload, err := Payload(t)
+	if err != nil {
+		return nil, err
+	}
+	return qr.Encode(payload, qr.M, qr.Auto)
+}
+
+// Code128 encodes the ticket's payload as a Code 128 barcode, which holds
+// at most 80 characters. Signed ticket numbers are too long to fit with
+// their entry time, print a copy of the ticket without it: lots that sign
+// their tickets bill from the signed entry time.
+func Code128(t *models.Ticket) (barcode.Barcode, error) {
+	payload, err := Payload(t)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > code128MaxLength {
+		return nil, errors.ErrTicketPayloadTooLong
+	}
+	return code128.Encode(payload)
+}
+
+// WritePNG writes the code as a black on white PNG, each module scale
+// pixels wide, surrounded by the quiet zone scanners need
+func WritePNG(w io.Writer, code barcode.Barcode, scale int) error {
+	if scale <= 0 {
+		return errors.ErrInvalidScale
+	}
+
+	grid := newModuleGrid(code)
+	img := image.NewGray(image.Rect(0, 0, grid.width()*scale, grid.height()*scale))
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			if grid.dark(x/scale, y/scale) {
+				img.SetGray(x, y, color.Gray{Y: 0})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// WriteSVG writes the code as a black on white SVG, each module scale
+// units wide, surrounded by the quiet zone scanners need. Runs of dark
+// modules are drawn as a single rectangle to keep the file small.
+func WriteSVG(w io.Writer, code barcode.Barcode, scale int) error {
+	if scale <= 0 {
+		return errors.ErrInvalidScale
+	}
+
+	grid := newModuleGrid(code)
+	width, height := grid.width()*scale, grid.height()*scale
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", width, height)
+	for y := 0; y < grid.height(); y++ {
+		for x := 0; x < grid.width(); x++ {
+			if !grid.dark(x, y) {
+				continue
+			}
+			run := 1
+			for grid.dark(x+run, y) {
+				run++
+			}
+			fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" fill="#000"/>`+"\n",
+				x*scale, y*scale, run*scale, scale)
+			x += run
+		}
+	}
+	fmt.Fprintln(bw, `</svg>`)
+	return bw.Flush()
+}
+
+// moduleGrid is a code laid out in modules, with its quiet zone
+type moduleGrid struct {
+	code barcode.Barcode
+	// quiet zone around the code, in modules
+	marginX, marginY int
+	// rows a 1D code is stretched over
+	rows int
+}
+
+func newModuleGrid(code barcode.Barcode) moduleGrid {
+	if code.Metadata().Dimensions == 1 {
+		return moduleGrid{code: code, marginX: code128QuietZone, rows: code128Height}
+	}
+	return moduleGrid{code: code, marginX: qrQuietZone, marginY: qrQuietZone, rows: code.Bounds().Dy()}
+}
+
+func (g moduleGrid) width() int {
+	return g.code.Bounds().Dx() + 2*g.marginX
+}
+
+func (g moduleGrid) height() int {
+	return g.rows + 2*g.marginY
+}
+
+// dark reports whether the module at x, y of the grid is dark. Modules
+// outside the code are light.
+func (g moduleGrid) dark(x, y int) bool {
+	x, y = x-g.marginX, y-g.marginY
+	bounds := g.code.Bounds()
+	if x < 0 || y < 0 || x >= bounds.Dx() || y >= g.rows {
+		return false
+	}
+	if g.code.Metadata().Dimensions == 1 {
+		y = 0
+	}
+
+	gray := color.GrayModel.Convert(g.code.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray)
+	return gray.Y < 128
+}
@@ -0,0 +1,141 @@
+package ticket
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayload(t *testing.T) {
+	issued := &models.Ticket{
+		TicketNumber:    "ab12cd34",
+		EntryTime:       time.Date(2024, time.March, 1, 8, 0, 0, 123, time.UTC),
+		SlotNumber:      3,
+		ReservationCode: "res1",
+		PermitID:        "permit1",
+	}
+
+	t.Run("should parse the tickets it encoded", func(t *testing.T) {
+		payload, err := Payload(issued)
+		assert.NoError(t, err)
+
+		parsed, err := ParsePayload(payload)
+
+		assert.NoError(t, err)
+		assert.Equal(t, issued, parsed)
+	})
+
+	t.Run("should leave out empty fields", func(t *testing.T) {
+		payload, err := Payload(&models.Ticket{TicketNumber: "ab12cd34"})
+		assert.NoError(t, err)
+
+		parsed, err := ParsePayload(payload)
+
+		assert.Equal(t, "PT1|ab12cd34", payload)
+		assert.NoError(t, err)
+		assert.Equal(t, &models.Ticket{TicketNumber: "ab12cd34"}, parsed)
+	})
+
+	t.Run("should reject tickets it can't encode", func(t *testing.T) {
+		_, nilErr := Payload(nil)
+		_, emptyErr := Payload(&models.Ticket{})
+		_, separatorErr := Payload(&models.Ticket{TicketNumber: "ab|cd"})
+
+		assert.Equal(t, errors.ErrNilTicket, nilErr)
+		assert.Equal(t, errors.ErrEmptyTicketNumber, emptyErr)
+		assert.Equal(t, errors.ErrInvalidTicketPayload, separatorErr)
+	})
+
+	t.Run("should reject invalid payloads", func(t *testing.T) {
+		for _, payload := range []string{"", "ab12cd34", "PT1", "PT1|", "PT9|ab12cd34", "PT1|ab12cd34|!", "PT1|ab12cd34||x", "PT1|a|||||"} {
+			_, err := ParsePayload(payload)
+
+			assert.Equal(t, errors.ErrInvalidTicketPayload, err, payload)
+		}
+	})
+}
+
+func TestRender(t *testing.T) {
+	issued := &models.Ticket{
+		TicketNumber: "ab12cd34",
+		EntryTime:    time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC),
+		SlotNumber:   3,
+	}
+	payload, _ := Payload(issued)
+
+	t.Run("should encode the payload in a QR code", func(t *testing.T) {
+		code, err := QRCode(issued)
+
+		assert.NoError(t, err)
+		assert.Equal(t, payload, code.Content())
+	})
+
+	t.Run("should encode the payload in a Code 128 barcode", func(t *testing.T) {
+		code, err := Code128(issued)
+
+		assert.NoError(t, err)
+		assert.Equal(t, payload, code.Content())
+	})
+
+	t.Run("should reject payloads too long for Code 128", func(t *testing.T) {
+		_, err := Code128(&models.Ticket{TicketNumber: strings.Repeat("a", 80)})
+
+		assert.Equal(t, errors.ErrTicketPayloadTooLong, err)
+	})
+
+	t.Run("should render a PNG with a quiet zone", func(t *testing.T) {
+		code, _ := QRCode(issued)
+		var buf bytes.Buffer
+
+		err := WritePNG(&buf, code, 2)
+		assert.NoError(t, err)
+
+		img, err := png.Decode(&buf)
+		assert.NoError(t, err)
+		side := (code.Bounds().Dx() + 2*qrQuietZone) * 2
+		assert.Equal(t, side, img.Bounds().Dx())
+		assert.Equal(t, side, img.Bounds().Dy())
+		r, _, _, _ := img.At(0, 0).RGBA()
+		assert.Equal(t, uint32(0xffff), r)
+		// the top left finder pattern starts right after the quiet zone
+		r, _, _, _ = img.At(qrQuietZone*2, qrQuietZone*2).RGBA()
+		assert.Equal(t, uint32(0), r)
+	})
+
+	t.Run("should render a barcode PNG as tall as its bars", func(t *testing.T) {
+		code, _ := Code128(issued)
+		var buf bytes.Buffer
+
+		assert.NoError(t, WritePNG(&buf, code, 1))
+
+		img, err := png.Decode(&buf)
+		assert.NoError(t, err)
+		assert.Equal(t, code.Bounds().Dx()+2*code128QuietZone, img.Bounds().Dx())
+		assert.Equal(t, code128Height, img.Bounds().Dy())
+	})
+
+	t.Run("should render an SVG", func(t *testing.T) {
+		code, _ := QRCode(issued)
+		var buf bytes.Buffer
+
+		err := WriteSVG(&buf, code, 3)
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(buf.String(), "<svg "))
+		assert.Contains(t, buf.String(), `<rect x="12" y="12" width="21" height="3" fill="#000"/>`)
+		assert.True(t, strings.HasSuffix(buf.String(), "</svg>\n"))
+	})
+
+	t.Run("should reject non positive scales", func(t *testing.T) {
+		code, _ := QRCode(issued)
+
+		assert.Equal(t, errors.ErrInvalidScale, WritePNG(&bytes.Buffer{}, code, 0))
+		assert.Equal(t, errors.ErrInvalidScale, WriteSVG(&bytes.Buffer{}, code, 0))
+	})
+}
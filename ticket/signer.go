@@ -0,0 +1,125 @@
+package ticket
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+)
+
+// MinKeySize is the fewest bytes a signing key may have, shorter keys
+// could be guessed and tickets forged with them
+const MinKeySize = 16
+
+// signatureSize is the number of bytes of the HMAC-SHA256 kept in a ticket
+// number, enough to make forging one impractical while keeping it short
+const signatureSize = 16
+
+// Claims are what a signed ticket number vouches for
+type Claims struct {
+	LotID     string
+	EntryTime time.Time
+	// Serial tells apart tickets issued by a lot at the same instant
+	Serial uint64
+}
+
+// Signer issues ticket numbers that encode their claims and an HMAC of
+// them, so anyone holding the key, e.g. an exit gate, can validate a
+// ticket offline and reject forged or altered ones.
+//
+// A ticket number is the claims and the signature, both base64url encoded,
+// separated by a dot.
+type Signer struct {
+	key []byte
+}
+
+// NewSigner returns a signer for the key, or ErrTicketKeyTooShort if it has
+// fewer than MinKeySize bytes
+func NewSigner(key []byte) (*Signer, error) {
+	if len(key) < MinKeySize {
+		return nil, errors.ErrTicketKeyTooShort
+	}
+	return &Signer{key: append([]byte(nil), key...)}, nil
+}
+
+// Sign returns the ticket number for the claims
+func (s *Signer) Sign(claims Claims) string {
+	payload := []byte(fmt.Sprintf("%d.%d.%s", claims.EntryTime.UnixNano(), claims.Serial, claims.LotID))
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(s.signature(payload))
+}
+
+// Verify checks the signature of the ticket number and returns its claims
+func (s *Signer) Verify(ticketNumber string) (Claims, error) {
+	encodedPayload, encodedSignature, found := strings.Cut(ticketNumber, ".")
+	if !found {
+		return Claims{}, errors.ErrMalformedTicket
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return Claims{}, errors.ErrMalformedTicket
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return Claims{}, errors.ErrMalformedTicket
+	}
+
+	if !hmac.Equal(signature, s.signature(payload)) {
+		return Claims{}, errors.ErrForgedTicket
+	}
+
+	// the lot ID goes last as it may contain dots
+	fields := strings.SplitN(string(payload), ".", 3)
+	if len(fields) != 3 {
+		return Claims{}, errors.ErrMalformedTicket
+	}
+	entryTime, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return Claims{}, errors.ErrMalformedTicket
+	}
+	serial, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return Claims{}, errors.ErrMalformedTicket
+	}
+
+	return Claims{
+		LotID:     fields[2],
+		EntryTime: time.Unix(0, entryTime).UTC(),
+		Serial:    serial,
+	}, nil
+}
+
+// VerifyTicket checks that the ticket was signed for the lot and that its
+// entry time wasn't altered. Tickets issued by another lot are
+// unrecognized.
+func (s *Signer) VerifyTicket(t *models.Ticket, lotID string) (Claims, error) {
+	if t == nil {
+		return Claims{}, errors.ErrNilTicket
+	}
+
+	claims, err := s.Verify(t.TicketNumber)
+	if err != nil {
+		return Claims{}, err
+	}
+	if claims.LotID != lotID {
+		return Claims{}, errors.ErrUnrecognizedTicket
+	}
+	if !t.EntryTime.IsZero() && !t.EntryTime.Equal(claims.EntryTime) {
+		return Claims{}, errors.ErrForgedTicket
+	}
+	return claims, nil
+}
+
+func (s *Signer) signature(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+	return mac.Sum(nil)[:signatureSize]
+}
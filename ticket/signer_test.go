@@ -0,0 +1,94 @@
+package ticket
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/natanaelrusli/parking-lot/errors"
+	"github.com/natanaelrusli/parking-lot/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSigner(t *testing.T, key string) *Signer {
+	signer, err := NewSigner([]byte(key))
+	assert.NoError(t, err)
+	return signer
+}
+
+func TestNewSigner(t *testing.T) {
+	for _, key := range []string{"", "secret", strings.Repeat("k", MinKeySize-1)} {
+		signer, err := NewSigner([]byte(key))
+
+		assert.Nil(t, signer)
+		assert.Equal(t, errors.ErrTicketKeyTooShort, err, key)
+	}
+
+	_, err := NewSigner([]byte(strings.Repeat("k", MinKeySize)))
+	assert.NoError(t, err)
+}
+
+func TestSigner(t *testing.T) {
+	signer := newSigner(t, "secret-key-of-32-bytes-for-tests")
+	claims := Claims{
+		LotID:     "lot.1",
+		EntryTime: time.Date(2024, time.March, 1, 8, 0, 0, 123, time.UTC),
+		Serial:    42,
+	}
+
+	t.Run("should verify the ticket numbers it signed", func(t *testing.T) {
+		verified, err := signer.Verify(signer.Sign(claims))
+
+		assert.NoError(t, err)
+		assert.Equal(t, claims.LotID, verified.LotID)
+		assert.Equal(t, claims.Serial, verified.Serial)
+		assert.True(t, claims.EntryTime.Equal(verified.EntryTime))
+	})
+
+	t.Run("should issue different numbers for different serials", func(t *testing.T) {
+		other := claims
+		other.Serial++
+
+		assert.NotEqual(t, signer.Sign(claims), signer.Sign(other))
+	})
+
+	t.Run("should reject ticket numbers signed with another key", func(t *testing.T) {
+		_, err := newSigner(t, "guessed-key-of-32-bytes-in-tests").Verify(signer.Sign(claims))
+
+		assert.Equal(t, errors.ErrForgedTicket, err)
+	})
+
+	t.Run("should reject altered claims", func(t *testing.T) {
+		signature := signer.Sign(claims)[strings.Index(signer.Sign(claims), "."):]
+		altered := base64.RawURLEncoding.EncodeToString([]byte("0.42.lot.1")) + signature
+
+		_, err := signer.Verify(altered)
+
+		assert.Equal(t, errors.ErrForgedTicket, err)
+	})
+
+	t.Run("should reject malformed ticket numbers", func(t *testing.T) {
+		for _, ticketNumber := range []string{"", "abcd1234", "!!.!!", "YQ.YQ"} {
+			_, err := signer.Verify(ticketNumber)
+
+			assert.Error(t, err, ticketNumber)
+		}
+		_, err := signer.Verify("abcd1234")
+		assert.Equal(t, errors.ErrMalformedTicket, err)
+	})
+
+	t.Run("should check the lot and entry time of a ticket", func(t *testing.T) {
+		ticketNumber := signer.Sign(claims)
+
+		_, valid := signer.VerifyTicket(&models.Ticket{TicketNumber: ticketNumber, EntryTime: claims.EntryTime}, "lot.1")
+		_, withoutTime := signer.VerifyTicket(&models.Ticket{TicketNumber: ticketNumber}, "lot.1")
+		_, otherLot := signer.VerifyTicket(&models.Ticket{TicketNumber: ticketNumber}, "lot2")
+		_, alteredTime := signer.VerifyTicket(&models.Ticket{TicketNumber: ticketNumber, EntryTime: claims.EntryTime.Add(time.Hour)}, "lot.1")
+
+		assert.NoError(t, valid)
+		assert.NoError(t, withoutTime)
+		assert.Equal(t, errors.ErrUnrecognizedTicket, otherLot)
+		assert.Equal(t, errors.ErrForgedTicket, alteredTime)
+	})
+}